@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"esgbook-software-engineer-technical-test-2024/internal/scoring"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// Validate implements `score-app validate [-datasets dir] <file|dir>...`. It
+// prints every problem found in the given score configs and returns the
+// process exit code: 0 when all configs are valid, 1 when any is not and 2
+// on usage errors.
+func Validate(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	datasetsDir := fs.String("datasets", "", "directory holding the datasets.yaml catalog used to check dataset fields, the embedded catalog when empty")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(out, "usage: score-app validate [-datasets dir] <file|dir>...")
+		return 2
+	}
+
+	datasets, err := config.LoadDatasets(*datasetsDir)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	catalog := scoring.CatalogFromDefinitions(datasets)
+
+	files, err := collectFiles(fs.Args())
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+
+	invalid := 0
+	var parsed []*config.Config
+	byName := make(map[string]*config.Config)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %v\n", path, err)
+			invalid++
+			continue
+		}
+		scoreConfig, err := config.ParseConfigFile(path, data)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %v\n", path, err)
+			invalid++
+			continue
+		}
+		parsed = append(parsed, scoreConfig)
+		byName[scoreConfig.Name] = scoreConfig
+	}
+
+	// configs validated together may reference each other
+	lookup := func(name string) (*config.Config, bool) {
+		cfg, ok := byName[name]
+		return cfg, ok
+	}
+	for _, scoreConfig := range parsed {
+		errs := scoring.ValidateConfig(scoreConfig, catalog, lookup)
+		for _, e := range errs {
+			fmt.Fprintln(out, e.Error())
+		}
+		if len(errs) > 0 {
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(out, "%d of %d configs invalid\n", invalid, len(files))
+		return 1
+	}
+	fmt.Fprintf(out, "%d configs valid\n", len(files))
+	return 0
+}
+
+// collectFiles expands directories to the score configs they contain.
+// Files named explicitly are always validated.
+func collectFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			// unparsable files are kept so their error is reported
+			if ok, err := config.IsScoreConfig(data); ok || err != nil {
+				files = append(files, path)
+			}
+		}
+	}
+	return files, nil
+}
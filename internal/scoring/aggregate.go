@@ -0,0 +1,460 @@
+package scoring
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// periods assigns dated rows to the fiscal year of their company.
+type periods struct {
+	fallback  yearEnd
+	companies map[string]yearEnd
+}
+
+type yearEnd struct {
+	month time.Month
+	day   int
+}
+
+// start is the first day of the fiscal year following the year end in
+// year. A 02-29 year end ends February on the 28th in other years.
+func (e yearEnd) start(year int) time.Time {
+	last := time.Date(year, e.month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, e.month, min(e.day, last)+1, 0, 0, 0, 0, time.UTC)
+}
+
+func newPeriods(fy c.FiscalYear) (*periods, error) {
+	parse := func(end string) (yearEnd, error) {
+		month, day, err := c.ParseYearEnd(end)
+		return yearEnd{month: month, day: day}, err
+	}
+	fallback, err := parse(fy.YearEnd(""))
+	if err != nil {
+		return nil, err
+	}
+	p := &periods{fallback: fallback, companies: make(map[string]yearEnd, len(fy.Companies))}
+	for _, cfy := range fy.Companies {
+		if p.companies[cfy.Company], err = parse(cfy.End); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// key is the key of the period of grain date falls in for company. Fiscal
+// years are named after the calendar year they end in; months count from
+// the day after the year end, so with a 06-15 year end the first month runs
+// from June 16th to July 15th, and quarters are three of them. Static rows
+// key the company alone.
+func (p *periods) key(company string, date time.Time, grain string) CompanyYearKey {
+	if grain == c.GrainStatic {
+		return CompanyYearKey{CompanyID: company}
+	}
+	end, ok := p.companies[company]
+	if !ok {
+		end = p.fallback
+	}
+	key := CompanyYearKey{CompanyID: company, Year: date.Year()}
+	if date.Month() > end.month || (date.Month() == end.month && date.Day() > end.day) {
+		key.Year++
+	}
+	if grain == c.GrainYear {
+		return key
+	}
+	start := end.start(key.Year - 1)
+	months := (date.Year()-start.Year())*12 + int(date.Month()) - int(start.Month())
+	if date.Day() < start.Day() {
+		months--
+	}
+	switch grain {
+	case c.GrainQuarter:
+		key.Period = months/3 + 1
+	case c.GrainMonth:
+		key.Period = months + 1
+	}
+	return key
+}
+
+// coarsen is the key of grain covering key, which is at a finer grain.
+func coarsen(key CompanyYearKey, grain string) CompanyYearKey {
+	switch grain {
+	case c.GrainStatic:
+		return CompanyYearKey{CompanyID: key.CompanyID}
+	case c.GrainYear:
+		return CompanyYearKey{CompanyID: key.CompanyID, Year: key.Year}
+	default:
+		// quarters of monthly keys
+		return CompanyYearKey{CompanyID: key.CompanyID, Year: key.Year, Period: (key.Period-1)/3 + 1}
+	}
+}
+
+// periodsPerYear is the number of periods of a year at grain.
+func periodsPerYear(grain string) int {
+	switch grain {
+	case c.GrainQuarter:
+		return 4
+	case c.GrainMonth:
+		return 12
+	default:
+		return 0
+	}
+}
+
+// joinGrains aligns the datasets loaded at a coarser grain than the keys:
+// every key reads the row of the period, or for static datasets the
+// company, covering it. The keys are those of every dated dataset, a
+// coarser period standing for each of its sub-periods.
+func joinGrains(grain string, declared []c.Dataset, datasets map[string]*Table) {
+	rank := c.GrainRank(grain)
+	keys := make(map[CompanyYearKey]bool)
+	for _, ds := range declared {
+		dsGrain := ds.DataGrain()
+		if dsGrain == c.GrainStatic {
+			continue
+		}
+		for _, key := range datasets[ds.Name].Keys() {
+			if c.GrainRank(dsGrain) >= rank {
+				keys[key] = true
+				continue
+			}
+			for period := 1; period <= periodsPerYear(grain); period++ {
+				fine := CompanyYearKey{CompanyID: key.CompanyID, Year: key.Year, Period: period}
+				if coarsen(fine, dsGrain) == key {
+					keys[fine] = true
+				}
+			}
+		}
+	}
+
+	for _, ds := range declared {
+		if c.GrainRank(ds.DataGrain()) >= rank {
+			continue
+		}
+		datasets[ds.Name] = datasets[ds.Name].at(keys, ds.DataGrain())
+	}
+}
+
+// aggregator reduces the rows of a dataset as they are read into a
+// columnar store: every key has a slot, and every field a column holding
+// its accumulator of each slot, so memory grows with the keys rather than
+// the rows, without an allocation per key and field. The slots of every
+// key are kept until the result is built; periods are not flushed early.
+type aggregator struct {
+	ds       c.Dataset
+	periods  *periods
+	grain    string
+	policies map[string]string
+	slots    map[CompanyYearKey]int
+	keys     []CompanyYearKey
+	columns  map[string]*column
+	// latest and earliest are the dates of the latest and earliest rows of
+	// each slot, to the second, kept when a field takes one of them whole.
+	rowDated bool
+	latest   []int64
+	earliest []int64
+}
+
+func newAggregator(ds c.Dataset, periods *periods, grain string) *aggregator {
+	policies := make(map[string]string, len(ds.Fields))
+	rowDated := wholeRow(ds.FieldAggregation(c.Field{}))
+	for _, field := range ds.Fields {
+		policies[field.Name] = ds.FieldAggregation(field)
+		rowDated = rowDated || wholeRow(policies[field.Name])
+	}
+	return &aggregator{
+		ds:       ds,
+		periods:  periods,
+		grain:    grain,
+		policies: policies,
+		slots:    make(map[CompanyYearKey]int),
+		columns:  make(map[string]*column, len(ds.Fields)),
+		rowDated: rowDated,
+	}
+}
+
+// wholeRow reports whether policy takes the value of one row of a period
+// whole.
+func wholeRow(policy string) bool {
+	return policy == c.AggregateLatest || policy == c.AggregateEarliest
+}
+
+// slot returns the slot of the period of grain a row falls in. A key is
+// kept even when none of its rows has a value.
+func (a *aggregator) slot(company string, date time.Time) int {
+	key := a.periods.key(company, date, a.grain)
+	i, ok := a.slots[key]
+	if !ok {
+		// the company may share the memory of the whole row it was read from
+		key.CompanyID = strings.Clone(key.CompanyID)
+		i = len(a.keys)
+		a.slots[key] = i
+		a.keys = append(a.keys, key)
+	}
+	return i
+}
+
+// column returns the column of a field, added on first use.
+func (a *aggregator) column(name string) *column {
+	col, ok := a.columns[name]
+	if !ok {
+		policy, declared := a.policies[name]
+		if !declared {
+			policy = a.ds.FieldAggregation(c.Field{Name: name})
+		}
+		col = &column{policy: policy}
+		a.columns[name] = col
+	}
+	return col
+}
+
+// date records the date of a row of slot i, even one without values.
+func (a *aggregator) date(i int, date time.Time) {
+	if !a.rowDated {
+		return
+	}
+	d := date.Unix()
+	if i >= len(a.latest) {
+		a.latest = grow(a.latest, i+1)
+		a.earliest = grow(a.earliest, i+1)
+		a.latest[i], a.earliest[i] = d, d
+		return
+	}
+	a.latest[i] = max(a.latest[i], d)
+	a.earliest[i] = min(a.earliest[i], d)
+}
+
+// add folds one row into the accumulators of the period of grain it falls
+// in.
+func (a *aggregator) add(row observation) {
+	i := a.slot(row.CompanyID, row.Date)
+	a.date(i, row.Date)
+	for name, v := range row.Values {
+		a.column(name).add(i, row.Date, v)
+	}
+}
+
+// addBatch folds the rows of a batch, in order.
+func (a *aggregator) addBatch(b *rowBatch) {
+	columns := make([]*column, len(b.fields))
+	for f, name := range b.fields {
+		columns[f] = a.column(name)
+	}
+	for r, company := range b.companies {
+		i := a.slot(company, b.dates[r])
+		a.date(i, b.dates[r])
+		for f, col := range columns {
+			if v, ok := b.value(r, f); ok {
+				col.add(i, b.dates[r], v)
+			}
+		}
+	}
+}
+
+// result reduces the accumulators of every key into a table, failing on
+// the first key whose rows tie.
+func (a *aggregator) result() (*Table, error) {
+	return a.table(func(_ CompanyYearKey, err error) error { return err })
+}
+
+// partial reduces the accumulators of every key whose rows do not tie into
+// a table, and returns the error of each key left out.
+func (a *aggregator) partial() (*Table, map[CompanyYearKey]error) {
+	var tied map[CompanyYearKey]error
+	t, _ := a.table(func(key CompanyYearKey, err error) error {
+		if tied == nil {
+			tied = make(map[CompanyYearKey]error)
+		}
+		tied[key] = err
+		return nil
+	})
+	return t, tied
+}
+
+// table reduces the accumulators of every key straight into the rows of a
+// table, with a column per field holding a value. A key whose rows tie is
+// passed to tied with its error, and left out unless tied fails the table.
+func (a *aggregator) table(tied func(CompanyYearKey, error) error) (*Table, error) {
+	fields := make([]string, 0, len(a.columns))
+	for name, col := range a.columns {
+		if slices.ContainsFunc(col.n, func(n int32) bool { return n > 0 }) {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	columns := make([]*column, len(fields))
+	for f, name := range fields {
+		columns[f] = a.columns[name]
+	}
+
+	t := newTable(fields, len(a.keys))
+	for i, key := range a.keys {
+		r := t.grow()
+		var tie error
+		for f, col := range columns {
+			v, ok, err := a.value(i, fields[f], col)
+			if err != nil {
+				tie = err
+				break
+			}
+			if ok {
+				t.put(r, f, v)
+			}
+		}
+		if tie != nil {
+			if err := tied(key, tie); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		t.commit(key)
+	}
+	return t, nil
+}
+
+// values reduces the accumulators of the key of slot i, by field.
+func (a *aggregator) values(i int) (map[string]float64, error) {
+	values := make(map[string]float64, len(a.columns))
+	for name, col := range a.columns {
+		v, ok, err := a.value(i, name, col)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			values[name] = v
+		}
+	}
+	return values, nil
+}
+
+// value reduces the accumulator of slot i of the column of a field, false
+// when no row of the slot has a value of it, or the row the policy takes
+// whole has none.
+func (a *aggregator) value(i int, name string, col *column) (float64, bool, error) {
+	if i >= len(col.n) || col.n[i] == 0 {
+		return 0, false, nil
+	}
+	switch {
+	case col.policy == c.AggregateLatest && col.dates[i] != a.latest[i],
+		col.policy == c.AggregateEarliest && col.dates[i] != a.earliest[i]:
+		return 0, false, nil
+	}
+	v, err := col.value(i, a.ds.Ties())
+	if err != nil {
+		key := a.keys[i]
+		return 0, false, fmt.Errorf("dataset %q, company %s, year %d: %s on %s: %w",
+			a.ds.Name, key.CompanyID, key.Year, name, time.Unix(col.dates[i], 0).UTC().Format("2006-01-02"), err)
+	}
+	return v, true, nil
+}
+
+// aggregate reduces rows already read, in order.
+func aggregate(ds c.Dataset, rows []observation, periods *periods, grain string) (*Table, error) {
+	a := newAggregator(ds, periods, grain)
+	for _, row := range rows {
+		a.add(row)
+	}
+	return a.result()
+}
+
+// column reduces the values one field has over the rows of each slot, in
+// the order they are read. Slots past the end have no value yet. Only the
+// dated policies keep dates, to the second, and the values of the rows at
+// the date of a slot: the first in tied, any later ones in extra. The rows
+// at the latest or earliest date of a slot tie field by field.
+type column struct {
+	policy string
+	n      []int32
+	sum    []float64
+	max    []float64
+	dates  []int64
+	tied   []float64
+	extra  map[int][]float64
+}
+
+func (col *column) add(i int, date time.Time, v float64) {
+	latest := col.policy == c.AggregateLatest || col.policy == c.AggregateLatestValue
+	earliest := col.policy == c.AggregateEarliest || col.policy == c.AggregateEarliestValue
+	dated := latest || earliest
+	if i >= len(col.n) {
+		col.n = grow(col.n, i+1)
+		col.sum = grow(col.sum, i+1)
+		col.max = grow(col.max, i+1)
+		if dated {
+			col.dates = grow(col.dates, i+1)
+			col.tied = grow(col.tied, i+1)
+		}
+	}
+	col.n[i]++
+	col.sum[i] += v
+	if col.n[i] == 1 || v > col.max[i] {
+		col.max[i] = v
+	}
+	if !dated {
+		return
+	}
+	d := date.Unix()
+	switch {
+	case col.n[i] == 1,
+		latest && d > col.dates[i],
+		earliest && d < col.dates[i]:
+		col.dates[i] = d
+		col.tied[i] = v
+		delete(col.extra, i)
+	case d == col.dates[i]:
+		if col.extra == nil {
+			col.extra = make(map[int][]float64)
+		}
+		col.extra[i] = append(col.extra[i], v)
+	}
+}
+
+func (col *column) value(i int, ties string) (float64, error) {
+	switch col.policy {
+	case c.AggregateSum:
+		return col.sum[i], nil
+	case c.AggregateMean:
+		return col.sum[i] / float64(col.n[i]), nil
+	case c.AggregateMax:
+		return col.max[i], nil
+	case c.AggregateCount:
+		return float64(col.n[i]), nil
+	default:
+		return breakTie(ties, append([]float64{col.tied[i]}, col.extra[i]...))
+	}
+}
+
+// grow extends s with zero values to length n.
+func grow[T any](s []T, n int) []T {
+	return append(s, make([]T, n-len(s))...)
+}
+
+// breakTie picks one of the values of rows sharing a date. Equal values are
+// no conflict.
+func breakTie(policy string, tied []float64) (float64, error) {
+	switch policy {
+	case c.TieFirst:
+		return tied[0], nil
+	case c.TieMax, c.TieMin:
+		pick := tied[0]
+		for _, v := range tied[1:] {
+			if (policy == c.TieMax && v > pick) || (policy == c.TieMin && v < pick) {
+				pick = v
+			}
+		}
+		return pick, nil
+	case c.TieError:
+		for _, v := range tied[1:] {
+			if v != tied[0] {
+				return 0, fmt.Errorf("%d rows with the same date disagree: %v", len(tied), tied)
+			}
+		}
+		return tied[0], nil
+	default:
+		return tied[len(tied)-1], nil
+	}
+}
@@ -0,0 +1,202 @@
+package scoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestAggregate(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d
+	}
+	row := func(company, day string, values map[string]float64) observation {
+		return observation{CompanyID: company, Date: date(day), Values: values}
+	}
+	rows := []observation{
+		row("a", "2023-02-01", map[string]float64{"v": 1}),
+		row("a", "2023-06-01", map[string]float64{"v": 4}),
+		row("a", "2023-06-01", map[string]float64{"v": 2}),
+		row("a", "2023-09-01", map[string]float64{}),
+		row("a", "2024-01-10", map[string]float64{"v": 8}),
+	}
+	calendar, err := newPeriods(c.FiscalYear{})
+	require.NoError(t, err)
+	a2023 := CompanyYearKey{CompanyID: "a", Year: 2023}
+
+	tests := []struct {
+		name        string
+		aggregation string
+		ties        string
+		want        float64
+		// wantNone is set when the key has no value of v
+		wantNone bool
+		wantErr  string
+	}{
+		// the latest row of 2023 has no value of v
+		{name: "latest row", aggregation: c.AggregateLatest, wantNone: true},
+		{name: "latest value breaks ties on the last row", aggregation: c.AggregateLatestValue, want: 2},
+		{name: "latest value first", aggregation: c.AggregateLatestValue, ties: c.TieFirst, want: 4},
+		{name: "latest value max", aggregation: c.AggregateLatestValue, ties: c.TieMax, want: 4},
+		{name: "latest value min", aggregation: c.AggregateLatestValue, ties: c.TieMin, want: 2},
+		{name: "latest value error", aggregation: c.AggregateLatestValue, ties: c.TieError, wantErr: `dataset "ds", company a, year 2023: v on 2023-06-01: 2 rows with the same date disagree: [4 2]`},
+		{name: "earliest row", aggregation: c.AggregateEarliest, ties: c.TieError, want: 1},
+		{name: "earliest value", aggregation: c.AggregateEarliestValue, ties: c.TieError, want: 1},
+		{name: "sum", aggregation: c.AggregateSum, want: 7},
+		{name: "mean", aggregation: c.AggregateMean, want: 7.0 / 3},
+		{name: "max", aggregation: c.AggregateMax, want: 4},
+		{name: "count", aggregation: c.AggregateCount, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := c.Dataset{Name: "ds", TieBreak: tt.ties, Fields: []c.Field{
+				{Name: "v", Type: c.FieldNumber, Aggregation: tt.aggregation},
+			}}
+			got, err := aggregate(ds, rows, calendar, c.GrainYear)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 2, got.Len())
+			v, ok := got.Value(a2023, "v")
+			if tt.wantNone {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.InDelta(t, tt.want, v, 1e-12)
+		})
+	}
+
+	// the dataset policy applies to fields without one
+	ds := c.Dataset{Name: "ds", Aggregation: c.AggregateSum, Fields: []c.Field{
+		{Name: "v", Type: c.FieldNumber},
+		{Name: "w", Type: c.FieldNumber, Aggregation: c.AggregateCount},
+	}}
+	got, err := aggregate(ds, rows, calendar, c.GrainYear)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"v": 7}, rowsOf(got)[a2023])
+}
+
+func TestAggregateWholeRow(t *testing.T) {
+	date := func(day string) time.Time {
+		d, err := time.Parse("2006-01-02", day)
+		require.NoError(t, err)
+		return d
+	}
+	rows := []observation{
+		{CompanyID: "a", Date: date("2023-03-01"), Values: map[string]float64{"v": 1, "w": 2}},
+		{CompanyID: "a", Date: date("2023-06-01"), Values: map[string]float64{"v": 3}},
+		{CompanyID: "b", Date: date("2023-06-01"), Values: map[string]float64{"v": 1}},
+		{CompanyID: "b", Date: date("2023-06-01"), Values: map[string]float64{"w": 4}},
+	}
+	calendar, err := newPeriods(c.FiscalYear{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		aggregation string
+		want        map[CompanyYearKey]map[string]float64
+	}{
+		// rows at the same date tie field by field
+		{name: "latest", aggregation: c.AggregateLatest, want: map[CompanyYearKey]map[string]float64{
+			{CompanyID: "a", Year: 2023}: {"v": 3},
+			{CompanyID: "b", Year: 2023}: {"v": 1, "w": 4},
+		}},
+		{name: "latest value", aggregation: c.AggregateLatestValue, want: map[CompanyYearKey]map[string]float64{
+			{CompanyID: "a", Year: 2023}: {"v": 3, "w": 2},
+			{CompanyID: "b", Year: 2023}: {"v": 1, "w": 4},
+		}},
+		{name: "earliest", aggregation: c.AggregateEarliest, want: map[CompanyYearKey]map[string]float64{
+			{CompanyID: "a", Year: 2023}: {"v": 1, "w": 2},
+			{CompanyID: "b", Year: 2023}: {"v": 1, "w": 4},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := c.Dataset{Name: "ds", Aggregation: tt.aggregation, Fields: []c.Field{
+				{Name: "v", Type: c.FieldNumber},
+				{Name: "w", Type: c.FieldNumber},
+			}}
+			got, err := aggregate(ds, rows, calendar, c.GrainYear)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rowsOf(got))
+		})
+	}
+
+	// a field taking the latest row whole is missing when only an earlier
+	// row has it, whatever the policy of the other fields
+	ds := c.Dataset{Name: "ds", Fields: []c.Field{
+		{Name: "v", Type: c.FieldNumber, Aggregation: c.AggregateSum},
+		{Name: "w", Type: c.FieldNumber},
+	}}
+	got, err := aggregate(ds, rows, calendar, c.GrainYear)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"v": 4}, rowsOf(got)[CompanyYearKey{CompanyID: "a", Year: 2023}])
+}
+
+func TestAggregateFiscalYears(t *testing.T) {
+	rows := []observation{
+		{CompanyID: "a", Date: time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC), Values: map[string]float64{"v": 1}},
+		{CompanyID: "a", Date: time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC), Values: map[string]float64{"v": 2}},
+		{CompanyID: "b", Date: time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC), Values: map[string]float64{"v": 3}},
+		{CompanyID: "b", Date: time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC), Values: map[string]float64{"v": 4}},
+	}
+	periods, err := newPeriods(c.FiscalYear{
+		End:       "03-31",
+		Companies: []c.CompanyFiscalYear{{Company: "b", End: "06-30"}},
+	})
+	require.NoError(t, err)
+
+	ds := c.Dataset{Name: "ds", Aggregation: c.AggregateSum, Fields: []c.Field{{Name: "v", Type: c.FieldNumber}}}
+	got, err := aggregate(ds, rows, periods, c.GrainYear)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "a", Year: 2023}: {"v": 1},
+		{CompanyID: "a", Year: 2024}: {"v": 2},
+		{CompanyID: "b", Year: 2023}: {"v": 3},
+		{CompanyID: "b", Year: 2024}: {"v": 4},
+	}, rowsOf(got))
+}
+
+func TestPeriodsMidMonthYearEnd(t *testing.T) {
+	periods, err := newPeriods(c.FiscalYear{
+		End:       "06-15",
+		Companies: []c.CompanyFiscalYear{{Company: "b", End: "02-28"}},
+	})
+	require.NoError(t, err)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		company string
+		date    time.Time
+		grain   string
+		want    CompanyYearKey
+	}{
+		// the year starts the day after it ended
+		{company: "a", date: date(2024, time.June, 16), grain: c.GrainQuarter, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 1}},
+		{company: "a", date: date(2024, time.June, 20), grain: c.GrainQuarter, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 1}},
+		{company: "a", date: date(2024, time.September, 15), grain: c.GrainQuarter, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 1}},
+		{company: "a", date: date(2024, time.September, 16), grain: c.GrainQuarter, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 2}},
+		{company: "a", date: date(2025, time.June, 10), grain: c.GrainQuarter, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 4}},
+		{company: "a", date: date(2024, time.June, 20), grain: c.GrainMonth, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 1}},
+		{company: "a", date: date(2025, time.June, 10), grain: c.GrainMonth, want: CompanyYearKey{CompanyID: "a", Year: 2025, Period: 12}},
+		{company: "a", date: date(2025, time.June, 15), grain: c.GrainYear, want: CompanyYearKey{CompanyID: "a", Year: 2025}},
+		// the 29th of a leap February follows a 02-28 year end
+		{company: "b", date: date(2024, time.February, 29), grain: c.GrainMonth, want: CompanyYearKey{CompanyID: "b", Year: 2025, Period: 1}},
+		{company: "b", date: date(2025, time.February, 28), grain: c.GrainMonth, want: CompanyYearKey{CompanyID: "b", Year: 2025, Period: 12}},
+		{company: "b", date: date(2025, time.March, 1), grain: c.GrainMonth, want: CompanyYearKey{CompanyID: "b", Year: 2026, Period: 1}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, periods.key(tt.company, tt.date, tt.grain), "%s on %s at %s", tt.company, tt.date.Format("2006-01-02"), tt.grain)
+	}
+}
@@ -0,0 +1,333 @@
+package scoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/stream"
+)
+
+// Debezium change operations.
+const (
+	opCreate   = "c"
+	opRead     = "r"
+	opUpdate   = "u"
+	opDelete   = "d"
+	opTruncate = "t"
+	opMessage  = "m"
+)
+
+// changeEvent is the envelope of a Debezium change event.
+type changeEvent struct {
+	Op     string         `json:"op"`
+	After  map[string]any `json:"after"`
+	Source changeSource   `json:"source"`
+}
+
+// changeSource is where a change was read from the source database: the
+// LSN of Postgres, the change LSN of SQL Server or the binlog file,
+// position and row of MySQL.
+type changeSource struct {
+	LSN       uint64 `json:"lsn"`
+	ChangeLSN string `json:"change_lsn"`
+	File      string `json:"file"`
+	Pos       uint64 `json:"pos"`
+	Row       uint64 `json:"row"`
+}
+
+// sourcePosition orders the changes of a source database. SQL Server LSNs
+// and MySQL binlog files are fixed width, so they order as strings.
+type sourcePosition struct {
+	File string `json:"file,omitempty"`
+	Pos  uint64 `json:"pos,omitempty"`
+	Row  uint64 `json:"row,omitempty"`
+}
+
+// position returns the position of a change, false when its connector
+// does not report one.
+func (src changeSource) position() (sourcePosition, bool) {
+	switch {
+	case src.LSN != 0:
+		return sourcePosition{Pos: src.LSN}, true
+	case src.ChangeLSN != "":
+		return sourcePosition{File: src.ChangeLSN}, true
+	case src.File != "":
+		return sourcePosition{File: src.File, Pos: src.Pos, Row: src.Row}, true
+	}
+	return sourcePosition{}, false
+}
+
+func (p sourcePosition) before(q sourcePosition) bool {
+	if p.File != q.File {
+		return p.File < q.File
+	}
+	if p.Pos != q.Pos {
+		return p.Pos < q.Pos
+	}
+	return p.Row < q.Row
+}
+
+// decodeDebezium unmarshals a Debezium key or value into v, unwrapping the
+// payload of converters that send the schema along.
+func decodeDebezium(data []byte, v any) error {
+	var wrapped struct {
+		Schema  json.RawMessage `json:"schema"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Schema != nil && wrapped.Payload != nil {
+		data = wrapped.Payload
+	}
+	return json.Unmarshal(data, v)
+}
+
+// changeStream is a dataset streamed as the change events of a source
+// table. It holds the current row of every primary key, so an update or a
+// delete takes the row out of the period it was aggregated in, and every
+// period a change touches is aggregated again from the rows left: deleting
+// the latest row of a year selects the latest of the others.
+type changeStream struct {
+	ds      c.Dataset
+	periods *periods
+	grain   string
+	// seq orders the rows as they were last written, so ties break as they
+	// do between the rows of a file.
+	seq  int64
+	rows map[string]changeRow
+	// members are the primary keys of the rows of each key.
+	members map[CompanyYearKey]map[string]bool
+	data    map[CompanyYearKey]map[string]float64
+	// conflicts are the keys whose rows tie under the error policy.
+	conflicts map[CompanyYearKey]error
+	// positions is the source position of the last change applied to each
+	// primary key, kept once the row is deleted, and truncated the position
+	// of the last truncate. Changes at or before them are replays.
+	positions map[string]sourcePosition
+	truncated *sourcePosition
+}
+
+type changeRow struct {
+	Row observation `json:"row"`
+	Seq int64       `json:"seq"`
+}
+
+func newChangeStream(ds c.Dataset, periods *periods, grain string) *changeStream {
+	return &changeStream{
+		ds:        ds,
+		periods:   periods,
+		grain:     grain,
+		rows:      make(map[string]changeRow),
+		members:   make(map[CompanyYearKey]map[string]bool),
+		data:      make(map[CompanyYearKey]map[string]float64),
+		conflicts: make(map[CompanyYearKey]error),
+		positions: make(map[string]sourcePosition),
+	}
+}
+
+// apply applies one change event: creates, snapshot reads and updates
+// write the row after the change, deletes and tombstones remove the row of
+// the event key, and truncates remove every row.
+func (cs *changeStream) apply(msg stream.Message) ([]string, error) {
+	if len(msg.Value) == 0 || string(msg.Value) == "null" {
+		// a tombstone follows a delete, so compaction can drop the events of
+		// the row
+		pk, err := cs.primaryKey(msg.Key)
+		if err != nil {
+			return nil, err
+		}
+		return cs.write(pk, nil)
+	}
+	var event changeEvent
+	if err := decodeDebezium(msg.Value, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal change event: %w", err)
+	}
+	pos, tracked := event.Source.position()
+	if tracked && cs.truncated != nil && !cs.truncated.before(pos) {
+		return nil, nil
+	}
+
+	switch event.Op {
+	case opTruncate:
+		if tracked {
+			cs.truncated = &pos
+		}
+		return cs.truncate(), nil
+	case opMessage:
+		return nil, nil
+	case opCreate, opRead, opUpdate, opDelete:
+	default:
+		return nil, fmt.Errorf("unknown change operation %q", event.Op)
+	}
+
+	pk, err := cs.primaryKey(msg.Key)
+	if err != nil {
+		return nil, err
+	}
+	if last, ok := cs.positions[pk]; tracked && ok && !last.before(pos) {
+		return nil, nil
+	}
+	var row *observation
+	if event.Op != opDelete {
+		if event.After == nil {
+			return nil, fmt.Errorf("%q change event has no row after it", event.Op)
+		}
+		after, ok := jsonObservation(event.After, cs.ds)
+		if !ok {
+			return nil, fmt.Errorf("invalid row for dataset %q", cs.ds.Name)
+		}
+		row = &after
+	}
+	if tracked {
+		cs.positions[pk] = pos
+	}
+	return cs.write(pk, row)
+}
+
+// primaryKey reads the primary key of the row of an event from its key, in
+// a canonical form.
+func (cs *changeStream) primaryKey(key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", fmt.Errorf("change event has no key")
+	}
+	var pk any
+	if err := decodeDebezium(key, &pk); err != nil {
+		return "", fmt.Errorf("failed to unmarshal change event key: %w", err)
+	}
+	canonical, err := json.Marshal(pk)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change event key: %w", err)
+	}
+	return string(canonical), nil
+}
+
+// write replaces the row of a primary key, removing it when row is nil,
+// and returns the companies of the keys it left and entered, with an error
+// wrapping ErrTiedRows when rows of one of them tie.
+func (cs *changeStream) write(pk string, row *observation) ([]string, error) {
+	var touched []CompanyYearKey
+	if old, ok := cs.rows[pk]; ok {
+		key := cs.key(old.Row)
+		delete(cs.rows, pk)
+		delete(cs.members[key], pk)
+		touched = append(touched, key)
+	}
+	if row != nil {
+		cs.seq++
+		key := cs.key(*row)
+		cs.rows[pk] = changeRow{Row: *row, Seq: cs.seq}
+		if cs.members[key] == nil {
+			cs.members[key] = make(map[string]bool)
+		}
+		cs.members[key][pk] = true
+		touched = append(touched, key)
+	}
+
+	var companies []string
+	var tied []error
+	for _, key := range touched {
+		cs.reaggregate(key)
+		if len(companies) == 0 || companies[0] != key.CompanyID {
+			companies = append(companies, key.CompanyID)
+		}
+		if err, ok := cs.conflicts[key]; ok {
+			tied = append(tied, err)
+		}
+	}
+	if len(tied) > 0 {
+		return companies, fmt.Errorf("%w: %w", ErrTiedRows, errors.Join(tied...))
+	}
+	return companies, nil
+}
+
+// truncate removes every row and returns their companies, sorted.
+func (cs *changeStream) truncate() []string {
+	seen := make(map[string]bool)
+	for key := range cs.members {
+		seen[key.CompanyID] = true
+	}
+	companies := make([]string, 0, len(seen))
+	for company := range seen {
+		companies = append(companies, company)
+	}
+	sort.Strings(companies)
+
+	clear(cs.rows)
+	clear(cs.members)
+	clear(cs.data)
+	clear(cs.conflicts)
+	return companies
+}
+
+func (cs *changeStream) key(row observation) CompanyYearKey {
+	return cs.periods.key(row.CompanyID, row.Date, cs.grain)
+}
+
+// reaggregate reduces the rows left in a key, in the order they were
+// written.
+func (cs *changeStream) reaggregate(key CompanyYearKey) {
+	delete(cs.data, key)
+	delete(cs.conflicts, key)
+	members := cs.members[key]
+	if len(members) == 0 {
+		delete(cs.members, key)
+		return
+	}
+	rows := make([]changeRow, 0, len(members))
+	for pk := range members {
+		rows = append(rows, cs.rows[pk])
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Seq < rows[j].Seq })
+
+	agg := newAggregator(cs.ds, cs.periods, cs.grain)
+	for _, row := range rows {
+		agg.add(row.Row)
+	}
+	values, err := agg.values(0)
+	if err != nil {
+		cs.conflicts[key] = err
+		return
+	}
+	cs.data[key] = values
+}
+
+// result returns the aggregates of the current rows. Keys whose rows tie
+// are left out until a change resolves the tie.
+func (cs *changeStream) result() *Table {
+	return tableOf(cs.data)
+}
+
+// changeState is the state of a changeStream kept in a checkpoint. The
+// aggregates are derived from the rows again when it is restored.
+type changeState struct {
+	Seq       int64                     `json:"seq"`
+	Rows      map[string]changeRow      `json:"rows"`
+	Positions map[string]sourcePosition `json:"positions"`
+	Truncated *sourcePosition           `json:"truncated,omitempty"`
+}
+
+func (cs *changeStream) state() changeState {
+	return changeState{Seq: cs.seq, Rows: cs.rows, Positions: cs.positions, Truncated: cs.truncated}
+}
+
+func (cs *changeStream) restore(state changeState) {
+	fresh := newChangeStream(cs.ds, cs.periods, cs.grain)
+	*cs = *fresh
+	cs.seq = state.Seq
+	cs.truncated = state.Truncated
+	for pk, pos := range state.Positions {
+		cs.positions[pk] = pos
+	}
+	for pk, row := range state.Rows {
+		key := cs.key(row.Row)
+		cs.rows[pk] = row
+		if cs.members[key] == nil {
+			cs.members[key] = make(map[string]bool)
+		}
+		cs.members[key][pk] = true
+	}
+	for key := range cs.members {
+		cs.reaggregate(key)
+	}
+}
@@ -0,0 +1,59 @@
+package scoring
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// decompress wraps rc in a streaming reader of its compression. Closing
+// the reader closes rc.
+func decompress(rc io.ReadCloser, compression string) (io.ReadCloser, error) {
+	var r io.Reader
+	release := func() {}
+	switch compression {
+	case "":
+		return rc, nil
+	case c.CompressionGzip:
+		// concatenated gzip members are read as one stream
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		r = zr
+	case c.CompressionZstd:
+		zr, err := zstd.NewReader(rc, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("failed to read zstd stream: %w", err)
+		}
+		r, release = zr, zr.Close
+	case c.CompressionBzip2:
+		r = bzip2.NewReader(rc)
+	case c.CompressionSnappy:
+		// the framing format, as written by snzip and python-snappy
+		r = snappy.NewReader(rc)
+	default:
+		rc.Close()
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+	return &decompressed{Reader: r, release: release, rc: rc}, nil
+}
+
+type decompressed struct {
+	io.Reader
+	release func()
+	rc      io.Closer
+}
+
+func (d *decompressed) Close() error {
+	d.release()
+	return d.rc.Close()
+}
@@ -2,232 +2,154 @@ package scoring
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"maps"
+	"time"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
-// DataLoader interface: for reading data from a specific file/path.
+// DataLoader reads the rows of one source of a dataset and passes them to
+// emit in order, so a source never has to fit in memory.
 type DataLoader interface {
-	loadData(ctx context.Context, path string) (map[CompanyYearKey]map[string]float64, error)
+	loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error
+}
+
+// batchLoader is a DataLoader that can also pass rows a batch at a time,
+// read column by column, for the aggregator to fold without a map per row.
+type batchLoader interface {
+	loadBatches(ctx context.Context, src source, ds c.Dataset, emit func(*rowBatch)) error
 }
 
 // CSVLoader A simple CSV loader example.
 type CSVLoader struct{}
 
-func (CSVLoader) loadData(ctx context.Context, path string) (map[CompanyYearKey]map[string]float64, error) {
-	return loadDatasetCSV(path)
+func (CSVLoader) loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	return loadDatasetCSV(ctx, src, ds, func(b *rowBatch) { b.rows(emit) })
 }
 
-// JSONLoader Temporary files to test the JSON Import only
-type JSONLoader struct{}
-
-func (JSONLoader) loadData(ctx context.Context, path string) (map[CompanyYearKey]map[string]float64, error) {
-	return loadJSONDataset(path)
+func (CSVLoader) loadBatches(ctx context.Context, src source, ds c.Dataset, emit func(*rowBatch)) error {
+	return loadDatasetCSV(ctx, src, ds, emit)
 }
 
-func loadDatasetCSV(filename string) (map[CompanyYearKey]map[string]float64, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// rowDate parses and checks the date of a row. Rows of undated static
+// datasets have the zero date.
+func rowDate(companyID, raw string, ds c.Dataset) (time.Time, error) {
+	if ds.Date.Column == "" {
+		if companyID == "" {
+			return time.Time{}, fmt.Errorf("missing %s field", ds.Keys[0])
+		}
+		return time.Time{}, nil
 	}
-	defer f.Close()
-
-	reader := csv.NewReader(f)
-
-	headers, err := reader.Read()
+	parsedTime, err := parseDate(raw, ds.Date.Format)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read headers: %v", err)
-	}
-
-	idxCompany := indexOf(headers, "company_id")
-	idxDate := indexOf(headers, "date")
-	if idxCompany == -1 || idxDate == -1 {
-		return nil, fmt.Errorf("missing required columns (company_id, date)")
-	}
-
-	// Use rowData to store the 'latest' row (by full date) for each (company, year)
-	data := make(map[CompanyYearKey]rowData)
-
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		companyID := row[idxCompany]
-
-		parsedTime, err := parseDateOrYear(row[idxDate])
-		if err != nil {
-			log.Printf("Skipping row for %s due to date parse error: %v", companyID, err)
-			continue
-		}
-		yearInt := parsedTime.Year()
-
-		if err := validateData(companyID, yearInt); err != nil {
-			log.Printf("Skipping invalid row: company_id=%s, year=%d (error: %v)", companyID, yearInt, err)
-			continue
-		}
-
-		key := CompanyYearKey{
-			CompanyID: companyID,
-			Year:      yearInt,
-		}
-
-		numericVals := map[string]float64{}
-		for i, colName := range headers {
-			if i == idxCompany || i == idxDate {
-				continue
-			}
-			valStr := row[i]
-			if valStr == "" {
-				continue
-			}
-			if v, err := strconv.ParseFloat(valStr, 64); err == nil {
-				numericVals[colName] = v
-			}
-		}
-
-		// 3) Check if we have an existing entry for (company, year). If not, store it.
-		if existing, ok := data[key]; !ok {
-			data[key] = rowData{
-				Date:    parsedTime,
-				Numeric: numericVals,
-			}
-		} else {
-			if parsedTime.After(existing.Date) {
-				data[key] = rowData{
-					Date:    parsedTime,
-					Numeric: numericVals,
-				}
-			}
-		}
+		return time.Time{}, fmt.Errorf("date parse error: %v", err)
 	}
+	return checkDate(companyID, parsedTime)
+}
 
-	result := make(map[CompanyYearKey]map[string]float64)
-	for key, rd := range data {
-		result[key] = rd.Numeric
+// checkDate validates the company and the year of a parsed row date.
+func checkDate(companyID string, date time.Time) (time.Time, error) {
+	if err := validateData(companyID, date.Year()); err != nil {
+		return time.Time{}, fmt.Errorf("invalid row for year %d: %v", date.Year(), err)
 	}
-
-	return result, nil
+	return date, nil
 }
 
-// loadJSONDataset ignore example just to check loadJSON
-func loadJSONDataset(filename string) (map[CompanyYearKey]map[string]float64, error) {
-	f, err := os.Open(filename)
+// LoadDatasets reads every declared dataset from dataDir, keyed by its
+// logical name, and joins them at the grain of the keys.
+func (s *DataLoaderService) LoadDatasets(
+	ctx context.Context,
+	dataDir string,
+) (map[string]*Table, error) {
+	loaded, err := s.loadAll(ctx, dataDir)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	bytes, err := io.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("error reading %s: %w", filename, err)
-	}
-
-	var rows []RawJSONRow
-	if err := json.Unmarshal(bytes, &rows); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON from %s: %w", filename, err)
-	}
-
-	data := make(map[CompanyYearKey]rowData)
+	return s.join(loaded), nil
+}
 
-	for _, r := range rows {
-		parsedTime, err := parseDateOrYear(r.DateStr)
+// loadAll reads every declared dataset from dataDir at the grain it is
+// aggregated at, before the grains are joined.
+func (s *DataLoaderService) loadAll(
+	ctx context.Context,
+	dataDir string,
+) (map[string]*Table, error) {
+	loaded := make(map[string]*Table, len(s.datasets.Datasets))
+	for _, ds := range s.datasets.Datasets {
+		data, err := s.LoadDataset(ctx, dataDir, ds)
 		if err != nil {
-			log.Printf("Skipping row for company=%s due to invalid date %q: %v", r.CompanyID, r.DateStr, err)
-			continue
-		}
-
-		yearInt := parsedTime.Year()
-
-		key := CompanyYearKey{
-			CompanyID: r.CompanyID,
-			Year:      yearInt,
-		}
-
-		numericVals := make(map[string]float64)
-		if r.Dis1 != nil {
-			numericVals["dis_1"] = *r.Dis1
-		}
-		if r.Dis2 != nil {
-			numericVals["dis_2"] = *r.Dis2
-		}
-		if r.Dis3 != nil {
-			numericVals["dis_3"] = *r.Dis3
-		}
-		if r.Dis4 != nil {
-			numericVals["dis_4"] = *r.Dis4
-		}
-
-		if existing, ok := data[key]; !ok {
-			data[key] = rowData{
-				Date:    parsedTime,
-				Numeric: numericVals,
-			}
-		} else {
-			if parsedTime.After(existing.Date) {
-				data[key] = rowData{
-					Date:    parsedTime,
-					Numeric: numericVals,
-				}
-			}
+			return nil, err
 		}
+		loaded[ds.Name] = data
 	}
+	return loaded, nil
+}
 
-	result := make(map[CompanyYearKey]map[string]float64, len(data))
-	for k, rd := range data {
-		result[k] = rd.Numeric
-	}
-
-	return result, nil
+// join returns the datasets of loadAll joined at the grain of the keys,
+// leaving loaded as it is, so one dataset of it can be loaded again and
+// joined with the others.
+func (s *DataLoaderService) join(loaded map[string]*Table) map[string]*Table {
+	joined := maps.Clone(loaded)
+	joinGrains(s.datasets.KeyGrain(), s.datasets.Datasets, joined)
+	return joined
 }
 
-// LoadAllData load data from folder
-func (s *DataLoaderService) LoadAllData(
+// LoadDataset reads every object matching the location of ds and
+// aggregates the rows of each company and period, at the grain of the
+// dataset or the coarser grain of the keys. A dataset whose objects all
+// have the versions of its last load is not read again. Streamed datasets
+// are read from the store their events are applied to.
+func (s *DataLoaderService) LoadDataset(
 	ctx context.Context,
 	dataDir string,
-) (map[string]map[CompanyYearKey]map[string]float64, error) {
-
-	files, err := os.ReadDir(dataDir)
+	ds c.Dataset,
+) (*Table, error) {
+	if ds.Stream != nil {
+		return s.streams.dataset(ds.Name)
+	}
+	loader, ok := s.registry.GetLoader(ds.DataFormat())
+	if !ok {
+		return nil, fmt.Errorf("no loader for format %q of dataset %q", ds.DataFormat(), ds.Name)
+	}
+	ds = s.project(ds)
+	sources, err := s.sources(ctx, dataDir, ds)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data directory %s: %w", dataDir, err)
+		return nil, err
+	}
+	if data, ok := s.cache.get(dataDir, ds, s.datasets, sources); ok {
+		return data, nil
 	}
 
-	combined := make(map[string]map[CompanyYearKey]map[string]float64)
-
-	for _, f := range files {
-		if f.IsDir() {
-			continue // skip subdirectories
-		}
-
-		fullPath := filepath.Join(dataDir, f.Name())
-		ext := filepath.Ext(f.Name()) // e.g. ".csv"
-
-		loader, ok := s.registry.GetLoader(ext)
-		if !ok {
-			log.Printf("[WARN] Skipping file with unsupported extension %q: %s", ext, f.Name())
-			continue
+	periods, err := newPeriods(s.datasets.FiscalYear)
+	if err != nil {
+		return nil, err
+	}
+	agg := newAggregator(ds, periods, aggregationGrain(ds, s.datasets))
+	for _, src := range sources {
+		var err error
+		if batches, ok := loader.(batchLoader); ok {
+			err = batches.loadBatches(ctx, src, ds, agg.addBatch)
+		} else {
+			err = loader.loadData(ctx, src, ds, agg.add)
 		}
-
-		ds, err := loader.loadData(ctx, fullPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load data from %s: %w", f.Name(), err)
+			return nil, fmt.Errorf("failed to load dataset %q from %s: %w", ds.Name, src, err)
 		}
-
-		// e.g. "waste_data_old.csv" => datasetName = "waste_data"
-		datasetName := strings.TrimSuffix(f.Name(), ext)
-		combined[datasetName] = ds
 	}
+	data, err := agg.result()
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(dataDir, ds, s.datasets, sources, data)
+	return data, nil
+}
 
-	return combined, nil
+// aggregationGrain is the grain the rows of ds are aggregated at: its own,
+// or the grain of the keys when that is coarser.
+func aggregationGrain(ds c.Dataset, catalog *c.Datasets) string {
+	if c.GrainRank(ds.DataGrain()) > c.GrainRank(catalog.KeyGrain()) {
+		return catalog.KeyGrain()
+	}
+	return ds.DataGrain()
 }
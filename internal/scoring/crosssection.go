@@ -0,0 +1,209 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// Cross-sectional operations compare a company with its peers: every
+// company scored for the same year or, when the optional group parameter
+// is given, every company of that year with the same group value. They are
+// barriers in the plan (see Phase): x must be known for all keys first.
+// A key whose x is null, or whose group is null when grouping, is left out
+// of the population and its result is null.
+
+// CrossSectionalFn computes a metric for a whole population. values holds
+// the non-null inputs of every peer and the result is aligned with it.
+type CrossSectionalFn func(op c.Operation, values []float64) ([]float64, error)
+
+var CrossSectional = map[string]CrossSectionalFn{
+	"percentile_rank": percentileRank,
+	"zscore":          zscore,
+	"minmax_scale":    minmaxScale,
+	"winsorize":       winsorize,
+}
+
+func isBarrier(m c.Metric) bool {
+	_, ok := CrossSectional[m.Operation.Type]
+	return ok
+}
+
+// percentileRank is the share of peers below each value, ties counting
+// half, from 0 for the lowest to 1 for the highest. A population of one
+// ranks 0.5.
+func percentileRank(op c.Operation, values []float64) ([]float64, error) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	n := len(values)
+	out := make([]float64, n)
+	for i, v := range values {
+		if n == 1 {
+			out[i] = 0.5
+			continue
+		}
+		below := sort.SearchFloat64s(sorted, v)
+		equal := sort.SearchFloat64s(sorted, math.Nextafter(v, math.Inf(1))) - below
+		out[i] = (float64(below) + 0.5*float64(equal-1)) / float64(n-1)
+	}
+	return out, nil
+}
+
+// zscore is the distance from the mean in population standard deviations,
+// 0 for every peer when all values are equal.
+func zscore(op c.Operation, values []float64) ([]float64, error) {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(values)))
+
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if stddev > 0 {
+			out[i] = (v - mean) / stddev
+		}
+	}
+	return out, nil
+}
+
+// minmaxScale maps the lowest value to 0 and the highest to 1, 0.5 for
+// every peer when all values are equal.
+func minmaxScale(op c.Operation, values []float64) ([]float64, error) {
+	lowest, highest := values[0], values[0]
+	for _, v := range values[1:] {
+		lowest = math.Min(lowest, v)
+		highest = math.Max(highest, v)
+	}
+
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if highest == lowest {
+			out[i] = 0.5
+		} else {
+			out[i] = (v - lowest) / (highest - lowest)
+		}
+	}
+	return out, nil
+}
+
+// winsorize clamps values to the lower and upper quantiles of the
+// population, interpolating linearly between peers.
+func winsorize(op c.Operation, values []float64) ([]float64, error) {
+	if len(op.Parameters) < 3 || op.Parameters[1].Value == nil || op.Parameters[2].Value == nil {
+		return nil, fmt.Errorf("[winsorize] lower and upper must be literal values")
+	}
+	lower, upper := *op.Parameters[1].Value, *op.Parameters[2].Value
+	if lower < 0 || upper > 1 || lower > upper {
+		return nil, fmt.Errorf("[winsorize] invalid quantiles %g and %g", lower, upper)
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	lo, hi := quantile(sorted, lower), quantile(sorted, upper)
+
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = math.Min(math.Max(v, lo), hi)
+	}
+	return out, nil
+}
+
+// quantile of sorted values, interpolating linearly between ranks.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(math.Floor(pos))
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// peerGroup identifies one population of a cross-sectional metric.
+type peerGroup struct {
+	Year   int
+	Period int
+	Group  float64
+}
+
+// computeBarrier evaluates a cross-sectional metric for every key at once
+// and stores the results.
+func (e *evaluation) computeBarrier(ctx context.Context, logger *zap.Logger, ref metricRef, explain *explainState) {
+	metric := e.plan.metrics[ref]
+	op := metric.Operation
+	fn := CrossSectional[op.Type]
+	groupIdx := indexOf(Signatures[op.Type].names(), "group")
+
+	type peer struct {
+		key   CompanyYearKey
+		value float64
+	}
+	populations := make(map[peerGroup][]peer)
+	var inputs []InputTrace
+
+	for _, key := range e.keys {
+		results := e.results[key]
+		results.score = ref.Score
+		if explain != nil && key == explain.key {
+			results.inputs = &inputs
+		}
+
+		x, xNull := resolveParam(logger, op.Parameters[0], key, results, e.datasets)
+		group := peerGroup{Year: key.Year, Period: key.Period}
+		groupNull := false
+		if groupIdx < len(op.Parameters) {
+			group.Group, groupNull = resolveParam(logger, op.Parameters[groupIdx], key, results, e.datasets)
+		}
+		results.inputs = nil
+
+		if xNull || groupNull {
+			continue
+		}
+		populations[group] = append(populations[group], peer{key: key, value: x})
+	}
+
+	for group, peers := range populations {
+		values := make([]float64, len(peers))
+		for i, p := range peers {
+			values[i] = p.value
+		}
+		out, err := fn(op, values)
+		if err != nil {
+			logger.Sugar().Infow("No value for peer group",
+				zap.String("metric", ref.String()),
+				zap.Int("year", group.Year),
+				zap.Error(err))
+			continue
+		}
+		for i, p := range peers {
+			results := e.results[p.key]
+			results.score = ref.Score
+			results.set(ref.Metric, out[i])
+		}
+	}
+
+	if explain != nil {
+		val, ok := e.results[explain.key].get(ref.Score, ref.Metric)
+		explain.traces = append(explain.traces, MetricTrace{
+			Score:     ref.Score,
+			Metric:    ref.Metric,
+			Operation: op.Type,
+			Value:     val,
+			Null:      !ok,
+			Barrier:   true,
+			Inputs:    inputs,
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestCrossSectionalFunctions(t *testing.T) {
+	winsorizeOp := c.Operation{Type: "winsorize", Parameters: []c.Parameter{{Source: "waste.was_1"}, literal(0.25), literal(0.75)}}
+
+	tests := []struct {
+		name    string
+		op      c.Operation
+		values  []float64
+		want    []float64
+		wantErr bool
+	}{
+		{name: "percentile_rank", op: c.Operation{Type: "percentile_rank"}, values: []float64{30, 10, 20}, want: []float64{1, 0, 0.5}},
+		{name: "percentile_rank ties", op: c.Operation{Type: "percentile_rank"}, values: []float64{10, 20, 20, 30}, want: []float64{0, 0.5, 0.5, 1}},
+		{name: "percentile_rank single", op: c.Operation{Type: "percentile_rank"}, values: []float64{7}, want: []float64{0.5}},
+
+		{name: "zscore", op: c.Operation{Type: "zscore"}, values: []float64{2, 4, 4, 4, 5, 5, 7, 9}, want: []float64{-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2}},
+		{name: "zscore equal", op: c.Operation{Type: "zscore"}, values: []float64{3, 3}, want: []float64{0, 0}},
+
+		{name: "minmax_scale", op: c.Operation{Type: "minmax_scale"}, values: []float64{5, 0, 10}, want: []float64{0.5, 0, 1}},
+		{name: "minmax_scale equal", op: c.Operation{Type: "minmax_scale"}, values: []float64{3, 3}, want: []float64{0.5, 0.5}},
+
+		{name: "winsorize", op: winsorizeOp, values: []float64{1, 2, 3, 4, 100}, want: []float64{2, 2, 3, 4, 4}},
+		{name: "winsorize source bound", op: c.Operation{Type: "winsorize", Parameters: []c.Parameter{{}, {Source: "a.b"}, literal(1)}}, values: []float64{1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CrossSectional[tt.op.Type](tt.op, tt.values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDeltaSlice(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+const peersConfig = `name: peers
+metrics:
+  - name: intensity
+    operation:
+      type: divide
+      parameters:
+        - source: emissions.emi_1
+        - source: waste.was_1
+  - name: rank
+    operation:
+      type: percentile_rank
+      parameters:
+        - source: self.intensity
+        - source: disclosure.sector
+          param: group
+  - name: overall_z
+    operation:
+      type: zscore
+      parameters:
+        - source: self.intensity
+  - name: scaled_rank
+    operation:
+      type: multiply
+      parameters:
+        - source: self.rank
+        - value: 100
+  - name: rank_of_rank
+    operation:
+      type: minmax_scale
+      parameters:
+        - source: self.scaled_rank
+`
+
+// peersDatasets has four companies in 2024, a-c in sector 1 and d alone in
+// sector 2, plus company a in 2023.
+func peersDatasets() map[string]*Table {
+	key := func(id string, year int) CompanyYearKey { return CompanyYearKey{CompanyID: id, Year: year} }
+	return tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"emissions": {
+			key("a", 2024): {"emi_1": 10},
+			key("b", 2024): {"emi_1": 20},
+			key("c", 2024): {"emi_1": 30},
+			key("d", 2024): {"emi_1": 40},
+			key("a", 2023): {"emi_1": 50},
+		},
+		"waste": {
+			key("a", 2024): {"was_1": 1},
+			key("b", 2024): {"was_1": 1},
+			key("c", 2024): {"was_1": 1},
+			key("d", 2024): {"was_1": 1},
+			key("a", 2023): {"was_1": 1},
+		},
+		"disclosure": {
+			key("a", 2024): {"sector": 1},
+			key("b", 2024): {"sector": 1},
+			key("c", 2024): {"sector": 1},
+			key("d", 2024): {"sector": 2},
+		},
+	})
+}
+
+func TestBuildPlanPhases(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(peersConfig))
+	require.NoError(t, err)
+	require.Empty(t, ValidateConfig(scoreConfig, nil, nil))
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	ref := func(metric string) metricRef { return metricRef{Score: "peers", Metric: metric} }
+	assert.Equal(t, []Phase{
+		{Order: []metricRef{ref("intensity")}},
+		{Barriers: []metricRef{ref("rank"), ref("overall_z")}, Order: []metricRef{ref("scaled_rank")}},
+		{Barriers: []metricRef{ref("rank_of_rank")}},
+	}, plan.Phases)
+	assert.Equal(t, map[metricRef]bool{ref("rank"): true, ref("overall_z"): true, ref("rank_of_rank"): true}, plan.Barriers)
+}
+
+func TestCrossSectionalScoring(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(peersConfig))
+	require.NoError(t, err)
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	datasets := peersDatasets()
+	rows := parallelComputeScores(context.Background(), zap.NewNop(), getAllDataCompanyKeys(datasets), plan, datasets, 3)
+
+	got := make(map[CompanyYearKey]map[string]float64)
+	for _, row := range rows {
+		got[row.Key] = row.Metrics
+	}
+
+	// 2024 peers: a-c rank within sector 1, d alone in sector 2
+	assert.Equal(t, 0.0, got[CompanyYearKey{CompanyID: "a", Year: 2024}]["rank"])
+	assert.Equal(t, 0.5, got[CompanyYearKey{CompanyID: "b", Year: 2024}]["rank"])
+	assert.Equal(t, 1.0, got[CompanyYearKey{CompanyID: "c", Year: 2024}]["rank"])
+	assert.Equal(t, 0.5, got[CompanyYearKey{CompanyID: "d", Year: 2024}]["rank"])
+	assert.Equal(t, 50.0, got[CompanyYearKey{CompanyID: "d", Year: 2024}]["scaled_rank"])
+	assert.Equal(t, 0.5, got[CompanyYearKey{CompanyID: "b", Year: 2024}]["rank_of_rank"])
+	assert.Equal(t, 1.34, got[CompanyYearKey{CompanyID: "d", Year: 2024}]["overall_z"])
+
+	// 2023 is its own population; a has no sector so no rank
+	assert.Equal(t, map[string]float64{"intensity": 50, "overall_z": 0}, got[CompanyYearKey{CompanyID: "a", Year: 2023}])
+
+	// a single key still sees its peers
+	metrics := computeScoresForKey(context.Background(), zap.NewNop(), CompanyYearKey{CompanyID: "c", Year: 2024}, plan, datasets)
+	assert.Equal(t, got[CompanyYearKey{CompanyID: "c", Year: 2024}], plan.outputMetrics(metrics))
+
+	traces := ExplainKey(context.Background(), zap.NewNop(), CompanyYearKey{CompanyID: "b", Year: 2024}, plan, datasets)
+	require.Len(t, traces, 5)
+	assert.Equal(t, MetricTrace{
+		Score: "peers", Metric: "rank", Operation: "percentile_rank", Value: 0.5, Barrier: true,
+		Inputs: []InputTrace{{Source: "self.intensity", Value: 20}, {Source: "disclosure.sector", Value: 1}},
+	}, traces[1])
+}
+
+func TestValidateConfigWinsorizeBounds(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: bounds
+metrics:
+  - name: metric_1
+    operation:
+      type: winsorize
+      parameters:
+        - source: waste.was_1
+        - value: 0.05
+        - value: 95
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{`9:11: metric_1: param "upper" of winsorize must be between 0 and 1, got 95`}, got)
+}
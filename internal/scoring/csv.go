@@ -0,0 +1,304 @@
+package scoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"time"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// csvChunkSize is the size of the chunks CSV input is split into, to be
+// parsed in parallel.
+var csvChunkSize = 1 << 20
+
+// rowBatch holds rows read column by column, without a map per row: the
+// value of field f in row r is values[r*len(fields)+f], when present.
+type rowBatch struct {
+	fields    []string
+	companies []string
+	dates     []time.Time
+	values    []float64
+	present   []bool
+}
+
+func (b *rowBatch) value(r, f int) (float64, bool) {
+	k := r*len(b.fields) + f
+	return b.values[k], b.present[k]
+}
+
+// rows passes the rows of the batch to emit, in order.
+func (b *rowBatch) rows(emit func(observation)) {
+	for r, company := range b.companies {
+		values := make(map[string]float64, len(b.fields))
+		for f, name := range b.fields {
+			if v, ok := b.value(r, f); ok {
+				values[name] = v
+			}
+		}
+		emit(observation{CompanyID: company, Date: b.dates[r], Values: values})
+	}
+}
+
+// loadDatasetCSV splits CSV input into chunks of whole records, parses them
+// in parallel and passes their rows to emit in the order of the input. At
+// most a chunk per CPU is in flight, so memory does not grow with the size
+// of the input.
+func loadDatasetCSV(ctx context.Context, src source, ds c.Dataset, emit func(*rowBatch)) error {
+	f, err := src.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	chunks := &csvChunker{r: f, size: csvChunkSize}
+	first, err := chunks.next()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	reader := csv.NewReader(bytes.NewReader(first.data))
+	headers, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read headers: %v", err)
+	}
+	layout, err := newCSVLayout(headers, ds)
+	if err != nil {
+		return err
+	}
+	offset := reader.InputOffset()
+	first.line += bytes.Count(first.data[:offset], []byte{'\n'})
+	first.data = first.data[offset:]
+
+	type parsed struct {
+		batch *rowBatch
+		err   error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// pending holds the chunks in flight, in the order of the input
+	pending := make(chan chan parsed, runtime.GOMAXPROCS(0))
+	defer func() {
+		cancel()
+		for range pending {
+		}
+	}()
+	go func() {
+		defer close(pending)
+		chunk, err := first, error(nil)
+		for ctx.Err() == nil && err != io.EOF {
+			done := make(chan parsed, 1)
+			select {
+			case pending <- done:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				done <- parsed{err: err}
+				return
+			}
+			go func(chunk csvChunk) {
+				batch, err := layout.parse(chunk)
+				done <- parsed{batch: batch, err: err}
+			}(chunk)
+			chunk, err = chunks.next()
+		}
+	}()
+
+	for done := range pending {
+		p := <-done
+		if p.err != nil {
+			return p.err
+		}
+		emit(p.batch)
+	}
+	return ctx.Err()
+}
+
+// csvLayout is where the columns of a dataset are in the records of a CSV
+// file.
+type csvLayout struct {
+	ds         c.Dataset
+	width      int
+	idxCompany int
+	idxDate    int
+	fields     []c.Field
+	names      []string
+	idxFields  []int
+	inferred   bool
+}
+
+func newCSVLayout(headers []string, ds c.Dataset) (*csvLayout, error) {
+	idxCompany := indexOf(headers, ds.Keys[0])
+	idxDate := indexOf(headers, ds.Date.Column)
+	if idxCompany == -1 || (ds.Date.Column != "" && idxDate == -1) {
+		return nil, fmt.Errorf("missing required columns (%s, %s)", ds.Keys[0], ds.Date.Column)
+	}
+
+	// without declared fields every other column is read as a number
+	fields := ds.Fields
+	inferred := ds.InfersFields()
+	if inferred {
+		for i, header := range headers {
+			if i != idxCompany && i != idxDate {
+				fields = append(fields, c.Field{Name: header, Type: c.FieldNumber})
+			}
+		}
+	}
+	names := make([]string, len(fields))
+	idxFields := make([]int, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+		if idxFields[i] = indexOf(headers, field.Column()); idxFields[i] == -1 {
+			return nil, fmt.Errorf("missing column for field %q", field.Name)
+		}
+	}
+	return &csvLayout{
+		ds:         ds,
+		width:      len(headers),
+		idxCompany: idxCompany,
+		idxDate:    idxDate,
+		fields:     fields,
+		names:      names,
+		idxFields:  idxFields,
+		inferred:   inferred,
+	}, nil
+}
+
+// parse reads the records of a chunk. Errors name lines of the whole input.
+func (l *csvLayout) parse(chunk csvChunk) (*rowBatch, error) {
+	reader := csv.NewReader(bytes.NewReader(chunk.data))
+	reader.FieldsPerRecord = l.width
+	reader.ReuseRecord = true
+
+	// a record takes a line unless it holds quoted line breaks
+	rows := bytes.Count(chunk.data, []byte{'\n'}) + 1
+	batch := &rowBatch{
+		fields:    l.names,
+		companies: make([]string, 0, rows),
+		dates:     make([]time.Time, 0, rows),
+		values:    make([]float64, 0, rows*len(l.fields)),
+		present:   make([]bool, 0, rows*len(l.fields)),
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return batch, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				parseErr.StartLine += chunk.line
+				parseErr.Line += chunk.line
+			}
+			return nil, err
+		}
+
+		companyID := row[l.idxCompany]
+		rawDate := ""
+		if l.idxDate >= 0 {
+			rawDate = row[l.idxDate]
+		}
+
+		parsedTime, err := rowDate(companyID, rawDate, l.ds)
+		if err != nil {
+			log.Printf("Skipping row for %s: %v", companyID, err)
+			continue
+		}
+
+		batch.companies = append(batch.companies, companyID)
+		batch.dates = append(batch.dates, parsedTime)
+		for i, field := range l.fields {
+			valStr := row[l.idxFields[i]]
+			v, ok := 0.0, false
+			if valStr != "" {
+				var err error
+				v, err = parseField(valStr, field.Type)
+				ok = err == nil
+				// inferred columns may hold text, such as a country name
+				if err != nil && !l.inferred {
+					log.Printf("Skipping %s of company_id=%s: %v", field.Name, companyID, err)
+				}
+			}
+			batch.values = append(batch.values, v)
+			batch.present = append(batch.present, ok)
+		}
+	}
+}
+
+// csvChunk is a run of whole records and the number of lines before it.
+type csvChunk struct {
+	data []byte
+	line int
+}
+
+// csvChunker splits CSV input into chunks of about size bytes, cut after
+// the last line break outside quotes, so no record spans two chunks.
+type csvChunker struct {
+	r    io.Reader
+	size int
+	// rest is the start of a record read past the last cut.
+	rest []byte
+	line int
+	eof  bool
+}
+
+// next returns the next chunk, or io.EOF at the end of the input.
+func (ch *csvChunker) next() (csvChunk, error) {
+	for !ch.eof {
+		buf := make([]byte, len(ch.rest)+ch.size)
+		copy(buf, ch.rest)
+		n, err := io.ReadFull(ch.r, buf[len(ch.rest):])
+		buf = buf[:len(ch.rest)+n]
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			ch.eof = true
+			ch.rest = buf
+		case err != nil:
+			return csvChunk{}, err
+		default:
+			cut := lastRecordEnd(buf)
+			if cut < 0 {
+				// a record longer than a chunk
+				ch.rest = buf
+				continue
+			}
+			ch.rest = bytes.Clone(buf[cut:])
+			return ch.cut(buf[:cut]), nil
+		}
+	}
+	if len(ch.rest) == 0 {
+		return csvChunk{}, io.EOF
+	}
+	chunk := ch.rest
+	ch.rest = nil
+	return ch.cut(chunk), nil
+}
+
+func (ch *csvChunker) cut(data []byte) csvChunk {
+	chunk := csvChunk{data: data, line: ch.line}
+	ch.line += bytes.Count(data, []byte{'\n'})
+	return chunk
+}
+
+// lastRecordEnd returns the offset after the last line break of buf
+// outside quotes, -1 when there is none. Escaped quotes are doubled, so
+// they leave the quoting as it was.
+func lastRecordEnd(buf []byte) int {
+	end, quoted := -1, false
+	for i, b := range buf {
+		switch b {
+		case '"':
+			quoted = !quoted
+		case '\n':
+			if !quoted {
+				end = i + 1
+			}
+		}
+	}
+	return end
+}
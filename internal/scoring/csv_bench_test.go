@@ -0,0 +1,83 @@
+package scoring
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// writeBenchCSV writes rows of disclosures, like generate-data.py, spread
+// over companies and a few years.
+func writeBenchCSV(b *testing.B, rows, companies int) string {
+	path := filepath.Join(b.TempDir(), "disclosure.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "company_id,date,dis_1,dis_2,dis_3,dis_4")
+	for i := range rows {
+		fmt.Fprintf(w, "%d,%d-%02d-%02d,%.2f,%.2f,%.2f,%.2f\n",
+			1000+i%companies, 2020+i%4, 1+i%12, 1+i%28, float64(i%997)/7, float64(i%991)/3, float64(i%983), float64(i%977)/11)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadDatasetCSV(b *testing.B) {
+	for _, size := range []struct{ rows, companies int }{
+		{rows: 200_000, companies: 1_000},
+		{rows: 200_000, companies: 100_000},
+	} {
+		path := writeBenchCSV(b, size.rows, size.companies)
+		info, err := os.Stat(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		src := source{bucket: blob.Dir(filepath.Dir(path)), object: blob.Object{Key: filepath.Base(path)}}
+		ds := disclosureDataset(path)
+		periods, err := newPeriods(c.FiscalYear{})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		run := func(name string, chunkSize int, load func(agg *aggregator) error) {
+			b.Run(fmt.Sprintf("companies=%d/%s", size.companies, name), func(b *testing.B) {
+				defer func(size int) { csvChunkSize = size }(csvChunkSize)
+				csvChunkSize = chunkSize
+				b.SetBytes(info.Size())
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					agg := newAggregator(ds, periods, c.GrainYear)
+					if err := load(agg); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := agg.result(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+		ctx := context.Background()
+		// one chunk holding the whole file, read a row at a time
+		run("rows", int(info.Size())+1, func(agg *aggregator) error {
+			return CSVLoader{}.loadData(ctx, src, ds, agg.add)
+		})
+		for _, chunkSize := range []int{64 << 10, 1 << 20} {
+			run(fmt.Sprintf("chunk=%dKiB", chunkSize>>10), chunkSize, func(agg *aggregator) error {
+				return CSVLoader{}.loadBatches(ctx, src, ds, agg.addBatch)
+			})
+		}
+	}
+}
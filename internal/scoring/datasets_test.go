@@ -0,0 +1,587 @@
+package scoring
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// testDatasets returns the catalog embedded in the binary, which the
+// checked-in data and configs follow.
+func testDatasets(t testing.TB) *c.Datasets {
+	t.Helper()
+	datasets, err := c.DefaultDatasets()
+	require.NoError(t, err)
+	return datasets
+}
+
+// testDataService loads the datasets of the embedded catalog, without
+// streamed rows.
+func testDataService(t testing.TB) *DataLoaderService {
+	return NewDataLoaderService(NewLoaderRegistry(), testDatasets(t), nil)
+}
+
+func TestLoadDatasetsCheckedIn(t *testing.T) {
+	datasets, err := testDataService(t).LoadDatasets(context.Background(), filepath.Join("..", "..", Dir))
+	require.NoError(t, err)
+
+	assert.Len(t, datasets, 3)
+	for _, name := range []string{"disclosure", "waste", "emissions"} {
+		assert.NotZero(t, datasets[name].Len(), name)
+	}
+	// the later of the two 2023 rows of company 1000
+	assert.Equal(t, 27.49, rowsOf(datasets["waste"])[CompanyYearKey{CompanyID: "1000", Year: 2023}]["was_1"])
+	assert.Equal(t, 29.48, rowsOf(datasets["disclosure"])[CompanyYearKey{CompanyID: "1000", Year: 2023}]["dis_1"])
+	emissions := rowsOf(datasets["emissions"])
+	assert.Equal(t, map[string]float64{"emi_1": 15.31, "emi_2": 55.55, "emi_3": 0.13, "emi_4": 69.19},
+		emissions[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+	// the latest row is taken whole: an empty value is not filled from an
+	// earlier row of the year
+	for _, key := range []CompanyYearKey{
+		{CompanyID: "1005", Year: 2024},
+		{CompanyID: "1006", Year: 2023},
+		{CompanyID: "1007", Year: 2024},
+	} {
+		assert.NotContains(t, emissions[key], "emi_1", key)
+	}
+
+	// latest_value fills it from the latest row with a value
+	catalog := testDatasets(t)
+	for i := range catalog.Datasets {
+		if catalog.Datasets[i].Name == "emissions" {
+			catalog.Datasets[i].Aggregation = c.AggregateLatestValue
+		}
+	}
+	datasets, err = NewDataLoaderService(NewLoaderRegistry(), catalog, nil).LoadDatasets(context.Background(), filepath.Join("..", "..", Dir))
+	require.NoError(t, err)
+	assert.Equal(t, 55.44, rowsOf(datasets["emissions"])[CompanyYearKey{CompanyID: "1005", Year: 2024}]["emi_1"])
+}
+
+func TestLoadDataset(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("facilities-1.csv", "id,period,sites,audited,extra\n"+
+		"1000,2023,4,true,9\n"+
+		"1001,2023,x,false,9\n")
+	write("facilities-2.csv", "id,period,sites,audited\n"+
+		"1000,2024,5,1\n")
+	write("facilities.json", `[{"id": 1002, "period": 2023, "sites": 7, "audited": false}]`)
+
+	ds := c.Dataset{
+		Name:     "facilities",
+		Location: "facilities-*.csv",
+		Keys:     []string{"id"},
+		Date:     c.DateColumn{Column: "period", Format: c.DateFormatYear},
+		Fields: []c.Field{
+			{Name: "sites", Type: c.FieldInteger},
+			{Name: "audited", Type: c.FieldBoolean},
+		},
+	}
+	dataService := testDataService(t)
+
+	data, err := dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"sites": 4, "audited": 1},
+		// sites is not an integer, undeclared columns are ignored
+		{CompanyID: "1001", Year: 2023}: {"audited": 0},
+		{CompanyID: "1000", Year: 2024}: {"sites": 5, "audited": 1},
+	}, rowsOf(data))
+
+	ds.Location = "facilities.json"
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1002", Year: 2023}: {"sites": 7, "audited": 0},
+	}, rowsOf(data))
+
+	ds.Location = "facilities-*.csv"
+	ds.Fields = append(ds.Fields, c.Field{Name: "staff", Type: c.FieldInteger})
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.ErrorContains(t, err, `missing column for field "staff"`)
+
+	ds.Location = "plants-*.csv"
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.ErrorContains(t, err, `no files match`)
+}
+
+func TestLoadDatasetSchemas(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("emissions.ndjson", `{"company": {"id": "1000"}, "reported": "2023-03-31", "metrics": {"emissions": {"scope1": 4, "scope2": 6}}}`+"\n"+
+		`{"company": {"id": "1000"}, "reported": "2023-09-30", "metrics": {"emissions": {"scope1": 5}}, "note": "restated"}`+"\n"+
+		"\n"+
+		`{"company": {"id": "1001"}, "reported": "2024-01-31", "metrics": {"emissions": {"scope1": 3, "scope2": "n/a"}}}`+"\n")
+	write("emissions.json", `[{"company": {"id": 1002}, "reported": "2023-12-31", "metrics": {"emissions": {"scope1": 1}}}]`)
+	write("emissions.csv", "company.id,reported,scope1,comment\n"+
+		"1003,2023-12-31,2,late\n")
+
+	ds := c.Dataset{
+		Name:     "emissions",
+		Location: "emissions.ndjson",
+		Keys:     []string{"company.id"},
+		Date:     c.DateColumn{Column: "reported", Format: "2006-01-02"},
+		Fields: []c.Field{
+			{Name: "scope1", Type: c.FieldNumber, Path: "metrics.emissions.scope1"},
+			{Name: "scope2", Type: c.FieldNumber, Path: "metrics.emissions.scope2"},
+		},
+	}
+	dataService := testDataService(t)
+
+	data, err := dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		// the later row is taken whole, without the scope2 it is missing
+		{CompanyID: "1000", Year: 2023}: {"scope1": 5},
+		{CompanyID: "1001", Year: 2024}: {"scope1": 3},
+	}, rowsOf(data))
+
+	// without declared fields every number is read
+	ds.Fields = nil
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"metrics_emissions_scope1": 5},
+		{CompanyID: "1001", Year: 2024}: {"metrics_emissions_scope1": 3},
+	}, rowsOf(data))
+
+	ds.Location = "emissions.json"
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1002", Year: 2023}: {"metrics_emissions_scope1": 1},
+	}, rowsOf(data))
+
+	ds.Location = "emissions.csv"
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1003", Year: 2023}: {"scope1": 2},
+	}, rowsOf(data))
+
+	write("broken.ndjson", `{"company": {"id": "1000"}, "reported": "2023-03-31"}`+"\n{\n")
+	ds.Location = "broken.ndjson"
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.ErrorContains(t, err, "object 2")
+}
+
+func TestLoadDatasetParquet(t *testing.T) {
+	type emissions struct {
+		Scope1 *float64 `parquet:"scope1,optional"`
+		Scope2 *float64 `parquet:"scope2,optional"`
+	}
+	type row struct {
+		CompanyID string    `parquet:"company_id"`
+		Reported  int32     `parquet:"reported,date"`
+		Audited   bool      `parquet:"audited"`
+		Comment   string    `parquet:"comment"`
+		Metrics   emissions `parquet:"metrics"`
+	}
+	value := func(v float64) *float64 { return &v }
+	// DATE columns count days since the epoch
+	day := func(year int, month time.Month, d int) int32 {
+		return int32(time.Date(year, month, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+	}
+
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "emissions.parquet"))
+	require.NoError(t, err)
+	// two rows per row group, so the rows of company 1000 span two groups
+	w := parquet.NewGenericWriter[row](f, parquet.MaxRowsPerRowGroup(2))
+	_, err = w.Write([]row{
+		{"1000", day(2023, time.March, 31), true, "draft", emissions{value(4), value(6)}},
+		{"1001", day(2024, time.January, 31), false, "", emissions{value(3), nil}},
+		{"1000", day(2023, time.September, 30), false, "restated", emissions{value(5), nil}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	ds := c.Dataset{
+		Name:     "emissions",
+		Location: "*.parquet",
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "reported"},
+		Fields: []c.Field{
+			{Name: "scope1", Type: c.FieldNumber, Path: "metrics.scope1"},
+			{Name: "scope2", Type: c.FieldNumber, Path: "metrics.scope2"},
+			{Name: "audited", Type: c.FieldBoolean},
+		},
+	}
+	dataService := testDataService(t)
+
+	data, err := dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		// the later row is taken whole, with its null scope2 missing
+		{CompanyID: "1000", Year: 2023}: {"scope1": 5, "audited": 0},
+		{CompanyID: "1001", Year: 2024}: {"scope1": 3, "audited": 0},
+	}, rowsOf(data))
+
+	// projected to scope1, the other columns are never looked up
+	ds.Fields = append(ds.Fields, c.Field{Name: "scope3", Type: c.FieldNumber})
+	projected := dataService.Project(map[string]map[string]bool{"emissions": {"scope1": true}})
+	data, err = projected.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"scope1": 5},
+		{CompanyID: "1001", Year: 2024}: {"scope1": 3},
+	}, rowsOf(data))
+
+	// a dataset none of whose fields are read still provides its keys
+	data, err = dataService.Project(map[string]map[string]bool{}).LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, 2, data.Len())
+
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.ErrorContains(t, err, `missing column for field "scope3"`)
+
+	// without a fields list every numeric column is read
+	ds.Fields = nil
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"metrics_scope1": 5},
+		rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+}
+
+// countingBucket counts the listings of its bucket and the objects opened
+// from it.
+type countingBucket struct {
+	blob.Bucket
+	listed int
+	opened int
+}
+
+func (b *countingBucket) List(ctx context.Context, prefix string) ([]blob.Object, error) {
+	b.listed++
+	return b.Bucket.List(ctx, prefix)
+}
+
+func (b *countingBucket) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.opened++
+	return b.Bucket.Open(ctx, key)
+}
+
+func TestLoadDatasetBucket(t *testing.T) {
+	type row struct {
+		CompanyID string  `parquet:"company_id"`
+		Year      int64   `parquet:"year"`
+		Scope1    float64 `parquet:"scope1"`
+	}
+	parquetFile := func(rows ...row) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, parquet.Write(&buf, rows))
+		return buf.Bytes()
+	}
+
+	lake := blob.NewMemory()
+	lake.Put("emissions/2023.parquet", parquetFile(row{"1000", 2023, 4}))
+	lake.Put("emissions/2024.parquet", parquetFile(row{"1000", 2024, 5}))
+	lake.Put("emissions/readme.txt", []byte("not a dataset"))
+	bucket := &countingBucket{Bucket: lake}
+
+	ds := c.Dataset{
+		Name:     "emissions",
+		Location: "s3://lake/emissions/*.parquet",
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "year", Format: c.DateFormatYear},
+		Fields:   []c.Field{{Name: "scope1", Type: c.FieldNumber}},
+	}
+	dataService := testDataService(t)
+	dataService.RegisterBucket("s3://lake/", bucket)
+
+	// memory objects are not io.ReaderAt, so they are spooled to disk
+	data, err := dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"scope1": 4},
+		{CompanyID: "1000", Year: 2024}: {"scope1": 5},
+	}, rowsOf(data))
+	assert.Equal(t, 2, bucket.opened)
+
+	// unchanged objects are not read again
+	_, err = dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bucket.opened)
+
+	// a new generation of one object reloads the dataset
+	lake.Put("emissions/2024.parquet", parquetFile(row{"1000", 2024, 6}))
+	data, err = dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"scope1": 6}, rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2024}])
+	assert.Equal(t, 4, bucket.opened)
+
+	// so does a different projection, which is kept alongside the whole load
+	projected := dataService.Project(map[string]map[string]bool{})
+	_, err = projected.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, 6, bucket.opened)
+	_, err = dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	_, err = projected.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, 6, bucket.opened)
+
+	// without a cache every load reads the objects
+	dataService.cache = nil
+	_, err = dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Equal(t, 8, bucket.opened)
+
+	lake.Delete("emissions/2023.parquet")
+	lake.Delete("emissions/2024.parquet")
+	_, err = dataService.LoadDataset(context.Background(), "", ds)
+	assert.ErrorContains(t, err, `no files match "s3://lake/emissions/*.parquet"`)
+}
+
+func TestLoadDatasetCompressed(t *testing.T) {
+	const rows = "company_id,date,emi_1\n1000,2023-06-30,7\n1001,2023-06-30,8\n"
+	want := map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"emi_1": 7},
+		{CompanyID: "1001", Year: 2023}: {"emi_1": 8},
+	}
+
+	// gzip, written as two members like appended exports
+	var gz bytes.Buffer
+	for _, part := range []string{rows[:32], rows[32:]} {
+		zw := gzip.NewWriter(&gz)
+		_, err := zw.Write([]byte(part))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "emissions_2023.csv.gz"), gz.Bytes(), 0o644))
+	// bzip2 has no writer in the standard library: rows compressed by bzip2
+	bz, err := base64.StdEncoding.DecodeString("QlpoOTFBWSZTWY4zKI8AABZbgAAQAAZ5wAAAriPEICAAVFABiaNNMEqngo0eiDJj053ZC3fRI6iQJJJVpKikECjHmf7UeMpgeMv7TZxdyRThQkI4zKI8")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "emissions_2023.csv.bz2"), bz, 0o644))
+
+	lake := blob.NewMemory()
+	zw, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	lake.Put("emissions/2023.json.zst", zw.EncodeAll([]byte(`[
+		{"company_id": "1000", "date": "2023-06-30", "emi_1": 7},
+		{"company_id": "1001", "date": "2023-06-30", "emi_1": 8}
+	]`), nil))
+	type row struct {
+		CompanyID string  `parquet:"company_id"`
+		Date      string  `parquet:"date"`
+		Emi1      float64 `parquet:"emi_1"`
+	}
+	var pq, sz bytes.Buffer
+	require.NoError(t, parquet.Write(&pq, []row{{"1000", "2023-06-30", 7}, {"1001", "2023-06-30", 8}}))
+	sw := snappy.NewBufferedWriter(&sz)
+	_, err = sw.Write(pq.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, sw.Close())
+	lake.Put("emissions/2023.parquet.snappy", sz.Bytes())
+	lake.Put("emissions/2023.parquet.gz", []byte("not gzip"))
+
+	dataService := testDataService(t)
+	dataService.cache = nil
+	dataService.RegisterBucket("s3://lake/", lake)
+	emissions := func(location string) c.Dataset {
+		return c.Dataset{
+			Name:     "emissions",
+			Location: location,
+			Keys:     []string{"company_id"},
+			Date:     c.DateColumn{Column: "date"},
+			Fields:   []c.Field{{Name: "emi_1", Type: c.FieldNumber}},
+		}
+	}
+	for _, location := range []string{
+		"emissions_*.csv.gz",
+		"emissions_*.csv.bz2",
+		"s3://lake/emissions/*.json.zst",
+		"s3://lake/emissions/*.parquet.snappy",
+	} {
+		data, err := dataService.LoadDataset(context.Background(), dir, emissions(location))
+		require.NoError(t, err, location)
+		assert.Equal(t, want, rowsOf(data), location)
+	}
+
+	_, err = dataService.LoadDataset(context.Background(), dir, emissions("s3://lake/emissions/*.parquet.gz"))
+	assert.ErrorContains(t, err, "failed to read gzip header")
+
+	// with compression turned off the file is read as it is
+	ds := emissions("emissions_*.csv.gz")
+	ds.Compression = c.CompressionNone
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.Error(t, err)
+}
+
+func TestLoadDatasetSQLDriverNotBuiltIn(t *testing.T) {
+	if _, ok := sqlDrivers[c.DriverPostgres]; ok {
+		t.Skip("built with the postgres driver")
+	}
+	ds := c.Dataset{
+		Name:     "emissions",
+		Database: &c.Database{Driver: c.DriverPostgres, DSN: "postgres://localhost/scores", Table: "emissions"},
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "reported", Format: "2006-01-02"},
+		Fields:   []c.Field{{Name: "scope1", Type: c.FieldNumber}},
+	}
+	_, err := testDataService(t).LoadDataset(context.Background(), "", ds)
+	assert.ErrorContains(t, err, "the postgres driver is not built in, build with -tags postgres")
+}
+
+func TestParseDate(t *testing.T) {
+	got, err := parseDate("19/05/2023", "02/01/2006")
+	require.NoError(t, err)
+	assert.Equal(t, 2023, got.Year())
+
+	_, err = parseDate("2023-05-19", c.DateFormatYear)
+	assert.Error(t, err)
+
+	got, err = parseDate("2023", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2023, got.Year())
+}
+
+func TestValidateConfigDeclaredFields(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: declared
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+        - source: waste.was_9
+        - source: water.wat_1
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`8:11: metric_1: source "waste.was_9" references unknown field "was_9" of dataset "waste"`,
+		`9:11: metric_1: source "water.wat_1" references unknown dataset or score "water"`,
+	}, got)
+}
+
+func TestLoadDatasetsGrains(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("revenue.csv", "company_id,quarter,rev\n"+
+		"a,2024-Q1,10\n"+
+		"a,2024Q2,20\n")
+	write("energy.csv", "company_id,month,kwh\n"+
+		"a,2024-04,1\n"+
+		"a,2024-05,2\n"+
+		"a,2024-06,3\n")
+	write("waste.csv", "company_id,date,was_1\n"+
+		"a,2023-11-30,5\n")
+	write("sectors.csv", "company_id,sector\n"+
+		"a,7\n"+
+		"b,8\n")
+
+	numeric := func(name string) []c.Field { return []c.Field{{Name: name, Type: c.FieldNumber}} }
+	dataService := &DataLoaderService{registry: NewLoaderRegistry(), datasets: &c.Datasets{
+		Grain: c.GrainQuarter,
+		// fiscal years end in March, so fiscal Q1 is April to June
+		FiscalYear: c.FiscalYear{End: "03-31"},
+		Datasets: []c.Dataset{
+			{Name: "revenue", Location: "revenue.csv", Keys: []string{"company_id"}, Grain: c.GrainQuarter,
+				Date: c.DateColumn{Column: "quarter", Format: c.DateFormatQuarter}, Fields: numeric("rev")},
+			{Name: "energy", Location: "energy.csv", Keys: []string{"company_id"}, Grain: c.GrainMonth,
+				Date: c.DateColumn{Column: "month", Format: "2006-01"}, Aggregation: c.AggregateSum, Fields: numeric("kwh")},
+			{Name: "waste", Location: "waste.csv", Keys: []string{"company_id"},
+				Date: c.DateColumn{Column: "date", Format: "2006-01-02"}, Fields: numeric("was_1")},
+			{Name: "sectors", Location: "sectors.csv", Keys: []string{"company_id"}, Grain: c.GrainStatic,
+				Fields: numeric("sector")},
+		},
+	}}
+
+	datasets, err := dataService.LoadDatasets(context.Background(), dir)
+	require.NoError(t, err)
+
+	key := func(year, quarter int) CompanyYearKey {
+		return CompanyYearKey{CompanyID: "a", Year: year, Period: quarter}
+	}
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		key(2024, 4): {"rev": 10},
+		key(2025, 1): {"rev": 20},
+	}, rowsOf(datasets["revenue"]))
+	// months rolled up to fiscal Q1 2025 with the sum policy
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		key(2025, 1): {"kwh": 6},
+	}, rowsOf(datasets["energy"]))
+	// fiscal 2024 waste covers each of its quarters
+	assert.Equal(t, 4, datasets["waste"].Len())
+	for quarter := 1; quarter <= 4; quarter++ {
+		assert.Equal(t, map[string]float64{"was_1": 5}, rowsOf(datasets["waste"])[key(2024, quarter)])
+	}
+	// sectors apply to every key of the company; b has no dated data
+	assert.Equal(t, 5, datasets["sectors"].Len())
+	assert.Equal(t, map[string]float64{"sector": 7}, rowsOf(datasets["sectors"])[key(2025, 1)])
+}
+
+func TestEvaluateQuarters(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: quarters
+metrics:
+  - name: change
+    operation:
+      type: yoy_change
+      parameters:
+        - source: revenue.rev
+  - name: rank
+    operation:
+      type: percentile_rank
+      parameters:
+        - source: revenue.rev
+`))
+	require.NoError(t, err)
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	key := func(company string, year, quarter int) CompanyYearKey {
+		return CompanyYearKey{CompanyID: company, Year: year, Period: quarter}
+	}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"revenue": {
+			key("a", 2023, 1): {"rev": 10},
+			key("a", 2023, 2): {"rev": 50},
+			key("a", 2024, 1): {"rev": 15},
+			key("b", 2024, 1): {"rev": 30},
+		},
+	})
+	rows := parallelComputeScores(context.Background(), zap.NewNop(), getAllDataCompanyKeys(datasets), plan, datasets, 2)
+
+	got := make(map[CompanyYearKey]map[string]float64)
+	var order []CompanyYearKey
+	for _, row := range rows {
+		got[row.Key] = row.Metrics
+		order = append(order, row.Key)
+	}
+	assert.Equal(t, []CompanyYearKey{key("a", 2023, 1), key("a", 2023, 2), key("a", 2024, 1), key("b", 2024, 1)}, order)
+	// Q1 2024 compares with Q1 2023, not with the quarter before
+	assert.Equal(t, 5.0, got[key("a", 2024, 1)]["change"])
+	// peers are the companies of the same quarter
+	assert.Equal(t, 0.0, got[key("a", 2024, 1)]["rank"])
+	assert.Equal(t, 1.0, got[key("b", 2024, 1)]["rank"])
+	assert.Equal(t, 0.5, got[key("a", 2023, 2)]["rank"])
+}
@@ -0,0 +1,238 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// Decimal arithmetic. A float64 input is taken at the shortest decimal that
+// reads back as it, 0.1 rather than 0.1000000000000000055..., and the
+// operation is computed exactly on those decimals. Only the result is
+// rounded, once, to the nearest float64, so the next metric in a chain
+// reads it back as the same decimal and sum and divide chains do not
+// accumulate binary drift.
+
+// DecimalOperations are the exact variants of the arithmetic operations,
+// used by metrics with decimal arithmetic.
+var DecimalOperations = map[string]OperationFn{
+	"sum":          decimalSum,
+	"subtract":     decimalSubtract,
+	"multiply":     decimalMultiply,
+	"divide":       decimalDivide,
+	"avg":          decimalAvg,
+	"weighted_sum": decimalWeightedSum,
+}
+
+// exact returns the decimal v prints as, or nil for NaN and infinities.
+func exact(v float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// exactAll converts values, failing on the first that is not finite.
+func exactAll(values []float64) ([]*big.Rat, error) {
+	rats := make([]*big.Rat, len(values))
+	for i, v := range values {
+		if rats[i] = exact(v); rats[i] == nil {
+			return nil, fmt.Errorf("%g has no decimal value", v)
+		}
+	}
+	return rats, nil
+}
+
+func nearest(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
+
+func decimalSum(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	values, err := exactAll(nonNullValues(logger, op.Parameters, key, results, datasets))
+	if err != nil {
+		return 0, true, fmt.Errorf("[decimalSum] %w", err)
+	}
+	if len(values) == 0 {
+		return 0, true, nil
+	}
+	total := new(big.Rat)
+	for _, v := range values {
+		total.Add(total, v)
+	}
+	return nearest(total), false, nil
+}
+
+func decimalSubtract(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 2 {
+		return 0, true, fmt.Errorf("[decimalSubtract] not enough parameters")
+	}
+	floats, ok := allValues(logger, op.Parameters, 2, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	values, err := exactAll(floats)
+	if err != nil {
+		return 0, true, fmt.Errorf("[decimalSubtract] %w", err)
+	}
+	return nearest(new(big.Rat).Sub(values[0], values[1])), false, nil
+}
+
+func decimalMultiply(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) == 0 {
+		return 0, true, fmt.Errorf("[decimalMultiply] not enough parameters")
+	}
+	floats, ok := allValues(logger, op.Parameters, len(op.Parameters), key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	values, err := exactAll(floats)
+	if err != nil {
+		return 0, true, fmt.Errorf("[decimalMultiply] %w", err)
+	}
+	product := big.NewRat(1, 1)
+	for _, v := range values {
+		product.Mul(product, v)
+	}
+	return nearest(product), false, nil
+}
+
+func decimalDivide(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 2 {
+		return 0, true, fmt.Errorf("[decimalDivide] not enough parameters")
+	}
+	floats, ok := allValues(logger, op.Parameters, 2, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	values, err := exactAll(floats)
+	if err != nil {
+		return 0, true, fmt.Errorf("[decimalDivide] %w", err)
+	}
+	if values[1].Sign() == 0 {
+		return 0, true, fmt.Errorf("[decimalDivide] division by zero")
+	}
+	return nearest(new(big.Rat).Quo(values[0], values[1])), false, nil
+}
+
+func decimalAvg(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	values, err := exactAll(nonNullValues(logger, op.Parameters, key, results, datasets))
+	if err != nil {
+		return 0, true, fmt.Errorf("[decimalAvg] %w", err)
+	}
+	if len(values) == 0 {
+		return 0, true, nil
+	}
+	total := new(big.Rat)
+	for _, v := range values {
+		total.Add(total, v)
+	}
+	return nearest(total.Quo(total, big.NewRat(int64(len(values)), 1))), false, nil
+}
+
+func decimalWeightedSum(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	total := new(big.Rat)
+	var anyNonNull bool
+
+	for _, p := range op.Parameters {
+		if p.Weight == nil {
+			return 0, true, fmt.Errorf("[decimalWeightedSum] parameter %q has no weight", p.Source)
+		}
+		val, isNull := resolveParam(logger, p, key, results, datasets)
+		if isNull {
+			continue
+		}
+		values, err := exactAll([]float64{val, *p.Weight})
+		if err != nil {
+			return 0, true, fmt.Errorf("[decimalWeightedSum] %w", err)
+		}
+		total.Add(total, values[0].Mul(values[0], values[1]))
+		anyNonNull = true
+	}
+
+	if !anyNonNull {
+		return 0, true, nil
+	}
+	return nearest(total), false, nil
+}
+
+// roundTo rounds v to precision decimal places. Like the decimal
+// operations it rounds the decimal v prints as, so 1.005 is a tie and
+// rounds half-up to 1.01 even though its float64 is slightly below it.
+// NaN and infinities are returned unchanged.
+func roundTo(v float64, precision int, mode string) float64 {
+	r := exact(v)
+	if r == nil {
+		return v
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	// q is truncated towards zero, rem has the sign of r
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if mode != c.RoundTruncate && rem.Sign() != 0 {
+		twice := new(big.Int).Abs(rem)
+		twice.Lsh(twice, 1)
+		cmp := twice.Cmp(r.Denom())
+		odd := new(big.Int).Abs(q).Bit(0) == 1
+		if cmp > 0 || (cmp == 0 && (mode == c.RoundHalfUp || odd)) {
+			q.Add(q, big.NewInt(int64(r.Sign())))
+		}
+	}
+
+	return nearest(new(big.Rat).SetFrac(q, scale))
+}
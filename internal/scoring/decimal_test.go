@@ -0,0 +1,162 @@
+package scoring
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		v         float64
+		precision int
+		mode      string
+		want      float64
+	}{
+		{v: 2.345, precision: 2, mode: c.RoundHalfEven, want: 2.34},
+		{v: 2.355, precision: 2, mode: c.RoundHalfEven, want: 2.36},
+		{v: 1.005, precision: 2, mode: c.RoundHalfUp, want: 1.01},
+		{v: -1.005, precision: 2, mode: c.RoundHalfUp, want: -1.01},
+		{v: -2.345, precision: 2, mode: c.RoundHalfEven, want: -2.34},
+		{v: 2.349, precision: 2, mode: c.RoundTruncate, want: 2.34},
+		{v: -2.349, precision: 2, mode: c.RoundTruncate, want: -2.34},
+		{v: 2.5, precision: 0, mode: c.RoundHalfEven, want: 2},
+		{v: 2.5, precision: 0, mode: c.RoundHalfUp, want: 3},
+		{v: 0.30000000000000004, precision: 15, mode: c.RoundHalfEven, want: 0.3},
+		{v: 123456.789, precision: 1, mode: c.RoundHalfEven, want: 123456.8},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, roundTo(tt.v, tt.precision, tt.mode), "%v to %d (%s)", tt.v, tt.precision, tt.mode)
+	}
+	assert.True(t, math.IsInf(roundTo(math.Inf(1), 2, c.RoundHalfEven), 1))
+}
+
+func TestDecimalOperations(t *testing.T) {
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste": {opKey: {"a": 0.1, "b": 0.2, "c": 0.3, "three": 3}},
+	})
+
+	tests := []struct {
+		name     string
+		op       string
+		params   []c.Parameter
+		want     float64
+		wantNull bool
+		wantErr  bool
+	}{
+		{name: "sum", op: "sum", params: sources("a", "b"), want: 0.3},
+		{name: "sum skips null", op: "sum", params: sources("a", "missing"), want: 0.1},
+		{name: "sum all null", op: "sum", params: sources("missing"), wantNull: true},
+		{name: "subtract", op: "subtract", params: sources("c", "a"), want: 0.2},
+		{name: "multiply", op: "multiply", params: sources("c", "three"), want: 0.9},
+		{name: "divide", op: "divide", params: sources("c", "a"), want: 3},
+		{name: "divide by zero", op: "divide", params: []c.Parameter{{Source: "waste.a"}, literal(0)}, wantErr: true},
+		{name: "avg", op: "avg", params: sources("a", "b", "missing"), want: 0.15},
+		{name: "weighted_sum", op: "weighted_sum", params: []c.Parameter{weighted("a", 3), weighted("b", 0.1)}, want: 0.32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := &Results{score: "score", values: map[string]map[string]float64{"score": {}}}
+			op := c.Operation{Type: tt.op, Parameters: tt.params}
+
+			got, isNull, err := DecimalOperations[tt.op](context.Background(), zap.NewNop(), op, opKey, results, datasets)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNull, isNull)
+			if !tt.wantNull {
+				// exactly, not within a delta
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecimalArithmeticChain(t *testing.T) {
+	config := func(arithmetic string) *c.Config {
+		scoreConfig, err := c.ParseConfig([]byte(`name: chain
+arithmetic: ` + arithmetic + `
+metrics:
+  - name: total
+    operation:
+      type: sum
+      parameters:
+        - source: waste.a
+        - source: waste.b
+  - name: share
+    operation:
+      type: divide
+      parameters:
+        - source: self.total
+        - value: 3
+  - name: scaled
+    arithmetic: float
+    operation:
+      type: multiply
+      parameters:
+        - source: self.share
+        - value: 3
+`))
+		require.NoError(t, err)
+		require.Empty(t, ValidateConfig(scoreConfig, nil, nil))
+		return scoreConfig
+	}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste": {opKey: {"a": 0.1, "b": 0.2}},
+	})
+	compute := func(scoreConfig *c.Config) map[string]float64 {
+		plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+		require.NoError(t, err)
+		return computeScoresForKey(context.Background(), zap.NewNop(), opKey, plan, datasets)
+	}
+
+	floats := compute(config("float"))
+	assert.Equal(t, 0.30000000000000004, floats["total"])
+
+	decimals := compute(config("decimal"))
+	assert.Equal(t, 0.3, decimals["total"])
+	assert.Equal(t, 0.1, decimals["share"])
+	// scaled opts out, so float64 drift is back
+	assert.Equal(t, 0.30000000000000004, decimals["scaled"])
+}
+
+func TestValidateConfigRounding(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: rounding
+arithmetic: exact
+metrics:
+  - name: metric_1
+    precision: 16
+    rounding: bankers
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: metric_2
+    arithmetic: decimal
+    operation:
+      type: log
+      parameters:
+        - source: waste.was_1
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, nil, nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`unknown arithmetic "exact", expected one of float, decimal`,
+		`4:11: metric_1: precision must be between 0 and 15, got 16`,
+		`4:11: metric_1: unknown rounding "bankers", expected one of half-even, half-up, truncate`,
+		`11:11: metric_2: log does not support decimal arithmetic`,
+	}, got)
+}
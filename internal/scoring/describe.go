@@ -0,0 +1,126 @@
+package scoring
+
+import (
+	"fmt"
+	"strings"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// ScoreDescription documents a score and the metrics it outputs, in column
+// order. Internal metrics are not described. Grain is the grain of the
+// result rows.
+type ScoreDescription struct {
+	Name  string `json:"name"`
+	Grain string `json:"grain"`
+	c.Metadata
+	Metrics []MetricDescription `json:"metrics"`
+}
+
+// MetricDescription documents one output metric.
+type MetricDescription struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Operation   string `json:"operation"`
+	Precision   int    `json:"precision"`
+	Rounding    string `json:"rounding"`
+	c.Metadata
+}
+
+// DescribeScore returns the description of scoreConfig, scored at the given
+// key grain.
+func DescribeScore(scoreConfig *c.Config, grain string) ScoreDescription {
+	desc := ScoreDescription{Name: scoreConfig.Name, Grain: grain, Metadata: scoreConfig.Metadata}
+	metrics := BuildMetricMap(scoreConfig)
+	columns, _ := OutputColumns(scoreConfig, nil)
+	for _, col := range columns {
+		m := metrics[col.Metric]
+		desc.Metrics = append(desc.Metrics, MetricDescription{
+			Name:        m.Name,
+			DisplayName: col.Header,
+			Operation:   m.Operation.Type,
+			Precision:   col.Precision,
+			Rounding:    col.Rounding,
+			Metadata:    m.Metadata,
+		})
+	}
+	return desc
+}
+
+// Schema is the JSON Schema of one result row: the company, the year, the
+// period at sub-annual grains and every output metric, null when it could
+// not be computed.
+func (d ScoreDescription) Schema() map[string]any {
+	properties := map[string]any{
+		"company": map[string]any{"type": "string"},
+		"year":    map[string]any{"type": "integer"},
+	}
+	required := []string{"company", "year"}
+	if n := periodsPerYear(d.Grain); n > 0 {
+		properties["period"] = map[string]any{"type": "integer", "minimum": 1, "maximum": n}
+		required = append(required, "period")
+	}
+	for _, m := range d.Metrics {
+		property := map[string]any{
+			"type":  []string{"number", "null"},
+			"title": m.DisplayName,
+		}
+		if m.Description != "" {
+			property["description"] = m.Description
+		}
+		if m.Unit != "" {
+			property["x-unit"] = m.Unit
+		}
+		if len(m.Tags) > 0 {
+			property["x-tags"] = m.Tags
+		}
+		properties[m.Name] = property
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      d.Name,
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	if d.Description != "" {
+		schema["description"] = d.Description
+	}
+	if d.Version != "" {
+		schema["x-version"] = d.Version
+	}
+	return schema
+}
+
+// CommentLines renders the description as '#' comment lines to precede a
+// CSV header: the score first, then one line per column.
+func (d ScoreDescription) CommentLines() []string {
+	lines := []string{"# " + describeLine(d.Name, "", d.Metadata)}
+	for _, m := range d.Metrics {
+		lines = append(lines, "# "+describeLine(m.DisplayName, m.Name, m.Metadata))
+	}
+	return lines
+}
+
+// describeLine is "<title> (<name>) [<unit>] v<version>: <description>",
+// leaving out whatever is not set.
+func describeLine(title, name string, md c.Metadata) string {
+	var b strings.Builder
+	b.WriteString(title)
+	if name != "" && name != title {
+		fmt.Fprintf(&b, " (%s)", name)
+	}
+	if md.Unit != "" {
+		fmt.Fprintf(&b, " [%s]", md.Unit)
+	}
+	if md.Version != "" {
+		fmt.Fprintf(&b, " v%s", strings.TrimPrefix(md.Version, "v"))
+	}
+	if md.Description != "" {
+		b.WriteString(": ")
+		// keep the comment on one line
+		b.WriteString(strings.Join(strings.Fields(md.Description), " "))
+	}
+	return b.String()
+}
@@ -0,0 +1,200 @@
+package scoring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+	pb "esgbook-software-engineer-technical-test-2024/protos/modules/scoring/generated"
+)
+
+const describedConfig = `name: described
+description: Waste intensity
+owner: esg-data@example.com
+version: 1.2.0
+methodology_url: https://example.com/methodology/described
+tags: [environment]
+metrics:
+  - name: helper
+    output: false
+    description: Never described
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: intensity
+    display_name: Waste intensity
+    description: |
+      Waste per unit
+      of revenue
+    unit: t/USDm
+    tags: [waste, intensity]
+    operation:
+      type: divide
+      parameters:
+        - source: self.helper
+        - source: disclosure.dis_1
+groups:
+  - name: overall
+    unit: points
+    children:
+      - metric: intensity
+`
+
+func TestDescribeScore(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(describedConfig))
+	require.NoError(t, err)
+	require.Empty(t, ValidateConfig(scoreConfig, nil, nil))
+
+	desc := DescribeScore(scoreConfig, c.GrainYear)
+	assert.Equal(t, ScoreDescription{
+		Name:  "described",
+		Grain: c.GrainYear,
+		Metadata: c.Metadata{
+			Description:    "Waste intensity",
+			Owner:          "esg-data@example.com",
+			Version:        "1.2.0",
+			MethodologyURL: "https://example.com/methodology/described",
+			Tags:           []string{"environment"},
+		},
+		Metrics: []MetricDescription{
+			{
+				Name:        "intensity",
+				DisplayName: "Waste intensity",
+				Operation:   "divide",
+				Precision:   2,
+				Rounding:    c.RoundHalfEven,
+				Metadata: c.Metadata{
+					Description: "Waste per unit\nof revenue\n",
+					Unit:        "t/USDm",
+					Tags:        []string{"waste", "intensity"},
+				},
+			},
+			{
+				Name:        "overall",
+				DisplayName: "overall",
+				Operation:   c.RollupOperation,
+				Precision:   2,
+				Rounding:    c.RoundHalfEven,
+				Metadata:    c.Metadata{Unit: "points"},
+			},
+		},
+	}, desc)
+
+	assert.Equal(t, []string{
+		"# described v1.2.0: Waste intensity",
+		"# Waste intensity (intensity) [t/USDm]: Waste per unit of revenue",
+		"# overall [points]",
+	}, desc.CommentLines())
+
+	schema, err := json.Marshal(desc.Schema())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "described",
+		"description": "Waste intensity",
+		"x-version": "1.2.0",
+		"type": "object",
+		"required": ["company", "year"],
+		"properties": {
+			"company": {"type": "string"},
+			"year": {"type": "integer"},
+			"intensity": {
+				"type": ["number", "null"],
+				"title": "Waste intensity",
+				"description": "Waste per unit\nof revenue\n",
+				"x-unit": "t/USDm",
+				"x-tags": ["waste", "intensity"]
+			},
+			"overall": {"type": ["number", "null"], "title": "overall", "x-unit": "points"}
+		}
+	}`, string(schema))
+}
+
+func TestValidateConfigMetadata(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: metadata
+version: latest
+methodology_url: example.com/methodology
+metrics:
+  - name: metric_1
+    tags: [waste, "", waste]
+    version: v2
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, nil, nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`methodology_url "example.com/methodology" must be an http or https URL`,
+		`version "latest" must look like 2, 1.4 or 1.4.0`,
+		`5:11: metric_1: empty tag`,
+		`5:11: metric_1: duplicate tag "waste"`,
+	}, got)
+}
+
+func describedRegistry(t *testing.T) *c.Registry {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "described.yaml"), []byte(describedConfig), 0o644))
+	configs, err := c.NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+	return configs
+}
+
+func TestDescribeScoreHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &Handler{Logger: zap.NewNop(), Configs: describedRegistry(t), Data: testDataService(t)}
+	r := gin.New()
+	r.GET("/scores/:name", handler.DescribeScoreHandler)
+	r.GET("/scores/:name/schema", handler.ScoreSchemaHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/scores/described", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var desc ScoreDescription
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &desc))
+	assert.Equal(t, "1.2.0", desc.Version)
+	assert.Equal(t, "t/USDm", desc.Metrics[0].Unit)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/scores/described/schema", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"x-unit":"t/USDm"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/scores/score_404", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGrpcDescribeScore(t *testing.T) {
+	s := &GrpcScoringServer{Logger: zap.NewNop(), Configs: describedRegistry(t), ConfigFileName: "described.yaml", Data: testDataService(t)}
+
+	resp, err := s.DescribeScore(context.Background(), &pb.DescribeScoreRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "described", resp.GetName())
+	assert.Equal(t, "https://example.com/methodology/described", resp.GetMetadata().GetMethodologyUrl())
+	require.Len(t, resp.GetMetrics(), 2)
+	assert.Equal(t, "Waste intensity", resp.GetMetrics()[0].GetDisplayName())
+	assert.Equal(t, []string{"waste", "intensity"}, resp.GetMetrics()[0].GetMetadata().GetTags())
+
+	_, err = s.DescribeScore(context.Background(), &pb.DescribeScoreRequest{ConfigFile: "score_404"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -5,21 +5,33 @@ import (
 	"encoding/csv"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
+
+	config "esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
 type Handler struct {
 	Ctx            context.Context
 	Logger         *zap.Logger
+	Configs        *config.Registry
 	ConfigFileName string
+	// Data loads the datasets scores read, shared by every request.
+	Data *DataLoaderService
 }
 
-// CalculateScoreHandler Calculate scores and print in csv format
+// CalculateScoreHandler Calculate scores and print in csv format. The score
+// config is picked with the ?config= query parameter and defaults to
+// ConfigFileName. ?metrics=metric_4,metric_1 limits the output, and the
+// computation, to those metrics. ?comments=true precedes the CSV header
+// with '#' lines describing the score and its columns; the JSON Schema of
+// the rows is linked from the Link header.
 func (h *Handler) CalculateScoreHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -27,12 +39,22 @@ func (h *Handler) CalculateScoreHandler(c *gin.Context) {
 	_, span := tracer.Start(ctx, "CalculateScoreHTTP")
 	defer span.End()
 
-	h.Logger.Info("Calculating score")
+	h.Logger.Info("Calculating score", zap.String("config", c.Query("config")))
 
-	lr := NewLoaderRegistry()
-	dataService := NewDataLoaderService(lr)
+	scoreConfig, err := resolveConfig(h.Configs, c.Query("config"), h.ConfigFileName)
+	if err != nil {
+		c.String(http.StatusNotFound, "Error: %v", err)
+		return
+	}
 
-	scoreConfig, scoredResults, err := CalculateScore(ctx, h.Logger, h.ConfigFileName, dataService)
+	selected := selectedMetrics(c.QueryArray("metrics"))
+	columns, err := OutputColumns(scoreConfig, selected)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Error: %v", err)
+		return
+	}
+
+	scoredResults, err := CalculateScore(ctx, h.Logger, scoreConfig, h.Configs.Get, h.Data, selected)
 	if err != nil {
 		h.Logger.Info(fmt.Sprintf("Error calculating score: %s", err.Error()))
 		c.String(http.StatusInternalServerError, "Error: %v", err)
@@ -44,13 +66,29 @@ func (h *Handler) CalculateScoreHandler(c *gin.Context) {
 
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", `attachment; filename="scores.csv"`)
+	c.Header("Link", fmt.Sprintf(`</scores/%s/schema>; rel="describedby"`, url.PathEscape(scoreConfig.Name)))
+
+	if c.Query("comments") == "true" {
+		desc := DescribeScore(scoreConfig, h.Data.Datasets().KeyGrain())
+		for _, line := range desc.CommentLines() {
+			if _, err := fmt.Fprintln(c.Writer, line); err != nil {
+				h.Logger.Info(fmt.Sprintf("Error writing comments: %s", err.Error()))
+				return
+			}
+		}
+	}
 
 	csvWriter := csv.NewWriter(c.Writer)
 	defer csvWriter.Flush()
 
+	// sub-annual keys carry their quarter or month
+	periods := h.Data.Datasets().KeyGrain() != config.GrainYear
 	header := []string{"company", "year"}
-	for _, metric := range scoreConfig.Metrics {
-		header = append(header, metric.Name)
+	if periods {
+		header = append(header, "period")
+	}
+	for _, col := range columns {
+		header = append(header, col.Header)
 	}
 	err = csvWriter.Write(header)
 	if err != nil {
@@ -64,9 +102,12 @@ func (h *Handler) CalculateScoreHandler(c *gin.Context) {
 			sr.Key.CompanyID,
 			strconv.Itoa(sr.Key.Year),
 		}
-		for _, metric := range scoreConfig.Metrics {
-			if val, ok := sr.Metrics[metric.Name]; ok {
-				row = append(row, fmt.Sprintf("%.2f", val))
+		if periods {
+			row = append(row, strconv.Itoa(sr.Key.Period))
+		}
+		for _, col := range columns {
+			if val, ok := sr.Metrics[col.Metric]; ok {
+				row = append(row, col.Format(val))
 			} else {
 				row = append(row, "") // or "NULL"
 			}
@@ -80,6 +121,95 @@ func (h *Handler) CalculateScoreHandler(c *gin.Context) {
 	}
 }
 
+// selectedMetrics flattens repeated and comma-separated metrics query
+// values.
+func selectedMetrics(values []string) []string {
+	var selected []string
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				selected = append(selected, name)
+			}
+		}
+	}
+	return selected
+}
+
+// ExplainHandler shows how every metric of a score was computed for one
+// company and year: GET /explain?company=<id>&year=<yyyy>[&config=<name>].
+// At quarter or month grain the period=<n> parameter is required too.
+// Each operation input says whether it was read from its source, is a
+// literal value or is the parameter default standing in for a null.
+func (h *Handler) ExplainHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tracer := otel.Tracer("score-app")
+	_, span := tracer.Start(ctx, "ExplainScoreHTTP")
+	defer span.End()
+
+	company := c.Query("company")
+	year, err := strconv.Atoi(c.Query("year"))
+	if company == "" || err != nil {
+		c.String(http.StatusBadRequest, "Error: company and year query parameters are required")
+		return
+	}
+	period := 0
+	if n := periodsPerYear(h.Data.Datasets().KeyGrain()); n > 0 {
+		period, err = strconv.Atoi(c.Query("period"))
+		if err != nil || period < 1 || period > n {
+			c.String(http.StatusBadRequest, "Error: period query parameter must be between 1 and %d", n)
+			return
+		}
+	}
+
+	scoreConfig, err := resolveConfig(h.Configs, c.Query("config"), h.ConfigFileName)
+	if err != nil {
+		c.String(http.StatusNotFound, "Error: %v", err)
+		return
+	}
+
+	key := CompanyYearKey{CompanyID: company, Year: year, Period: period}
+	traces, err := ExplainScore(ctx, h.Logger, scoreConfig, h.Configs.Get, h.Data, key)
+	if err != nil {
+		h.Logger.Info(fmt.Sprintf("Error explaining score: %s", err.Error()))
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	resp := gin.H{
+		"score":   scoreConfig.Name,
+		"company": company,
+		"year":    year,
+		"metrics": traces,
+	}
+	if period > 0 {
+		resp["period"] = period
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DescribeScoreHandler returns the metadata of a score and of every metric
+// it outputs: GET /scores/<name>.
+func (h *Handler) DescribeScoreHandler(c *gin.Context) {
+	scoreConfig, err := resolveConfig(h.Configs, c.Param("name"), "")
+	if err != nil {
+		c.String(http.StatusNotFound, "Error: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, DescribeScore(scoreConfig, h.Data.Datasets().KeyGrain()))
+}
+
+// ScoreSchemaHandler returns the JSON Schema of the rows a score outputs:
+// GET /scores/<name>/schema.
+func (h *Handler) ScoreSchemaHandler(c *gin.Context) {
+	scoreConfig, err := resolveConfig(h.Configs, c.Param("name"), "")
+	if err != nil {
+		c.String(http.StatusNotFound, "Error: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, DescribeScore(scoreConfig, h.Data.Datasets().KeyGrain()).Schema())
+}
+
 func HealthCheckHandler(c *gin.Context) {
 	if err := isServiceHealthy(); err != nil {
 		// If the service is NOT healthy:
@@ -7,17 +7,24 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"esgbook-software-engineer-technical-test-2024/middleware"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
 func BenchmarkCalculateScoreHandler(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 
 	logger, _ := middleware.InitializeLogger()
+	configs, err := config.NewRegistry(logger, "", nil)
+	if err != nil {
+		b.Fatalf("failed to load configs: %v", err)
+	}
 
 	handler := &Handler{
 		Ctx:            nil, // The handler uses the request's context.
 		Logger:         logger,
+		Configs:        configs,
 		ConfigFileName: "score_1.yaml",
+		Data:           testDataService(b),
 	}
 
 	r := gin.New()
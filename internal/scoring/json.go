@@ -0,0 +1,145 @@
+package scoring
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// JSONLoader reads a JSON array of objects, decoding one object at a time.
+type JSONLoader struct{}
+
+func (JSONLoader) loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	return loadJSONDataset(ctx, src, ds, false, emit)
+}
+
+// NDJSONLoader reads newline-delimited JSON, one object per line.
+type NDJSONLoader struct{}
+
+func (NDJSONLoader) loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	return loadJSONDataset(ctx, src, ds, true, emit)
+}
+
+// loadJSONDataset streams the objects of a JSON array, or of an NDJSON file
+// when lines is set. The key, the date and the fields are read from the
+// columns declared by ds, which may be dotted paths into nested objects.
+func loadJSONDataset(ctx context.Context, src source, ds c.Dataset, lines bool, emit func(observation)) error {
+	f, err := src.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	if !lines {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("failed to unmarshal JSON: expected an array of objects")
+		}
+	}
+
+	for n := 1; lines || dec.More(); n++ {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			if lines && err == io.EOF {
+				break
+			}
+			return fmt.Errorf("failed to unmarshal JSON, object %d: %w", n, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if row, ok := jsonObservation(obj, ds); ok {
+			emit(row)
+		}
+	}
+	return nil
+}
+
+// jsonObservation reads the row of one object. Without declared fields every
+// number is read, nested ones named by their path joined with underscores.
+func jsonObservation(obj map[string]any, ds c.Dataset) (observation, bool) {
+	companyID := jsonString(lookupPath(obj, ds.Keys[0]))
+	parsedTime, err := rowDate(companyID, jsonString(lookupPath(obj, ds.Date.Column)), ds)
+	if err != nil {
+		log.Printf("Skipping row for company=%s: %v", companyID, err)
+		return observation{}, false
+	}
+
+	numericVals := make(map[string]float64)
+	if ds.InfersFields() {
+		skip := map[string]bool{ds.Keys[0]: true, ds.Date.Column: true}
+		collectNumbers(obj, "", "", skip, numericVals)
+	}
+	for _, field := range ds.Fields {
+		raw := lookupPath(obj, field.Column())
+		if raw == nil {
+			continue
+		}
+		v, err := parseField(jsonString(raw), field.Type)
+		if err != nil {
+			log.Printf("Skipping %s of company_id=%s: %v", field.Name, companyID, err)
+			continue
+		}
+		numericVals[field.Name] = v
+	}
+
+	return observation{CompanyID: companyID, Date: parsedTime, Values: numericVals}, true
+}
+
+// lookupPath reads a dotted path from an object. A key holding the whole
+// path wins over nesting; a missing value is nil.
+func lookupPath(obj map[string]any, path string) any {
+	if v, ok := obj[path]; ok {
+		return v
+	}
+	head, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return nil
+	}
+	nested, _ := obj[head].(map[string]any)
+	if nested == nil {
+		return nil
+	}
+	return lookupPath(nested, rest)
+}
+
+// collectNumbers stores every number under obj, except the paths in skip.
+func collectNumbers(obj map[string]any, path, name string, skip map[string]bool, values map[string]float64) {
+	for k, v := range obj {
+		p, n := k, k
+		if path != "" {
+			p, n = path+"."+k, name+"_"+k
+		}
+		if skip[p] {
+			continue
+		}
+		switch v := v.(type) {
+		case float64:
+			values[n] = v
+		case map[string]any:
+			collectNumbers(v, p, n, skip, values)
+		}
+	}
+}
+
+// jsonString renders a decoded JSON scalar the way it would appear in a CSV
+// cell, so both formats share the same parsing.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
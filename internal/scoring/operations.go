@@ -3,6 +3,7 @@ package scoring
 import (
 	"context"
 	"fmt"
+	"math"
 
 	"go.uber.org/zap"
 
@@ -12,17 +13,18 @@ import (
 func evalSum(
 	ctx context.Context,
 	logger *zap.Logger,
-	params []c.Parameter,
+	op c.Operation,
 	key CompanyYearKey,
-	results map[string]float64,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool, error) {
 
+	params := op.Parameters
 	var total float64
 	var anyNonNull bool
 
 	for _, p := range params {
-		val, isNull := getValue(logger, p.Source, key, results, datasets)
+		val, isNull := resolveParam(logger, p, key, results, datasets)
 		if !isNull {
 			total += val
 			anyNonNull = true
@@ -40,19 +42,20 @@ func evalSum(
 func evalOr(
 	ctx context.Context,
 	logger *zap.Logger,
-	params []c.Parameter,
+	op c.Operation,
 	key CompanyYearKey,
-	results map[string]float64,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool, error) {
 
+	params := op.Parameters
 	if len(params) < 2 {
 		logger.Warn("[evalOr] Not enough parameters found")
 		return 0, true, fmt.Errorf("[evalOr] not enough parameters")
 	}
 
-	xVal, xNull := getValue(logger, params[0].Source, key, results, datasets)
-	yVal, yNull := getValue(logger, params[1].Source, key, results, datasets)
+	xVal, xNull := resolveParam(logger, params[0], key, results, datasets)
+	yVal, yNull := resolveParam(logger, params[1], key, results, datasets)
 
 	if !xNull {
 		return xVal, false, nil
@@ -67,19 +70,18 @@ func evalOr(
 func evalDivide(
 	ctx context.Context,
 	logger *zap.Logger,
-	params []c.Parameter,
+	op c.Operation,
 	key CompanyYearKey,
-	results map[string]float64,
-	//results map[CompanyYearKey]map[string]float64,
-
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool, error) {
 
+	params := op.Parameters
 	if len(params) < 2 {
 		return 0, true, fmt.Errorf("[evalDivide] not enough parameters")
 	}
-	xVal, xNull := getValue(logger, params[0].Source, key, results, datasets)
-	yVal, yNull := getValue(logger, params[1].Source, key, results, datasets)
+	xVal, xNull := resolveParam(logger, params[0], key, results, datasets)
+	yVal, yNull := resolveParam(logger, params[1], key, results, datasets)
 
 	if xNull || yNull {
 		return 0, true, nil
@@ -91,18 +93,500 @@ func evalDivide(
 	return xVal / yVal, false, nil
 }
 
+// Null semantics. Aggregating operations (sum, min, max, avg, weighted_sum,
+// coalesce) skip null inputs and are null only if every input is null.
+// rollup follows its missing policy.
+// Positional operations (subtract, multiply, divide, abs, negate, clamp, pow,
+// log, threshold) are null if any input is null. Invalid inputs, such as a
+// zero divisor or the log of a non-positive number, are errors and the
+// metric is null for that key.
+
+// resolveParam resolves one parameter: its literal value, else its source,
+// else its default when the source is null.
+func resolveParam(
+	logger *zap.Logger,
+	p c.Parameter,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool) {
+	return resolveParamAt(logger, p, key, key.Year, results, datasets)
+}
+
+// resolveParamAt resolves a parameter as of another year of the same
+// company. Literals are the same every year.
+func resolveParamAt(
+	logger *zap.Logger,
+	p c.Parameter,
+	key CompanyYearKey,
+	year int,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool) {
+	if p.Value != nil {
+		results.record(InputTrace{Value: *p.Value, Literal: true})
+		return *p.Value, false
+	}
+
+	traceYear := 0
+	if year != key.Year {
+		traceYear = year
+	}
+
+	var val float64
+	isNull := true
+	if p.Source != "" {
+		val, isNull = getValueAt(logger, p.Source, key, year, results, datasets)
+	}
+	if isNull && p.Default != nil {
+		results.record(InputTrace{Source: p.Source, Year: traceYear, Value: *p.Default, Defaulted: true})
+		return *p.Default, false
+	}
+	results.record(InputTrace{Source: p.Source, Year: traceYear, Value: val, Null: isNull})
+	return val, isNull
+}
+
+// nonNullValues resolves every parameter and keeps the non-null values.
+func nonNullValues(
+	logger *zap.Logger,
+	params []c.Parameter,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) []float64 {
+	var values []float64
+	for _, p := range params {
+		val, isNull := resolveParam(logger, p, key, results, datasets)
+		if !isNull {
+			values = append(values, val)
+		}
+	}
+	return values
+}
+
+// allValues resolves the first n parameters, returning false if any of
+// them is null or missing.
+func allValues(
+	logger *zap.Logger,
+	params []c.Parameter,
+	n int,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) ([]float64, bool) {
+	if len(params) < n {
+		return nil, false
+	}
+	values := make([]float64, n)
+	for i := 0; i < n; i++ {
+		val, isNull := resolveParam(logger, params[i], key, results, datasets)
+		if isNull {
+			return nil, false
+		}
+		values[i] = val
+	}
+	return values, true
+}
+
+func evalSubtract(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 2 {
+		return 0, true, fmt.Errorf("[evalSubtract] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 2, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	return values[0] - values[1], false, nil
+}
+
+func evalMultiply(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) == 0 {
+		return 0, true, fmt.Errorf("[evalMultiply] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, len(op.Parameters), key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	product := 1.0
+	for _, v := range values {
+		product *= v
+	}
+	return product, false, nil
+}
+
+func evalMin(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	values := nonNullValues(logger, op.Parameters, key, results, datasets)
+	if len(values) == 0 {
+		return 0, true, nil
+	}
+	lowest := values[0]
+	for _, v := range values[1:] {
+		lowest = math.Min(lowest, v)
+	}
+	return lowest, false, nil
+}
+
+func evalMax(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	values := nonNullValues(logger, op.Parameters, key, results, datasets)
+	if len(values) == 0 {
+		return 0, true, nil
+	}
+	highest := values[0]
+	for _, v := range values[1:] {
+		highest = math.Max(highest, v)
+	}
+	return highest, false, nil
+}
+
+// evalAvg averages the non-null parameters only, so a missing input does
+// not drag the mean towards zero.
+func evalAvg(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	values := nonNullValues(logger, op.Parameters, key, results, datasets)
+	if len(values) == 0 {
+		return 0, true, nil
+	}
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values)), false, nil
+}
+
+// evalWeightedSum adds every non-null parameter multiplied by its weight.
+// Weights are not renormalised when some inputs are null.
+func evalWeightedSum(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	var total float64
+	var anyNonNull bool
+
+	for _, p := range op.Parameters {
+		if p.Weight == nil {
+			return 0, true, fmt.Errorf("[evalWeightedSum] parameter %q has no weight", p.Source)
+		}
+		val, isNull := resolveParam(logger, p, key, results, datasets)
+		if !isNull {
+			total += val * *p.Weight
+			anyNonNull = true
+		}
+	}
+
+	if !anyNonNull {
+		return 0, true, nil
+	}
+	return total, false, nil
+}
+
+// evalRollup is the weighted mean of its children, the metric behind every
+// group. Weights default to 1. Null children are handled by op.Missing: with
+// renormalise their weight is left out of the total, with zero they count as
+// zero, and with null the result is null. A rollup whose children are all
+// null is null whatever the policy.
+func evalRollup(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	var total, totalWeight float64
+	var anyNonNull, anyNull bool
+
+	for _, p := range op.Parameters {
+		weight := 1.0
+		if p.Weight != nil {
+			weight = *p.Weight
+		}
+		val, isNull := resolveParam(logger, p, key, results, datasets)
+		if isNull {
+			anyNull = true
+			if op.Missing == c.MissingZero {
+				totalWeight += weight
+			}
+			continue
+		}
+		total += val * weight
+		totalWeight += weight
+		anyNonNull = true
+	}
+
+	switch {
+	case !anyNonNull:
+		return 0, true, nil
+	case anyNull && op.Missing == c.MissingNull:
+		return 0, true, nil
+	case totalWeight == 0:
+		return 0, true, fmt.Errorf("[evalRollup] weights sum to zero")
+	}
+	return total / totalWeight, false, nil
+}
+
+func evalAbs(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalAbs] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 1, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	return math.Abs(values[0]), false, nil
+}
+
+func evalNegate(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalNegate] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 1, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	return -values[0], false, nil
+}
+
+// evalClamp bounds x to [min, max].
+func evalClamp(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 3 {
+		return 0, true, fmt.Errorf("[evalClamp] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 3, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	x, lower, upper := values[0], values[1], values[2]
+	if lower > upper {
+		return 0, true, fmt.Errorf("[evalClamp] min %g is greater than max %g", lower, upper)
+	}
+	return math.Min(math.Max(x, lower), upper), false, nil
+}
+
+func evalPow(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 2 {
+		return 0, true, fmt.Errorf("[evalPow] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 2, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	result := math.Pow(values[0], values[1])
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, true, fmt.Errorf("[evalPow] %g to the power %g is not a finite number", values[0], values[1])
+	}
+	return result, false, nil
+}
+
+// evalLog is the natural logarithm of x, or the logarithm in base when a
+// second parameter is given.
+func evalLog(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalLog] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, len(op.Parameters), key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	if values[0] <= 0 {
+		return 0, true, fmt.Errorf("[evalLog] log of non-positive value %g", values[0])
+	}
+	if len(values) == 1 {
+		return math.Log(values[0]), false, nil
+	}
+	base := values[1]
+	if base <= 0 || base == 1 {
+		return 0, true, fmt.Errorf("[evalLog] invalid base %g", base)
+	}
+	return math.Log(values[0]) / math.Log(base), false, nil
+}
+
+// evalThreshold is a step function: 1 when x reaches the threshold, else 0.
+func evalThreshold(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 2 {
+		return 0, true, fmt.Errorf("[evalThreshold] not enough parameters")
+	}
+	values, ok := allValues(logger, op.Parameters, 2, key, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	if values[0] >= values[1] {
+		return 1, false, nil
+	}
+	return 0, false, nil
+}
+
+// evalCoalesce returns the first non-null parameter, the N-ary form of or.
+func evalCoalesce(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	for _, p := range op.Parameters {
+		val, isNull := resolveParam(logger, p, key, results, datasets)
+		if !isNull {
+			return val, false, nil
+		}
+	}
+	return 0, true, nil
+}
+
+// evalExpr evaluates the expression compiled when the config was loaded.
+// References resolve exactly like parameter sources.
+func evalExpr(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if op.Expr == nil {
+		return 0, true, fmt.Errorf("[evalExpr] no compiled expression: %v", op.ExprErr)
+	}
+
+	val, isNull, err := op.Expr.Eval(func(source string) (float64, bool) {
+		val, isNull := getValue(logger, source, key, results, datasets)
+		results.record(InputTrace{Source: source, Value: val, Null: isNull})
+		return val, isNull
+	})
+	if err != nil {
+		return 0, true, fmt.Errorf("[evalExpr] %q: %w", op.Expression, err)
+	}
+	return val, isNull, nil
+}
+
 // OperationFn file operations store
 type OperationFn func(
 	ctx context.Context,
 	logger *zap.Logger,
-	params []c.Parameter,
+	op c.Operation,
 	key CompanyYearKey,
-	results map[string]float64,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool, error)
 
 var Operations = map[string]OperationFn{
-	"sum":    evalSum,
-	"or":     evalOr,
-	"divide": evalDivide,
+	"sum":            evalSum,
+	"or":             evalOr,
+	"divide":         evalDivide,
+	"expr":           evalExpr,
+	"subtract":       evalSubtract,
+	"multiply":       evalMultiply,
+	"min":            evalMin,
+	"max":            evalMax,
+	"avg":            evalAvg,
+	"weighted_sum":   evalWeightedSum,
+	"rollup":         evalRollup,
+	"abs":            evalAbs,
+	"negate":         evalNegate,
+	"clamp":          evalClamp,
+	"pow":            evalPow,
+	"log":            evalLog,
+	"threshold":      evalThreshold,
+	"step":           evalThreshold,
+	"coalesce":       evalCoalesce,
+	"lag":            evalLag,
+	"yoy_change":     evalYoYChange,
+	"yoy_pct_change": evalYoYPctChange,
+	"rolling_avg":    evalRollingAvg,
+	"cagr":           evalCAGR,
+	"carry_forward":  evalCarryForward,
 }
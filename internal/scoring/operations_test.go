@@ -0,0 +1,139 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// opKey and opDatasets are the inputs shared by the operation tests:
+// waste.neg is negative, waste.zero is 0 and waste.missing is null.
+var opKey = CompanyYearKey{CompanyID: "1", Year: 2024}
+
+var opDatasets = tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+	"waste": {opKey: {"two": 2, "three": 3, "ten": 10, "neg": -4, "zero": 0}},
+})
+
+func sources(names ...string) []c.Parameter {
+	params := make([]c.Parameter, len(names))
+	for i, name := range names {
+		params[i] = c.Parameter{Source: "waste." + name}
+	}
+	return params
+}
+
+func weighted(name string, weight float64) c.Parameter {
+	return c.Parameter{Source: "waste." + name, Weight: &weight}
+}
+
+func literal(v float64) c.Parameter {
+	return c.Parameter{Value: &v}
+}
+
+func withDefault(name string, d float64) c.Parameter {
+	return c.Parameter{Source: "waste." + name, Default: &d}
+}
+
+func TestOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       string
+		params   []c.Parameter
+		want     float64
+		wantNull bool
+		wantErr  bool
+	}{
+		{name: "sum", op: "sum", params: sources("two", "three"), want: 5},
+		{name: "sum skips null", op: "sum", params: sources("two", "missing"), want: 2},
+		{name: "sum all null", op: "sum", params: sources("missing"), wantNull: true},
+
+		{name: "subtract", op: "subtract", params: sources("ten", "three"), want: 7},
+		{name: "subtract null", op: "subtract", params: sources("ten", "missing"), wantNull: true},
+
+		{name: "multiply", op: "multiply", params: sources("two", "three", "ten"), want: 60},
+		{name: "multiply null", op: "multiply", params: sources("two", "missing"), wantNull: true},
+
+		{name: "divide", op: "divide", params: sources("ten", "two"), want: 5},
+		{name: "divide null", op: "divide", params: sources("missing", "two"), wantNull: true},
+		{name: "divide by zero", op: "divide", params: sources("ten", "zero"), wantErr: true},
+
+		{name: "min", op: "min", params: sources("ten", "neg", "two"), want: -4},
+		{name: "min skips null", op: "min", params: sources("missing", "ten"), want: 10},
+		{name: "min all null", op: "min", params: sources("missing"), wantNull: true},
+
+		{name: "max", op: "max", params: sources("ten", "neg", "two"), want: 10},
+		{name: "max skips null", op: "max", params: sources("neg", "missing"), want: -4},
+		{name: "max all null", op: "max", params: sources("missing"), wantNull: true},
+
+		{name: "avg", op: "avg", params: sources("two", "ten"), want: 6},
+		{name: "avg skips null", op: "avg", params: sources("two", "missing", "ten"), want: 6},
+		{name: "avg all null", op: "avg", params: sources("missing"), wantNull: true},
+
+		{name: "weighted_sum", op: "weighted_sum", params: []c.Parameter{weighted("two", 0.5), weighted("ten", 2)}, want: 21},
+		{name: "weighted_sum skips null", op: "weighted_sum", params: []c.Parameter{weighted("missing", 0.5), weighted("ten", 2)}, want: 20},
+		{name: "weighted_sum all null", op: "weighted_sum", params: []c.Parameter{weighted("missing", 1)}, wantNull: true},
+		{name: "weighted_sum without weight", op: "weighted_sum", params: sources("two"), wantErr: true},
+
+		{name: "abs", op: "abs", params: sources("neg"), want: 4},
+		{name: "abs null", op: "abs", params: sources("missing"), wantNull: true},
+
+		{name: "negate", op: "negate", params: sources("two"), want: -2},
+		{name: "negate null", op: "negate", params: sources("missing"), wantNull: true},
+
+		{name: "clamp above", op: "clamp", params: sources("ten", "two", "three"), want: 3},
+		{name: "clamp below", op: "clamp", params: sources("neg", "two", "three"), want: 2},
+		{name: "clamp inside", op: "clamp", params: sources("three", "two", "ten"), want: 3},
+		{name: "clamp null bound", op: "clamp", params: sources("three", "missing", "ten"), wantNull: true},
+		{name: "clamp inverted bounds", op: "clamp", params: sources("three", "ten", "two"), wantErr: true},
+
+		{name: "pow", op: "pow", params: sources("two", "ten"), want: 1024},
+		{name: "pow null", op: "pow", params: sources("two", "missing"), wantNull: true},
+		{name: "pow not finite", op: "pow", params: sources("zero", "neg"), wantErr: true},
+
+		{name: "log natural", op: "log", params: sources("ten"), want: 2.302585092994046},
+		{name: "log base", op: "log", params: sources("ten", "ten"), want: 1},
+		{name: "log null", op: "log", params: sources("missing"), wantNull: true},
+		{name: "log non-positive", op: "log", params: sources("neg"), wantErr: true},
+		{name: "log invalid base", op: "log", params: sources("ten", "zero"), wantErr: true},
+
+		{name: "threshold reached", op: "threshold", params: sources("ten", "ten"), want: 1},
+		{name: "threshold not reached", op: "threshold", params: sources("two", "three"), want: 0},
+		{name: "threshold null", op: "threshold", params: sources("missing", "three"), wantNull: true},
+		{name: "step", op: "step", params: sources("three", "two"), want: 1},
+
+		{name: "divide by literal", op: "divide", params: []c.Parameter{sources("ten")[0], literal(1000)}, want: 0.01},
+		{name: "default replaces null", op: "sum", params: []c.Parameter{withDefault("missing", 0), withDefault("two", 7)}, want: 2},
+		{name: "default keeps positional non-null", op: "subtract", params: []c.Parameter{withDefault("missing", 1), sources("three")[0]}, want: -2},
+		{name: "literal only", op: "max", params: []c.Parameter{literal(-1), literal(3)}, want: 3},
+
+		{name: "or", op: "or", params: sources("missing", "two"), want: 2},
+		{name: "coalesce", op: "coalesce", params: sources("missing", "missing", "three", "two"), want: 3},
+		{name: "coalesce all null", op: "coalesce", params: sources("missing", "missing"), wantNull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opFn, ok := Operations[tt.op]
+			if !assert.True(t, ok, "operation %q not registered", tt.op) {
+				return
+			}
+			results := &Results{score: "score", values: map[string]map[string]float64{"score": {}}}
+			op := c.Operation{Type: tt.op, Parameters: tt.params}
+
+			got, isNull, err := opFn(context.Background(), zap.NewNop(), op, opKey, results, opDatasets)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNull, isNull)
+			if !tt.wantNull {
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
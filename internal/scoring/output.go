@@ -0,0 +1,125 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// ErrUnknownMetric is returned when a request selects a metric the score
+// does not output.
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// Column is one metric of the score output, rounded to Precision decimal
+// places with the Rounding mode.
+type Column struct {
+	Metric    string
+	Header    string
+	Precision int
+	Rounding  string
+}
+
+// OutputColumns returns the columns of scoreConfig in output order. When
+// selected is not empty only those metrics are returned, still in output
+// order; selecting a metric that is not an output is an error.
+func OutputColumns(scoreConfig *c.Config, selected []string) ([]Column, error) {
+	type column struct {
+		Column
+		order *int
+	}
+	var columns []column
+	outputs := make(map[string]bool)
+	for _, m := range scoreConfig.Metrics {
+		if !m.IsOutput() {
+			continue
+		}
+		outputs[m.Name] = true
+		columns = append(columns, column{Column{
+			Metric:    m.Name,
+			Header:    m.Header(m.Name),
+			Precision: m.OutputPrecision(),
+			Rounding:  m.OutputRounding(),
+		}, m.Order})
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		a, b := columns[i].order, columns[j].order
+		return a != nil && (b == nil || *a < *b)
+	})
+
+	want := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		if !outputs[name] {
+			return nil, fmt.Errorf("%w %q in score %q", ErrUnknownMetric, name, scoreConfig.Name)
+		}
+		want[name] = true
+	}
+
+	out := make([]Column, 0, len(columns))
+	for _, col := range columns {
+		if len(want) == 0 || want[col.Metric] {
+			out = append(out, col.Column)
+		}
+	}
+	return out, nil
+}
+
+// outputMetrics copies the output columns out of a key's target metrics,
+// rounded. This is the only place output values are rounded, so every
+// transport returns the same numbers; metrics that read other metrics see
+// them unrounded.
+func (p *Plan) outputMetrics(values map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(p.Columns))
+	for _, col := range p.Columns {
+		if val, ok := values[col.Metric]; ok {
+			out[col.Metric] = roundTo(val, col.Precision, col.Rounding)
+		}
+	}
+	return out
+}
+
+// Format renders a value of the column with exactly its precision.
+func (col Column) Format(val float64) string {
+	return strconv.FormatFloat(val, 'f', col.Precision, 64)
+}
+
+// prune keeps in order only the metrics the output columns depend on,
+// directly or through other metrics and scores.
+func prune(
+	order []metricRef,
+	graph map[metricRef][]metricRef,
+	target string,
+	columns []Column,
+) []metricRef {
+	dependencies := make(map[metricRef][]metricRef)
+	for ref, dependents := range graph {
+		for _, dependent := range dependents {
+			dependencies[dependent] = append(dependencies[dependent], ref)
+		}
+	}
+
+	needed := make(map[metricRef]bool)
+	var visit func(ref metricRef)
+	visit = func(ref metricRef) {
+		if needed[ref] {
+			return
+		}
+		needed[ref] = true
+		for _, dep := range dependencies[ref] {
+			visit(dep)
+		}
+	}
+	for _, col := range columns {
+		visit(metricRef{Score: target, Metric: col.Metric})
+	}
+
+	pruned := make([]metricRef, 0, len(needed))
+	for _, ref := range order {
+		if needed[ref] {
+			pruned = append(pruned, ref)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,174 @@
+package scoring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+const columnsConfig = `name: columns
+metrics:
+  - name: helper
+    output: false
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: unused
+    visibility: internal
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_2
+  - name: metric_1
+    display_name: Waste (t)
+    operation:
+      type: sum
+      parameters:
+        - source: self.helper
+  - name: metric_2
+    order: 2
+    precision: 0
+    rounding: truncate
+    operation:
+      type: multiply
+      parameters:
+        - source: self.helper
+        - value: 10
+  - name: metric_3
+    order: 1
+    operation:
+      type: sum
+      parameters:
+        - source: emissions.emi_1
+groups:
+  - name: overall
+    display_name: Overall
+    children:
+      - metric: metric_1
+      - metric: metric_3
+`
+
+func TestOutputColumns(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(columnsConfig))
+	require.NoError(t, err)
+	require.Empty(t, ValidateConfig(scoreConfig, nil, nil))
+
+	columns, err := OutputColumns(scoreConfig, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Metric: "metric_3", Header: "metric_3", Precision: 2, Rounding: c.RoundHalfEven},
+		{Metric: "metric_2", Header: "metric_2", Precision: 0, Rounding: c.RoundTruncate},
+		{Metric: "metric_1", Header: "Waste (t)", Precision: 2, Rounding: c.RoundHalfEven},
+		{Metric: "overall", Header: "Overall", Precision: 2, Rounding: c.RoundHalfEven},
+	}, columns)
+
+	columns, err = OutputColumns(scoreConfig, []string{"overall", "metric_2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metric_2", "overall"}, []string{columns[0].Metric, columns[1].Metric})
+
+	for _, name := range []string{"helper", "metric_9"} {
+		_, err = OutputColumns(scoreConfig, []string{name})
+		assert.ErrorIs(t, err, ErrUnknownMetric, name)
+	}
+}
+
+func TestBuildSelectedPlanPrunes(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(columnsConfig))
+	require.NoError(t, err)
+
+	ref := func(metric string) metricRef { return metricRef{Score: "columns", Metric: metric} }
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+	assert.NotContains(t, plan.Order, ref("unused"))
+	assert.Len(t, plan.Order, 5)
+
+	plan, err = BuildSelectedPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t), []string{"metric_2"})
+	require.NoError(t, err)
+	assert.Equal(t, []metricRef{ref("helper"), ref("metric_2")}, plan.Order)
+
+	key := CompanyYearKey{CompanyID: "1", Year: 2024}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste":     {key: {"was_1": 3.09, "was_2": 5}},
+		"emissions": {key: {"emi_1": 1}},
+	})
+	rows := parallelComputeScores(context.Background(), zap.NewNop(), []CompanyYearKey{key}, plan, datasets, 1)
+	assert.Equal(t, []ScoredRow{{Key: key, Metrics: map[string]float64{"metric_2": 30}}}, rows)
+
+	_, err = BuildSelectedPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t), []string{"helper"})
+	assert.ErrorIs(t, err, ErrUnknownMetric)
+}
+
+func TestValidateConfigPresentation(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: presentation
+metrics:
+  - name: metric_1
+    visibility: hidden
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: metric_2
+    output: true
+    visibility: internal
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: metric_3
+    display_name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+groups:
+  - name: overall
+    children:
+      - metric: metric_1
+        display_name: Total
+      - metric: metric_3
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, nil, nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`3:11: metric_1: unknown visibility "hidden", expected public or internal`,
+		`9:11: metric_2: output: true conflicts with visibility "internal"`,
+		`16:11: metric_3: column "metric_1" is also the header of "metric_1"`,
+		`25:17: leaf "metric_1" cannot set output options or metadata, set them on the metric`,
+	}, got)
+}
+
+func TestCalculateScoreHandlerUnknownMetric(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	configs, err := c.NewRegistry(zap.NewNop(), "", nil)
+	require.NoError(t, err)
+	handler := &Handler{Logger: zap.NewNop(), Configs: configs, ConfigFileName: "score_1.yaml", Data: testDataService(t)}
+
+	r := gin.New()
+	r.GET("/run-scores", handler.CalculateScoreHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/run-scores?metrics=metric_4,metric_9", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown metric "metric_9"`)
+}
+
+func TestSelectedMetrics(t *testing.T) {
+	assert.Equal(t, []string{"metric_4", "metric_1", "metric_2"},
+		selectedMetrics([]string{"metric_4, metric_1", "metric_2", ""}))
+	assert.Nil(t, selectedMetrics(nil))
+}
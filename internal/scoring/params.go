@@ -0,0 +1,246 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// Param is one named parameter of an operation. Literal parameters must be
+// given as a value, not a source, and pass Check when it is set.
+type Param struct {
+	Name     string
+	Optional bool
+	Literal  bool
+	Check    func(v float64) error
+}
+
+// Signature declares the parameters an operation accepts. Operations with
+// named Params bind each parameter by its param name, falling back to
+// position for unnamed ones. Variadic operations take MinArgs or more
+// unnamed parameters.
+type Signature struct {
+	Params   []Param
+	Variadic bool
+	MinArgs  int
+}
+
+func named(names ...string) Signature {
+	params := make([]Param, len(names))
+	for i, name := range names {
+		params[i] = Param{Name: name}
+	}
+	return Signature{Params: params}
+}
+
+func variadic(minArgs int) Signature {
+	return Signature{Variadic: true, MinArgs: minArgs}
+}
+
+// withOptional marks the given parameters of the signature as optional.
+func (s Signature) withOptional(names ...string) Signature {
+	params := append([]Param(nil), s.Params...)
+	for i := range params {
+		if contains(names, params[i].Name) {
+			params[i].Optional = true
+		}
+	}
+	s.Params = params
+	return s
+}
+
+// withLiteral marks the given parameters of the signature as literal
+// values accepted by check.
+func (s Signature) withLiteral(check func(v float64) error, names ...string) Signature {
+	params := append([]Param(nil), s.Params...)
+	for i := range params {
+		if contains(names, params[i].Name) {
+			params[i].Literal = true
+			params[i].Check = check
+		}
+	}
+	s.Params = params
+	return s
+}
+
+func positiveWhole(v float64) error {
+	if v < 1 || v != math.Trunc(v) {
+		return fmt.Errorf("must be a positive whole number, got %g", v)
+	}
+	return nil
+}
+
+func fraction(v float64) error {
+	if v < 0 || v > 1 {
+		return fmt.Errorf("must be between 0 and 1, got %g", v)
+	}
+	return nil
+}
+
+// Signatures is the parameter signature of every entry in Operations and
+// CrossSectional.
+var Signatures = map[string]Signature{
+	"sum":            variadic(1),
+	"or":             named("x", "y"),
+	"divide":         named("x", "y"),
+	"expr":           {},
+	"subtract":       named("x", "y"),
+	"multiply":       variadic(1),
+	"min":            variadic(1),
+	"max":            variadic(1),
+	"avg":            variadic(1),
+	"weighted_sum":   variadic(1),
+	"rollup":         variadic(1),
+	"abs":            named("x"),
+	"negate":         named("x"),
+	"clamp":          named("x", "min", "max"),
+	"pow":            named("base", "exponent"),
+	"log":            named("x", "base").withOptional("base"),
+	"threshold":      named("x", "threshold"),
+	"step":           named("x", "threshold"),
+	"coalesce":       variadic(1),
+	"lag":            named("x", "n").withOptional("n").withLiteral(positiveWhole, "n"),
+	"yoy_change":     named("x"),
+	"yoy_pct_change": named("x"),
+	"rolling_avg":    named("x", "window").withLiteral(positiveWhole, "window"),
+	"cagr":           named("x", "years").withLiteral(positiveWhole, "years"),
+	"carry_forward":  named("x"),
+
+	"percentile_rank": named("x", "group").withOptional("group"),
+	"zscore":          named("x", "group").withOptional("group"),
+	"minmax_scale":    named("x", "group").withOptional("group"),
+	"winsorize":       named("x", "lower", "upper", "group").withOptional("group").withLiteral(fraction, "lower", "upper"),
+}
+
+func (s Signature) names() []string {
+	names := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func (s Signature) required() int {
+	n := 0
+	for _, p := range s.Params {
+		if !p.Optional {
+			n++
+		}
+	}
+	return n
+}
+
+// bindError is a binding problem, positioned at the parameter that caused
+// it or at the operation.
+type bindError struct {
+	Pos     c.Position
+	Message string
+}
+
+func (e bindError) Error() string {
+	return e.Message
+}
+
+// bindParameters orders the parameters of op to match its signature, so
+// operations can read them by position: parameters with a param name go to
+// that slot, unnamed ones fill the remaining slots in order. Absent optional
+// parameters are left with an empty Source; trailing ones are dropped.
+// Every problem found is returned.
+func bindParameters(op c.Operation, sig Signature) ([]c.Parameter, []bindError) {
+	params := op.Parameters
+	var errs []bindError
+	fail := func(pos c.Position, format string, args ...any) {
+		errs = append(errs, bindError{Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if sig.Variadic {
+		if len(params) < sig.MinArgs {
+			fail(op.Pos, "%s expects at least %d parameters, got %d", op.Type, sig.MinArgs, len(params))
+		}
+		for _, p := range params {
+			if p.Param != "" {
+				fail(p.Pos, "%s does not take named parameters, got param %q", op.Type, p.Param)
+			}
+		}
+		return params, errs
+	}
+
+	switch {
+	case len(params) < sig.required():
+		fail(op.Pos, "%s expects at least %d parameters, got %d", op.Type, sig.required(), len(params))
+	case len(params) > len(sig.Params):
+		fail(op.Pos, "%s expects at most %d parameters, got %d", op.Type, len(sig.Params), len(params))
+	}
+
+	names := sig.names()
+	slots := make([]*c.Parameter, len(sig.Params))
+	for i := range params {
+		p := &params[i]
+		if p.Param == "" {
+			continue
+		}
+		idx := indexOf(names, p.Param)
+		switch {
+		case idx < 0 && len(names) == 0:
+			fail(p.Pos, "%s does not take named parameters, got param %q", op.Type, p.Param)
+		case idx < 0:
+			fail(p.Pos, "unknown param %q for %s, expected one of %v", p.Param, op.Type, names)
+		case slots[idx] != nil:
+			fail(p.Pos, "param %q bound more than once", p.Param)
+		default:
+			slots[idx] = p
+		}
+	}
+
+	next := 0
+	for i := range params {
+		p := &params[i]
+		if p.Param != "" {
+			continue
+		}
+		for next < len(slots) && slots[next] != nil {
+			next++
+		}
+		if next == len(slots) {
+			break // reported as too many parameters above
+		}
+		slots[next] = p
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	bound := make([]c.Parameter, len(slots))
+	last := 0
+	for i, p := range slots {
+		if p == nil {
+			if !sig.Params[i].Optional {
+				fail(op.Pos, "%s requires param %q", op.Type, sig.Params[i].Name)
+			}
+			continue
+		}
+		bound[i] = *p
+		last = i + 1
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return bound[:last], nil
+}
+
+// bindOperation returns op with its parameters in signature order, or the
+// first binding error.
+func bindOperation(op c.Operation) (c.Operation, error) {
+	sig, ok := Signatures[op.Type]
+	if !ok {
+		return op, fmt.Errorf("unknown operation %q", op.Type)
+	}
+	bound, errs := bindParameters(op, sig)
+	if len(errs) > 0 {
+		return op, errs[0]
+	}
+	op.Parameters = bound
+	return op, nil
+}
@@ -0,0 +1,161 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestEveryOperationHasSignature(t *testing.T) {
+	for name := range Operations {
+		_, ok := Signatures[name]
+		assert.True(t, ok, "operation %q has no signature", name)
+	}
+	for name := range CrossSectional {
+		_, ok := Signatures[name]
+		assert.True(t, ok, "operation %q has no signature", name)
+	}
+	for name := range Signatures {
+		_, perKey := Operations[name]
+		_, crossSectional := CrossSectional[name]
+		assert.True(t, perKey != crossSectional, "signature %q must have exactly one operation", name)
+	}
+}
+
+func TestBindParameters(t *testing.T) {
+	param := func(source, name string) c.Parameter {
+		return c.Parameter{Source: source, Param: name}
+	}
+
+	tests := []struct {
+		name    string
+		op      string
+		params  []c.Parameter
+		want    []string
+		wantErr []string
+	}{
+		{
+			name:   "positional",
+			op:     "divide",
+			params: []c.Parameter{param("a.x", ""), param("a.y", "")},
+			want:   []string{"a.x", "a.y"},
+		},
+		{
+			name:   "named out of order",
+			op:     "divide",
+			params: []c.Parameter{param("a.y", "y"), param("a.x", "x")},
+			want:   []string{"a.x", "a.y"},
+		},
+		{
+			name:   "named and positional",
+			op:     "clamp",
+			params: []c.Parameter{param("a.hi", "max"), param("a.x", ""), param("a.lo", "")},
+			want:   []string{"a.x", "a.lo", "a.hi"},
+		},
+		{
+			name:   "optional omitted",
+			op:     "log",
+			params: []c.Parameter{param("a.x", "x")},
+			want:   []string{"a.x"},
+		},
+		{
+			name:   "optional named",
+			op:     "log",
+			params: []c.Parameter{param("a.b", "base"), param("a.x", "")},
+			want:   []string{"a.x", "a.b"},
+		},
+		{
+			name:   "variadic",
+			op:     "sum",
+			params: []c.Parameter{param("a.x", ""), param("a.y", ""), param("a.z", "")},
+			want:   []string{"a.x", "a.y", "a.z"},
+		},
+		{
+			name:    "unknown name",
+			op:      "divide",
+			params:  []c.Parameter{param("a.x", "x"), param("a.y", "denominator")},
+			wantErr: []string{`unknown param "denominator" for divide, expected one of [x y]`},
+		},
+		{
+			name:    "duplicate name",
+			op:      "subtract",
+			params:  []c.Parameter{param("a.x", "y"), param("a.y", "y")},
+			wantErr: []string{`param "y" bound more than once`},
+		},
+		{
+			name:    "required missing",
+			op:      "log",
+			params:  []c.Parameter{param("a.b", "base")},
+			wantErr: []string{`log requires param "x"`},
+		},
+		{
+			name:    "too many",
+			op:      "abs",
+			params:  []c.Parameter{param("a.x", ""), param("a.y", "")},
+			wantErr: []string{"abs expects at most 1 parameters, got 2"},
+		},
+		{
+			name:    "named variadic",
+			op:      "avg",
+			params:  []c.Parameter{param("a.x", "x")},
+			wantErr: []string{`avg does not take named parameters, got param "x"`},
+		},
+		{
+			name:    "variadic too few",
+			op:      "coalesce",
+			wantErr: []string{"coalesce expects at least 1 parameters, got 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := c.Operation{Type: tt.op, Parameters: tt.params}
+			bound, errs := bindParameters(op, Signatures[tt.op])
+
+			var gotErrs []string
+			for _, e := range errs {
+				gotErrs = append(gotErrs, e.Message)
+			}
+			assert.Equal(t, tt.wantErr, gotErrs)
+			if tt.wantErr != nil {
+				return
+			}
+
+			var got []string
+			for _, p := range bound {
+				got = append(got, p.Source)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuildPlanBindsNamedParameters(t *testing.T) {
+	scoreConfig := &c.Config{Name: "score_1", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "divide", Parameters: []c.Parameter{
+			{Source: "waste.was_1", Param: "y"},
+			{Source: "emissions.emi_1", Param: "x"},
+		}}},
+	}}
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	key := CompanyYearKey{CompanyID: "1", Year: 2024}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste":     {key: {"was_1": 4}},
+		"emissions": {key: {"emi_1": 10}},
+	})
+	metrics := computeScoresForKey(context.Background(), zap.NewNop(), key, plan, datasets)
+	assert.Equal(t, map[string]float64{"metric_1": 2.5}, metrics)
+
+	scoreConfig.Metrics[0].Operation.Parameters[0].Param = "x"
+	_, err = BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `param "x" bound more than once`)
+}
@@ -0,0 +1,311 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// ParquetLoader reads Parquet files one row group at a time. Only the key,
+// the date and the field columns of the dataset are read, so a dataset
+// projected to the fields scores reference skips every other column.
+type ParquetLoader struct{}
+
+func (ParquetLoader) loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	return loadDatasetParquet(ctx, src, ds, emit)
+}
+
+// parquetField is a field and the leaf column holding it.
+type parquetField struct {
+	field  c.Field
+	column parquet.LeafColumn
+}
+
+func loadDatasetParquet(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	rc, err := src.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	r, size, cleanup, err := randomAccess(rc, src.object.Size)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %v", err)
+	}
+	schema := file.Schema()
+
+	key, okKey := lookupLeaf(schema, ds.Keys[0])
+	date, okDate := lookupLeaf(schema, ds.Date.Column)
+	if !okKey || (ds.Date.Column != "" && !okDate) {
+		return fmt.Errorf("missing required columns (%s, %s)", ds.Keys[0], ds.Date.Column)
+	}
+
+	fields, err := parquetFields(schema, ds)
+	if err != nil {
+		return err
+	}
+	for _, col := range append([]parquet.LeafColumn{key, date}, fieldColumns(fields)...) {
+		if col.Node != nil && col.MaxRepetitionLevel > 0 {
+			return fmt.Errorf("column %s is repeated", strings.Join(col.Path, "."))
+		}
+	}
+
+	for n, rowGroup := range file.RowGroups() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := emitRowGroup(rowGroup, ds, key, date, fields, emit); err != nil {
+			return fmt.Errorf("row group %d: %w", n, err)
+		}
+	}
+	return nil
+}
+
+// randomAccess returns a reader Parquet can seek in: local files are read
+// in place, remote objects are first copied to a temporary file, removed
+// by cleanup.
+func randomAccess(rc io.Reader, size int64) (io.ReaderAt, int64, func(), error) {
+	if r, ok := rc.(io.ReaderAt); ok {
+		return r, size, func() {}, nil
+	}
+	tmp, err := os.CreateTemp("", "dataset-*.parquet")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err = io.Copy(tmp, rc)
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, fmt.Errorf("failed to copy object: %w", err)
+	}
+	return tmp, size, cleanup, nil
+}
+
+// emitRowGroup reads the projected columns of one row group and emits its
+// rows. Null values are missing, like empty CSV cells.
+func emitRowGroup(
+	rowGroup parquet.RowGroup,
+	ds c.Dataset,
+	key, date parquet.LeafColumn,
+	fields []parquetField,
+	emit func(observation),
+) error {
+	chunks := rowGroup.ColumnChunks()
+	numRows := rowGroup.NumRows()
+
+	keys, err := readColumn(chunks[key.ColumnIndex], numRows)
+	if err != nil {
+		return err
+	}
+	var dates []parquet.Value
+	if date.Node != nil {
+		if dates, err = readColumn(chunks[date.ColumnIndex], numRows); err != nil {
+			return err
+		}
+	}
+	values := make([][]parquet.Value, len(fields))
+	for i, field := range fields {
+		if values[i], err = readColumn(chunks[field.column.ColumnIndex], numRows); err != nil {
+			return err
+		}
+	}
+
+	for row := range int(numRows) {
+		companyID := parquetString(keys[row])
+		var parsedTime time.Time
+		if dates != nil {
+			parsedTime, err = parquetDate(companyID, dates[row], date, ds)
+		} else {
+			parsedTime, err = rowDate(companyID, "", ds)
+		}
+		if err != nil {
+			log.Printf("Skipping row for %s: %v", companyID, err)
+			continue
+		}
+
+		numericVals := map[string]float64{}
+		for i, field := range fields {
+			v := values[i][row]
+			if v.IsNull() {
+				continue
+			}
+			parsed, err := parseField(parquetString(v), field.field.Type)
+			if err != nil {
+				log.Printf("Skipping %s of company_id=%s: %v", field.field.Name, companyID, err)
+				continue
+			}
+			numericVals[field.field.Name] = parsed
+		}
+
+		emit(observation{
+			CompanyID: companyID,
+			Date:      parsedTime,
+			Values:    numericVals,
+		})
+	}
+	return nil
+}
+
+// parquetFields resolves the columns of the declared fields. Without a
+// fields list every numeric column other than the key and the date is read,
+// nested ones named by their path joined with underscores.
+func parquetFields(schema *parquet.Schema, ds c.Dataset) ([]parquetField, error) {
+	if ds.InfersFields() {
+		var fields []parquetField
+		for _, path := range schema.Columns() {
+			column := strings.Join(path, ".")
+			if column == ds.Keys[0] || column == ds.Date.Column {
+				continue
+			}
+			leaf, _ := schema.Lookup(path...)
+			if !numericLeaf(leaf) {
+				continue
+			}
+			fields = append(fields, parquetField{
+				field:  c.Field{Name: strings.Join(path, "_"), Type: c.FieldNumber},
+				column: leaf,
+			})
+		}
+		return fields, nil
+	}
+
+	fields := make([]parquetField, len(ds.Fields))
+	for i, field := range ds.Fields {
+		leaf, ok := lookupLeaf(schema, field.Column())
+		if !ok {
+			return nil, fmt.Errorf("missing column for field %q", field.Name)
+		}
+		fields[i] = parquetField{field: field, column: leaf}
+	}
+	return fields, nil
+}
+
+func fieldColumns(fields []parquetField) []parquet.LeafColumn {
+	columns := make([]parquet.LeafColumn, len(fields))
+	for i, field := range fields {
+		columns[i] = field.column
+	}
+	return columns
+}
+
+// lookupLeaf finds the leaf column at a dotted path.
+func lookupLeaf(schema *parquet.Schema, path string) (parquet.LeafColumn, bool) {
+	if path == "" {
+		return parquet.LeafColumn{}, false
+	}
+	leaf, ok := schema.Lookup(strings.Split(path, ".")...)
+	if !ok || !leaf.Node.Leaf() {
+		return parquet.LeafColumn{}, false
+	}
+	return leaf, true
+}
+
+// numericLeaf reports whether a column holds plain numbers, rather than
+// dates, times or timestamps stored as integers.
+func numericLeaf(leaf parquet.LeafColumn) bool {
+	typ := leaf.Node.Type()
+	if logical := typ.LogicalType(); logical != nil && logical.Integer == nil {
+		return false
+	}
+	switch typ.Kind() {
+	case parquet.Int32, parquet.Int64, parquet.Float, parquet.Double:
+		return true
+	}
+	return false
+}
+
+// readColumn reads the values of a column chunk, page by page.
+func readColumn(chunk parquet.ColumnChunk, numRows int64) ([]parquet.Value, error) {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	values := make([]parquet.Value, 0, numRows)
+	for {
+		page, err := pages.ReadPage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]parquet.Value, page.NumValues())
+		read := 0
+		reader := page.Values()
+		for read < len(buf) {
+			n, err := reader.ReadValues(buf[read:])
+			read += n
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, buf[:read]...)
+	}
+	if int64(len(values)) != numRows {
+		return nil, fmt.Errorf("column %d has %d values for %d rows", chunk.Column(), len(values), numRows)
+	}
+	return values, nil
+}
+
+// parquetDate reads the date of a row. DATE and TIMESTAMP columns are
+// dates already; any other column is parsed in the declared format.
+func parquetDate(companyID string, v parquet.Value, column parquet.LeafColumn, ds c.Dataset) (time.Time, error) {
+	if v.IsNull() {
+		return rowDate(companyID, "", ds)
+	}
+	logical := column.Node.Type().LogicalType()
+	switch {
+	case logical != nil && logical.Date != nil:
+		return checkDate(companyID, time.Unix(int64(v.Int32())*24*60*60, 0).UTC())
+	case logical != nil && logical.Timestamp != nil:
+		unit := logical.Timestamp.Unit
+		var t time.Time
+		switch {
+		case unit.Millis != nil:
+			t = time.UnixMilli(v.Int64())
+		case unit.Micros != nil:
+			t = time.UnixMicro(v.Int64())
+		default:
+			t = time.Unix(0, v.Int64())
+		}
+		return checkDate(companyID, t.UTC())
+	}
+	return rowDate(companyID, parquetString(v), ds)
+}
+
+// parquetString renders a value the way it would appear in a CSV cell, so
+// every format shares the same parsing.
+func parquetString(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	case parquet.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10)
+	case parquet.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'f', -1, 64)
+	default:
+		return string(v.ByteArray())
+	}
+}
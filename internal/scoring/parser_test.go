@@ -2,16 +2,59 @@ package scoring
 
 import (
 	"context"
+	"encoding/csv"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"esgbook-software-engineer-technical-test-2024/internal/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
+// disclosureDataset declares dis_1 to dis_4 keyed by company_id and dated by
+// date in either format.
+func disclosureDataset(location string) c.Dataset {
+	return c.Dataset{
+		Name:     "disclosure",
+		Location: location,
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "date"},
+		Fields: []c.Field{
+			{Name: "dis_1", Type: c.FieldNumber},
+			{Name: "dis_2", Type: c.FieldNumber},
+			{Name: "dis_3", Type: c.FieldNumber},
+			{Name: "dis_4", Type: c.FieldNumber},
+		},
+	}
+}
+
+// loadRows collects every observation a loader emits for the file at path.
+func loadRows(t *testing.T, loader DataLoader, path string, ds c.Dataset) []observation {
+	src := source{bucket: blob.Dir(filepath.Dir(path)), object: blob.Object{Key: filepath.Base(path)}}
+	var rows []observation
+	err := loader.loadData(context.Background(), src, ds, func(row observation) {
+		rows = append(rows, row)
+	})
+	require.NoError(t, err)
+	return rows
+}
+
+// calendarYears aggregates disclosure rows by calendar year, keeping the
+// latest row.
+func calendarYears(t *testing.T, rows []observation) map[CompanyYearKey]map[string]float64 {
+	periods, err := newPeriods(c.FiscalYear{})
+	require.NoError(t, err)
+	results, err := aggregate(disclosureDataset(""), rows, periods, c.GrainYear)
+	require.NoError(t, err)
+	return rowsOf(results)
+}
+
 func TestLoadDatasetCSV(t *testing.T) {
 	// 1) Create a temporary file with minimal CSV content for testing.
 	csvContent := `company_id,date,dis_1,dis_2,dis_3,dis_4
@@ -28,8 +71,8 @@ func TestLoadDatasetCSV(t *testing.T) {
 	require.NoError(t, tmpfile.Close())
 
 	// 2) Call the function under test
-	results, err := config.LoadDatasetCSV(tmpfile.Name())
-	require.NoError(t, err)
+	rows := loadRows(t, CSVLoader{}, tmpfile.Name(), disclosureDataset(tmpfile.Name()))
+	results := calendarYears(t, rows)
 
 	// 3) Validate what we expect
 	//
@@ -55,6 +98,42 @@ func TestLoadDatasetCSV(t *testing.T) {
 	assert.Equal(t, 88.88, row2["dis_2"])
 }
 
+func TestLoadDatasetCSVChunks(t *testing.T) {
+	// quoted names hold line breaks and doubled quotes, and rows of the same
+	// company and date tie, the last one read winning
+	var csvContent strings.Builder
+	csvContent.WriteString("company_id,date,name,emi_1\n")
+	for i := range 200 {
+		fmt.Fprintf(&csvContent, "%d,2023-06-01,\"Acme \"\"%d\"\"\nHoldings\",%d\n", 1000+i%7, i, i)
+	}
+	path := filepath.Join(t.TempDir(), "emissions.csv")
+	require.NoError(t, os.WriteFile(path, []byte(csvContent.String()), 0o644))
+	ds := c.Dataset{Name: "emissions", Keys: []string{"company_id"}, Date: c.DateColumn{Column: "date"}}
+
+	whole := loadRows(t, CSVLoader{}, path, ds)
+	require.Len(t, whole, 200)
+	assert.Equal(t, map[string]float64{"emi_1": 199}, whole[199].Values)
+
+	defer func(size int) { csvChunkSize = size }(csvChunkSize)
+	for _, size := range []int{1, 16, 100, 1000} {
+		csvChunkSize = size
+		assert.Equal(t, whole, loadRows(t, CSVLoader{}, path, ds), "chunks of %d bytes", size)
+	}
+
+	csvChunkSize = 64
+	broken := csvContent.String() + "1000,2023-06-01,\"Acme\nHoldings\n1001,2023-06-01,x,1\n"
+	require.NoError(t, os.WriteFile(path, []byte(broken), 0o644))
+	src := source{bucket: blob.Dir(filepath.Dir(path)), object: blob.Object{Key: filepath.Base(path)}}
+	err := CSVLoader{}.loadData(context.Background(), src, ds, func(observation) {})
+	var parseErr *csv.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 402, parseErr.StartLine)
+
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	err = CSVLoader{}.loadData(context.Background(), src, ds, func(observation) {})
+	assert.EqualError(t, err, "failed to read headers: EOF")
+}
+
 func TestLoadDatasetJSON(t *testing.T) {
 	// Minimal JSON content
 	jsonContent := `[
@@ -71,10 +150,8 @@ func TestLoadDatasetJSON(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, tmpfile.Close())
 
-	loader := JSONLoader{}
-
-	results, err := loader.loadData(context.Background(), tmpfile.Name())
-	require.NoError(t, err)
+	rows := loadRows(t, JSONLoader{}, tmpfile.Name(), disclosureDataset(tmpfile.Name()))
+	results := calendarYears(t, rows)
 
 	// 3) We expect (1000, 2023) and (1001, 2024) final entries, with "later" row overwriting the earlier one for (1001,2024).
 	require.Len(t, results, 2)
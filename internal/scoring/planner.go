@@ -0,0 +1,404 @@
+package scoring
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// metricRef addresses one metric of one score.
+type metricRef struct {
+	Score  string
+	Metric string
+}
+
+func (r metricRef) String() string {
+	return r.Score + "." + r.Metric
+}
+
+// Plan is the evaluation order of a target score and of every score it
+// references through <score>.<metric> sources. Following Order guarantees a
+// metric's dependencies, in any score, are computed first for the key.
+//
+// Cross-sectional metrics are Barriers: they need their inputs for every
+// key before they can run, so the plan is split into Phases evaluated one
+// after the other across all keys.
+//
+// Columns are the target metrics the plan outputs. Metrics none of them
+// depend on are left out of Order.
+type Plan struct {
+	Target   *c.Config
+	Scores   map[string]*c.Config
+	Order    []metricRef
+	Phases   []Phase
+	Barriers map[metricRef]bool
+	Columns  []Column
+	metrics  map[metricRef]c.Metric
+	// datasets are the logical datasets sources read from.
+	datasets *c.Datasets
+}
+
+// Phase is one step of the evaluation. Its Barriers are computed across
+// all keys first, once every earlier phase is complete; Order is then
+// evaluated key by key.
+type Phase struct {
+	Barriers []metricRef
+	Order    []metricRef
+}
+
+// BuildPlan resolves the scores reachable from target and sorts the metrics
+// its outputs need topologically. Sources whose prefix is one of datasets
+// read a dataset field, even where a score has the same name. A dependency
+// cycle, within a score or across scores, is reported with its full path.
+func BuildPlan(logger *zap.Logger, target *c.Config, lookup c.Lookup, datasets *c.Datasets) (*Plan, error) {
+	return BuildSelectedPlan(logger, target, lookup, datasets, nil)
+}
+
+// BuildSelectedPlan is BuildPlan for the selected output metrics of target
+// only, or all of them when selected is empty.
+func BuildSelectedPlan(logger *zap.Logger, target *c.Config, lookup c.Lookup, datasets *c.Datasets, selected []string) (*Plan, error) {
+	columns, err := OutputColumns(target, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := reachableScores(target, lookup, datasets)
+	graph, inDegree, nodes := buildDependencyGraph(logger, scores, datasets)
+	order, err := topologicalSort(logger, nodes, graph, inDegree)
+	if err != nil {
+		cycles := findCycles(nodes, graph)
+		if len(cycles) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, formatCycle(cycles[0], target.Name))
+		}
+		return nil, err
+	}
+	order = prune(order, graph, target.Name, columns)
+
+	plan := &Plan{
+		Target:   target,
+		Scores:   make(map[string]*c.Config, len(scores)),
+		Order:    order,
+		Columns:  columns,
+		metrics:  make(map[metricRef]c.Metric),
+		datasets: datasets,
+	}
+	for _, cfg := range scores {
+		plan.Scores[cfg.Name] = cfg
+		for _, m := range cfg.Metrics {
+			// bind named parameters once so operations read them by position
+			if _, known := Signatures[m.Operation.Type]; known {
+				op, err := bindOperation(m.Operation)
+				if err != nil {
+					return nil, fmt.Errorf("metric %q of score %q: %w", m.Name, cfg.Name, err)
+				}
+				m.Operation = op
+			}
+			if m.Arithmetic == "" {
+				m.Arithmetic = cfg.Arithmetic
+			}
+			plan.metrics[metricRef{Score: cfg.Name, Metric: m.Name}] = m
+		}
+	}
+	plan.Phases, plan.Barriers = buildPhases(order, graph, plan.metrics)
+	return plan, nil
+}
+
+// buildPhases assigns every metric to the earliest phase where its inputs
+// are complete: a per-key metric runs in the phase of its latest
+// dependency, a barrier in the phase after it.
+func buildPhases(
+	order []metricRef,
+	graph map[metricRef][]metricRef,
+	metrics map[metricRef]c.Metric,
+) ([]Phase, map[metricRef]bool) {
+	barriers := make(map[metricRef]bool)
+	phaseOf := make(map[metricRef]int, len(order))
+	for _, ref := range order {
+		if isBarrier(metrics[ref]) {
+			barriers[ref] = true
+			phaseOf[ref] = 1
+		}
+	}
+
+	last := 0
+	for _, ref := range order {
+		// order is topological, so phaseOf[ref] is final here
+		last = max(last, phaseOf[ref])
+		for _, dependent := range graph[ref] {
+			phase := phaseOf[ref]
+			if barriers[dependent] {
+				phase++
+			}
+			phaseOf[dependent] = max(phaseOf[dependent], phase)
+		}
+	}
+
+	phases := make([]Phase, last+1)
+	for _, ref := range order {
+		p := &phases[phaseOf[ref]]
+		if barriers[ref] {
+			p.Barriers = append(p.Barriers, ref)
+		} else {
+			p.Order = append(p.Order, ref)
+		}
+	}
+	return phases, barriers
+}
+
+// DatasetFields lists the dataset fields the metrics of the plan read,
+// keyed by dataset.
+func (p *Plan) DatasetFields() map[string]map[string]bool {
+	fields := make(map[string]map[string]bool)
+	for _, ref := range p.Order {
+		for _, source := range metricSources(p.metrics[ref]) {
+			prefix, name, ok := splitSource(source)
+			if !ok || !p.datasets.Has(prefix) {
+				continue
+			}
+			if fields[prefix] == nil {
+				fields[prefix] = make(map[string]bool)
+			}
+			fields[prefix][name] = true
+		}
+	}
+	return fields
+}
+
+// crossSectional reports whether any metric of the plan needs its peers.
+func (p *Plan) crossSectional() bool {
+	return len(p.Barriers) > 0
+}
+
+// datasetNames tells the logical datasets apart from scores, such as a
+// catalog of declared or of loaded datasets.
+type datasetNames interface {
+	Has(name string) bool
+}
+
+// reachableScores returns target followed by every score it references,
+// directly or transitively, in discovery order.
+func reachableScores(target *c.Config, lookup c.Lookup, datasets datasetNames) []*c.Config {
+	scores := []*c.Config{target}
+	seen := map[string]bool{target.Name: true}
+
+	for i := 0; i < len(scores); i++ {
+		cfg := scores[i]
+		for _, m := range cfg.Metrics {
+			for _, source := range metricSources(m) {
+				prefix, _, ok := splitSource(source)
+				if !ok || prefix == "self" || datasets.Has(prefix) || seen[prefix] {
+					continue
+				}
+				if lookup == nil {
+					continue
+				}
+				dep, found := lookup(prefix)
+				if !found {
+					continue // not a score, resolved as a dataset at evaluation time
+				}
+				seen[prefix] = true
+				scores = append(scores, dep)
+			}
+		}
+	}
+	return scores
+}
+
+// buildDependencyGraph maps every metric to the metrics that depend on it,
+// across all the given scores. nodes lists the metrics in config order.
+func buildDependencyGraph(
+	logger *zap.Logger,
+	scores []*c.Config,
+	datasets datasetNames,
+) (map[metricRef][]metricRef, map[metricRef]int, []metricRef) {
+	graph := make(map[metricRef][]metricRef)
+	inDegree := make(map[metricRef]int)
+	var nodes []metricRef
+
+	names := make(map[string]bool, len(scores))
+	for _, cfg := range scores {
+		names[cfg.Name] = true
+		for _, m := range cfg.Metrics {
+			ref := metricRef{Score: cfg.Name, Metric: m.Name}
+			if _, dup := inDegree[ref]; dup {
+				continue
+			}
+			inDegree[ref] = 0
+			graph[ref] = []metricRef{}
+			nodes = append(nodes, ref)
+		}
+	}
+
+	for _, cfg := range scores {
+		for _, m := range cfg.Metrics {
+			ref := metricRef{Score: cfg.Name, Metric: m.Name}
+			for _, source := range metricSources(m) {
+				dep, ok := metricDependency(source, cfg.Name, names, datasets)
+				if !ok {
+					continue
+				}
+				if _, defined := inDegree[dep]; !defined {
+					// undefined targets evaluate to null; ValidateConfig reports them
+					continue
+				}
+				graph[dep] = append(graph[dep], ref)
+				inDegree[ref]++
+			}
+		}
+	}
+
+	logger.Sugar().Debugw("Built dependency graph",
+		"graph", graph,
+		"inDegree", inDegree)
+
+	return graph, inDegree, nodes
+}
+
+// topologicalSort orders nodes so every metric follows its dependencies,
+// and fails when a dependency cycle exists.
+func topologicalSort(
+	logger *zap.Logger,
+	nodes []metricRef,
+	graph map[metricRef][]metricRef,
+	inDegree map[metricRef]int,
+) ([]metricRef, error) {
+	remaining := make(map[metricRef]int, len(inDegree))
+	var queue []metricRef
+	for _, ref := range nodes {
+		remaining[ref] = inDegree[ref]
+		if inDegree[ref] == 0 {
+			queue = append(queue, ref)
+		}
+	}
+
+	var sorted []metricRef
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, current)
+
+		for _, dep := range graph[current] {
+			remaining[dep]--
+			if remaining[dep] == 0 {
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	if len(sorted) < len(nodes) {
+		logger.Error("Cycle detected in metric dependencies")
+		return nil, fmt.Errorf("cycle detected in metric dependencies")
+	}
+
+	logger.Sugar().Debugw("Sorted metrics", "order", sorted)
+
+	return sorted, nil
+}
+
+// findCycles returns every dependency cycle in the graph as the path of
+// metrics, starting and ending on the same one.
+func findCycles(nodes []metricRef, graph map[metricRef][]metricRef) [][]metricRef {
+	// graph points from a dependency to its dependents; walk it reversed so
+	// paths read in reference order (a -> b means a uses b).
+	deps := make(map[metricRef][]metricRef)
+	for _, from := range nodes {
+		for _, to := range graph[from] {
+			deps[to] = append(deps[to], from)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[metricRef]int)
+	var stack []metricRef
+	var cycles [][]metricRef
+
+	var visit func(ref metricRef)
+	visit = func(ref metricRef) {
+		state[ref] = visiting
+		stack = append(stack, ref)
+		for _, dep := range deps[ref] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				for i := range stack {
+					if stack[i] == dep {
+						cycle := append(append([]metricRef{}, stack[i:]...), dep)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[ref] = done
+	}
+
+	for _, ref := range nodes {
+		if state[ref] == unvisited {
+			visit(ref)
+		}
+	}
+	return cycles
+}
+
+// formatCycle renders a cycle path. Metrics of the home score are shown
+// bare, metrics of other scores qualified with their score name.
+func formatCycle(cycle []metricRef, home string) string {
+	parts := make([]string, len(cycle))
+	for i, ref := range cycle {
+		if ref.Score == home {
+			parts[i] = ref.Metric
+		} else {
+			parts[i] = ref.String()
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// metricDependency resolves a source to the metric it reads, if it reads a
+// metric rather than a dataset field. self. refers to the current score.
+func metricDependency(source, current string, scores map[string]bool, datasets datasetNames) (metricRef, bool) {
+	prefix, name, ok := splitSource(source)
+	if !ok {
+		return metricRef{}, false
+	}
+	if prefix == "self" {
+		return metricRef{Score: current, Metric: name}, true
+	}
+	if !datasets.Has(prefix) && scores[prefix] {
+		return metricRef{Score: prefix, Metric: name}, true
+	}
+	return metricRef{}, false
+}
+
+// metricSources lists every source a metric reads: its parameter sources
+// and the references in its expression.
+func metricSources(m c.Metric) []string {
+	sources := make([]string, 0, len(m.Operation.Parameters))
+	for _, p := range m.Operation.Parameters {
+		sources = append(sources, p.Source)
+	}
+	if m.Operation.Expr != nil {
+		for _, ref := range m.Operation.Expr.Refs() {
+			sources = append(sources, ref.Source)
+		}
+	}
+	return sources
+}
+
+// splitSource splits a <prefix>.<name> source.
+func splitSource(source string) (string, string, bool) {
+	parts := strings.Split(source, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
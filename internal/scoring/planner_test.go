@@ -0,0 +1,146 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func lookupOf(configs ...*c.Config) c.Lookup {
+	byName := make(map[string]*c.Config, len(configs))
+	for _, cfg := range configs {
+		byName[cfg.Name] = cfg
+	}
+	return func(name string) (*c.Config, bool) {
+		cfg, ok := byName[name]
+		return cfg, ok
+	}
+}
+
+func TestBuildPlanOrdersAcrossScores(t *testing.T) {
+	score2 := &c.Config{Name: "score_2", Metrics: []c.Metric{
+		{Name: "metric_2", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "self.metric_1"}}}},
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "waste.was_1"}}}},
+	}}
+	score1 := &c.Config{Name: "score_1", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "divide", Parameters: []c.Parameter{
+			{Source: "score_2.metric_2", Param: "x"},
+			{Source: "disclosure.dis_1", Param: "y"},
+		}}},
+	}}
+
+	plan, err := BuildPlan(zap.NewNop(), score1, lookupOf(score1, score2), testDatasets(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, []metricRef{
+		{Score: "score_2", Metric: "metric_1"},
+		{Score: "score_2", Metric: "metric_2"},
+		{Score: "score_1", Metric: "metric_1"},
+	}, plan.Order)
+	assert.Equal(t, map[string]map[string]bool{
+		"waste":      {"was_1": true},
+		"disclosure": {"dis_1": true},
+	}, plan.DatasetFields())
+
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste":      {{CompanyID: "1", Year: 2024}: {"was_1": 10}},
+		"disclosure": {{CompanyID: "1", Year: 2024}: {"dis_1": 4}},
+	})
+	metrics := computeScoresForKey(context.Background(), zap.NewNop(), CompanyYearKey{CompanyID: "1", Year: 2024}, plan, datasets)
+	assert.Equal(t, map[string]float64{"metric_1": 2.5}, metrics)
+}
+
+func TestBuildPlanReportsCrossScoreCycle(t *testing.T) {
+	score1 := &c.Config{Name: "score_1", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_2.metric_1"}}}},
+	}}
+	score2 := &c.Config{Name: "score_2", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_3.metric_1"}}}},
+	}}
+	score3 := &c.Config{Name: "score_3", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_1.metric_1"}}}},
+	}}
+
+	_, err := BuildPlan(zap.NewNop(), score1, lookupOf(score1, score2, score3), testDatasets(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metric_1 -> score_2.metric_1 -> score_3.metric_1 -> metric_1")
+}
+
+func TestBuildPlanOrdersExpressionDependencies(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: score_1
+metrics:
+  - name: metric_1
+    operation:
+      type: expr
+      expression: (self.metric_2 + disclosure.dis_2) / max(emissions.emi_4, 1) * 100
+  - name: metric_2
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+`))
+	require.NoError(t, err)
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+	assert.Equal(t, []metricRef{
+		{Score: "score_1", Metric: "metric_2"},
+		{Score: "score_1", Metric: "metric_1"},
+	}, plan.Order)
+
+	key := CompanyYearKey{CompanyID: "1", Year: 2024}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste":      {key: {"was_1": 10}},
+		"disclosure": {key: {"dis_2": 5}},
+		"emissions":  {key: {"emi_4": 0.5}},
+	})
+	metrics := computeScoresForKey(context.Background(), zap.NewNop(), key, plan, datasets)
+	assert.Equal(t, map[string]float64{"metric_1": 1500, "metric_2": 10}, metrics)
+}
+
+func TestExplainKeyMarksDefaults(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: score_1
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+        - source: disclosure.dis_1
+          default: 0
+  - name: metric_2
+    operation:
+      type: divide
+      parameters:
+        - source: self.metric_1
+          param: x
+        - value: 1000
+          param: y
+`))
+	require.NoError(t, err)
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	key := CompanyYearKey{CompanyID: "1", Year: 2024}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste": {key: {"was_1": 500}},
+	})
+	traces := ExplainKey(context.Background(), zap.NewNop(), key, plan, datasets)
+
+	assert.Equal(t, []MetricTrace{
+		{Score: "score_1", Metric: "metric_1", Operation: "sum", Value: 500, Inputs: []InputTrace{
+			{Source: "waste.was_1", Value: 500},
+			{Source: "disclosure.dis_1", Value: 0, Defaulted: true},
+		}},
+		{Score: "score_1", Metric: "metric_2", Operation: "divide", Value: 0.5, Inputs: []InputTrace{
+			{Source: "self.metric_1", Value: 500},
+			{Value: 1000, Literal: true},
+		}},
+	}, traces)
+}
@@ -0,0 +1,184 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestRollup(t *testing.T) {
+	tests := []struct {
+		name     string
+		missing  string
+		params   []c.Parameter
+		want     float64
+		wantNull bool
+		wantErr  bool
+	}{
+		{name: "equal weights", params: sources("two", "ten"), want: 6},
+		{name: "weighted", params: []c.Parameter{weighted("two", 3), weighted("ten", 1)}, want: 4},
+		{name: "renormalise", params: []c.Parameter{weighted("ten", 1), weighted("missing", 3)}, want: 10},
+		{name: "renormalise explicit", missing: c.MissingRenormalise, params: sources("ten", "missing"), want: 10},
+		{name: "zero", missing: c.MissingZero, params: []c.Parameter{weighted("ten", 1), weighted("missing", 3)}, want: 2.5},
+		{name: "null", missing: c.MissingNull, params: sources("ten", "missing"), wantNull: true},
+		{name: "null complete", missing: c.MissingNull, params: sources("two", "ten"), want: 6},
+		{name: "all null", missing: c.MissingZero, params: sources("missing", "missing"), wantNull: true},
+		{name: "zero weights", params: []c.Parameter{weighted("ten", 0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := &Results{score: "score", values: map[string]map[string]float64{"score": {}}}
+			op := c.Operation{Type: c.RollupOperation, Missing: tt.missing, Parameters: tt.params}
+
+			got, isNull, err := evalRollup(context.Background(), zap.NewNop(), op, opKey, results, opDatasets)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNull, isNull)
+			if !tt.wantNull {
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+const pillarsConfig = `name: pillars
+metrics:
+  - name: energy
+    operation:
+      type: sum
+      parameters:
+        - source: emissions.emi_1
+  - name: water
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+  - name: board
+    operation:
+      type: sum
+      parameters:
+        - source: disclosure.dis_1
+groups:
+  - name: overall
+    missing: "null"
+    children:
+      - name: environmental
+        weight: 3
+        missing: zero
+        children:
+          - metric: energy
+            weight: 0.75
+          - metric: water
+            weight: 0.25
+      - name: governance
+        weight: 1
+        children:
+          - metric: board
+`
+
+func TestParseConfigExpandsGroups(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(pillarsConfig))
+	require.NoError(t, err)
+	require.NoError(t, c.Validate(scoreConfig))
+
+	weight := func(v float64) *float64 { return &v }
+	require.Len(t, scoreConfig.Metrics, 6)
+	assert.Equal(t, c.Metric{
+		Name: "environmental",
+		Operation: c.Operation{
+			Type:    c.RollupOperation,
+			Missing: c.MissingZero,
+			Parameters: []c.Parameter{
+				{Source: "self.energy", Weight: weight(0.75), Pos: c.Position{Line: 26, Column: 21}},
+				{Source: "self.water", Weight: weight(0.25), Pos: c.Position{Line: 28, Column: 21}},
+			},
+			Pos: c.Position{Line: 22, Column: 15},
+		},
+		Pos: c.Position{Line: 22, Column: 15},
+	}, scoreConfig.Metrics[3])
+	assert.Equal(t, "governance", scoreConfig.Metrics[4].Name)
+	assert.Equal(t, "overall", scoreConfig.Metrics[5].Name)
+	assert.Equal(t, []string{"self.environmental", "self.governance"},
+		[]string{scoreConfig.Metrics[5].Operation.Parameters[0].Source, scoreConfig.Metrics[5].Operation.Parameters[1].Source})
+}
+
+func TestHierarchicalScoring(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(pillarsConfig))
+	require.NoError(t, err)
+	require.Empty(t, ValidateConfig(scoreConfig, nil, nil))
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	full := CompanyYearKey{CompanyID: "1", Year: 2024}
+	partial := CompanyYearKey{CompanyID: "2", Year: 2024}
+	datasets := tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"emissions":  {full: {"emi_1": 8}, partial: {"emi_1": 8}},
+		"waste":      {full: {"was_1": 4}},
+		"disclosure": {full: {"dis_1": 1}, partial: {"dis_1": 1}},
+	})
+
+	assert.Equal(t, map[string]float64{
+		"energy": 8, "water": 4, "board": 1,
+		"environmental": 7, "governance": 1, "overall": 5.5,
+	}, computeScoresForKey(context.Background(), zap.NewNop(), full, plan, datasets))
+
+	// water is counted as zero in environmental; overall is complete
+	assert.Equal(t, map[string]float64{
+		"energy": 8, "board": 1,
+		"environmental": 6, "governance": 1, "overall": 4.75,
+	}, computeScoresForKey(context.Background(), zap.NewNop(), partial, plan, datasets))
+
+	// without governance, overall is null under its null policy
+	datasets["disclosure"] = tableOf(map[CompanyYearKey]map[string]float64{full: {"dis_1": 1}})
+	assert.Equal(t, map[string]float64{"energy": 8, "environmental": 6},
+		computeScoresForKey(context.Background(), zap.NewNop(), partial, plan, datasets))
+}
+
+func TestValidateConfigGroups(t *testing.T) {
+	yamlContent := `name: groups
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+      missing: zero
+groups:
+  - name: overall
+    missing: sometimes
+    children:
+      - metric: metric_1
+        weight: -1
+      - metric: metric_9
+      - name: empty
+      - metric: metric_1
+        children:
+          - metric: metric_1
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+	assert.EqualError(t, c.Validate(scoreConfig), `group "empty" has no children in "groups"`)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, nil, nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`5:13: metric_1: sum does not take a missing policy`,
+		`10:11: overall: unknown missing policy "sometimes", expected one of renormalise, zero, null`,
+		`13:17: overall: weight of "self.metric_1" must not be negative, got -1`,
+		`15:17: overall: source "self.metric_9" references undefined metric "metric_9"`,
+		`16:15: empty: group "empty" has no children`,
+		`17:17: group "metric_1" has both a metric and children`,
+	}, got)
+}
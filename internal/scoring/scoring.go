@@ -2,6 +2,7 @@ package scoring
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,76 +13,10 @@ import (
 	c "esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
-// buildDependencyGraph list of metrics that depend on 'a'
-func buildDependencyGraph(logger *zap.Logger, scoreConfig *c.Config) (map[string][]string, map[string]int) {
-	graph := make(map[string][]string)
-	inDegree := make(map[string]int)
-
-	for _, m := range scoreConfig.Metrics {
-		inDegree[m.Name] = 0
-		graph[m.Name] = []string{}
-	}
-
-	for _, m := range scoreConfig.Metrics {
-		for _, param := range m.Operation.Parameters {
-			if strings.HasPrefix(param.Source, "self.") {
-				depMetric := strings.TrimPrefix(param.Source, "self.")
-				graph[depMetric] = append(graph[depMetric], m.Name)
-				inDegree[m.Name]++
-			}
-		}
-	}
-
-	logger.Sugar().Debug("No value for key",
-		zap.Any("order", graph),
-		zap.Any("order", inDegree))
-
-	return graph, inDegree
-}
-
-// topologicalSort check if dependency cycle exists
-func topologicalSort(
-	logger *zap.Logger,
-	scoreConfig *c.Config,
-	graph map[string][]string,
-	inDegree map[string]int,
-) ([]string, error) {
-	var queue []string
-	for metricName, deg := range inDegree {
-		if deg == 0 {
-			queue = append(queue, metricName)
-		}
-	}
-
-	var sorted []string
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-		sorted = append(sorted, current)
-
-		for _, dep := range graph[current] {
-			inDegree[dep]--
-			if inDegree[dep] == 0 {
-				queue = append(queue, dep)
-			}
-		}
-	}
-
-	if len(sorted) < len(scoreConfig.Metrics) {
-		logger.Error("Cycle detected in metric dependencies")
-		return nil, fmt.Errorf("cycle detected in metric dependencies")
-	}
-
-	logger.Sugar().Debug("No value for key",
-		zap.Any("order", sorted))
-
-	return sorted, nil
-}
-
-func getAllDataCompanyKeys(datasets map[string]map[CompanyYearKey]map[string]float64) []CompanyYearKey {
+func getAllDataCompanyKeys(datasets map[string]*Table) []CompanyYearKey {
 	unique := make(map[CompanyYearKey]bool)
 	for _, ds := range datasets {
-		for key := range ds {
+		for _, key := range ds.Keys() {
 			unique[key] = true
 		}
 	}
@@ -97,16 +32,18 @@ func evaluateMetric(
 	logger *zap.Logger,
 	metric c.Metric,
 	key CompanyYearKey,
-	//results map[CompanyYearKey]map[string]float64,
-	results map[string]float64,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool) {
 	// if we've already computed metric, return it
-	if val, ok := results[metric.Name]; ok {
+	if val, ok := results.get(results.score, metric.Name); ok {
 		return val, false
 	}
 
 	opFn, ok := Operations[metric.Operation.Type]
+	if decimalFn, exact := DecimalOperations[metric.Operation.Type]; exact && metric.Arithmetic == c.ArithmeticDecimal {
+		opFn = decimalFn
+	}
 	if !ok {
 		logger.Sugar().Infow("No value for key",
 			zap.String("company_id", key.CompanyID),
@@ -116,7 +53,7 @@ func evaluateMetric(
 		return 0, true
 	}
 
-	val, isNull, err := opFn(ctx, logger, metric.Operation.Parameters, key, results, datasets)
+	val, isNull, err := opFn(ctx, logger, metric.Operation, key, results, datasets)
 	if err != nil {
 		logger.Sugar().Infow("No value for key",
 			zap.String("company_id", key.CompanyID),
@@ -127,41 +64,59 @@ func evaluateMetric(
 	}
 
 	if !isNull {
-		results[metric.Name] = val
+		results.set(metric.Name, val)
 	}
 
 	return val, isNull
 }
 
-// getValue from source file
+// getValueAt reads a source as of another year of the same company. Metric
+// sources read the results already computed for that year.
+func getValueAt(
+	logger *zap.Logger,
+	source string,
+	key CompanyYearKey,
+	year int,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool) {
+	if year == key.Year {
+		return getValue(logger, source, key, results, datasets)
+	}
+	past := CompanyYearKey{CompanyID: key.CompanyID, Year: year, Period: key.Period}
+	return getValue(logger, source, past, results.at(year), datasets)
+}
+
+// getValue from source file, from the metrics computed so far for the
+// current score (self.<metric>) or from another score in the plan
+// (<score>.<metric>)
 func getValue(
 	logger *zap.Logger,
 	source string,
 	key CompanyYearKey,
-	//results map[CompanyYearKey]map[string]float64,
-	results map[string]float64,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	results *Results,
+	datasets map[string]*Table,
 ) (float64, bool) {
-	if strings.HasPrefix(source, "self.") {
-		metricName := strings.TrimPrefix(source, "self.")
-		val, ok := results[metricName]
+	parts := strings.Split(source, ".")
+	if len(parts) != 2 {
+		return 0, true // invalid format => null
+	}
+	datasetName := parts[0]
+	metricKey := parts[1]
+
+	if scoreName, ok := results.scoreFor(datasetName); ok {
+		val, ok := results.get(scoreName, metricKey)
 		if !ok {
 			logger.Sugar().Infow("No value for key",
 				zap.String("company_id", key.CompanyID),
 				zap.Int("year", key.Year),
-				zap.String("value", fmt.Sprintf("%v", val)))
+				zap.String("score", scoreName),
+				zap.String("metric", metricKey))
 			return 0, true
 		}
 		return val, false
 	}
 
-	parts := strings.Split(source, ".")
-	if len(parts) != 2 {
-		return 0, true // invalid format => null
-	}
-	datasetName := parts[0]
-	metricKey := parts[1]
-
 	ds, ok := datasets[datasetName]
 	if !ok {
 		logger.Sugar().Infow("Unknown dataset",
@@ -169,8 +124,8 @@ func getValue(
 
 		return 0, true
 	}
-	row, ok := ds[key]
-	if !ok {
+	val, ok := ds.Value(key, metricKey)
+	if !ok && !ds.Has(key) {
 		logger.Sugar().Infow("no row for key",
 			zap.String("company_id", key.CompanyID),
 			zap.Int("year", key.Year),
@@ -178,7 +133,6 @@ func getValue(
 
 		return 0, true
 	}
-	val, ok := row[metricKey]
 	if !ok {
 		logger.Sugar().Infow("No value for key",
 			zap.String("company_id", key.CompanyID),
@@ -197,193 +151,347 @@ func parallelComputeScores(
 	ctx context.Context,
 	logger *zap.Logger,
 	allKeys []CompanyYearKey,
-	topoOrder []string,
-	metricMap map[string]c.Metric,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	plan *Plan,
+	datasets map[string]*Table,
 	numWorkers int,
 ) []ScoredRow {
-	jobs := make(chan CompanyYearKey, len(allKeys))
-	results := make(chan RowResult, len(allKeys))
-
-	var wg sync.WaitGroup
-	wg.Add(numWorkers)
-
-	for i := 0; i < numWorkers; i++ {
-		go func() {
-			defer wg.Done()
-			for key := range jobs {
-				metricsMap := computeScoresForKey(ctx, logger, key, topoOrder, metricMap, datasets)
-				results <- RowResult{
-					Row: ScoredRow{
-						Key:     key,
-						Metrics: metricsMap,
-					},
-					Err: nil,
-				}
-			}
-		}()
-	}
+	rows := make(chan ScoredRow, len(allKeys))
+	newEvaluation(plan, datasets, allKeys).run(ctx, logger, numWorkers, rows, nil)
+	close(rows)
 
-	for _, key := range allKeys {
-		jobs <- key
+	scoredRows := make([]ScoredRow, 0, len(allKeys))
+	for row := range rows {
+		scoredRows = append(scoredRows, row)
 	}
-	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
-	var scoredRows []ScoredRow
-	for r := range results {
-		if r.Err != nil {
-			logger.Sugar().Infow("Error computing scores",
-				"company_id", r.Row.Key.CompanyID,
-				"year", r.Row.Key.Year,
-				"error", r.Err.Error(),
-			)
-			continue
-		}
-		scoredRows = append(scoredRows, r.Row)
-	}
+	sortScoredRows(scoredRows)
+	return scoredRows
+}
 
+// sortScoredRows orders rows by company, then year, then period.
+func sortScoredRows(scoredRows []ScoredRow) {
 	sort.Slice(scoredRows, func(i, j int) bool {
 		iKey := scoredRows[i].Key
 		jKey := scoredRows[j].Key
-		if iKey.CompanyID == jKey.CompanyID {
+		if iKey.CompanyID != jKey.CompanyID {
+			return iKey.CompanyID < jKey.CompanyID
+		}
+		if iKey.Year != jKey.Year {
 			return iKey.Year < jKey.Year
 		}
-		return iKey.CompanyID < jKey.CompanyID
+		return iKey.Period < jKey.Period
 	})
+}
 
-	return scoredRows
+// companyYears is the unit of work of the scoring workers: every year of
+// one company, ascending. At quarter or month grain each period of the year
+// is a series of its own, so time-series operations compare a quarter with
+// the same quarter of earlier years.
+type companyYears struct {
+	CompanyID string
+	Period    int
+	Years     []int
 }
 
-// computeScoresForKey
-func computeScoresForKey(
+// key is the key of one year of the series.
+func (cy companyYears) key(year int) CompanyYearKey {
+	return CompanyYearKey{CompanyID: cy.CompanyID, Year: year, Period: cy.Period}
+}
+
+// groupByCompany partitions keys by company and period, sorted by company
+// then period then year.
+func groupByCompany(keys []CompanyYearKey) []companyYears {
+	type series struct {
+		companyID string
+		period    int
+	}
+	bySeries := make(map[series][]int)
+	for _, key := range keys {
+		s := series{companyID: key.CompanyID, period: key.Period}
+		bySeries[s] = append(bySeries[s], key.Year)
+	}
+	companies := make([]companyYears, 0, len(bySeries))
+	for s, years := range bySeries {
+		sort.Ints(years)
+		companies = append(companies, companyYears{CompanyID: s.companyID, Period: s.period, Years: years})
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		if companies[i].CompanyID != companies[j].CompanyID {
+			return companies[i].CompanyID < companies[j].CompanyID
+		}
+		return companies[i].Period < companies[j].Period
+	})
+	return companies
+}
+
+// evaluation holds the results of every key while a plan is evaluated
+// phase by phase. Each key's results live for the whole evaluation so
+// later phases, and later years of the same company, can read them.
+type evaluation struct {
+	plan      *Plan
+	datasets  map[string]*Table
+	companies []companyYears
+	keys      []CompanyYearKey
+	results   map[CompanyYearKey]*Results
+}
+
+// explainState collects the traces of the key being explained.
+type explainState struct {
+	key    CompanyYearKey
+	traces []MetricTrace
+}
+
+func newEvaluation(
+	plan *Plan,
+	datasets map[string]*Table,
+	keys []CompanyYearKey,
+) *evaluation {
+	e := &evaluation{
+		plan:      plan,
+		datasets:  datasets,
+		companies: groupByCompany(keys),
+		results:   make(map[CompanyYearKey]*Results, len(keys)),
+	}
+	for _, company := range e.companies {
+		history := make(map[int]*Results, len(company.Years))
+		for _, year := range company.Years {
+			key := company.key(year)
+			results := newResults(plan)
+			results.history = history
+			history[year] = results
+			e.results[key] = results
+			e.keys = append(e.keys, key)
+		}
+	}
+	return e
+}
+
+// run evaluates every phase of the plan. Barriers are computed across all
+// keys between phases; metrics are evaluated by numWorkers workers, each
+// taking whole companies so years are evaluated oldest first. Rows are
+// sent to rows, when not nil, as each company completes the last phase.
+func (e *evaluation) run(
+	ctx context.Context,
+	logger *zap.Logger,
+	numWorkers int,
+	rows chan<- ScoredRow,
+	explain *explainState,
+) {
+	for i, phase := range e.plan.Phases {
+		for _, ref := range phase.Barriers {
+			e.computeBarrier(ctx, logger, ref, explain)
+		}
+
+		var out chan<- ScoredRow
+		if i == len(e.plan.Phases)-1 {
+			out = rows
+		}
+
+		jobs := make(chan companyYears, len(e.companies))
+		var wg sync.WaitGroup
+		wg.Add(numWorkers)
+		for w := 0; w < numWorkers; w++ {
+			go func() {
+				defer wg.Done()
+				for company := range jobs {
+					e.evaluateCompany(ctx, logger, company, phase.Order, out, explain)
+				}
+			}()
+		}
+		for _, company := range e.companies {
+			jobs <- company
+		}
+		close(jobs)
+		wg.Wait()
+	}
+}
+
+// evaluateCompany evaluates refs for every year of a company, oldest first.
+func (e *evaluation) evaluateCompany(
 	ctx context.Context,
 	logger *zap.Logger,
+	company companyYears,
+	refs []metricRef,
+	rows chan<- ScoredRow,
+	explain *explainState,
+) {
+	for _, year := range company.Years {
+		key := company.key(year)
+		results := e.results[key]
+		for _, ref := range refs {
+			results.score = ref.Score
+			metricDef := e.plan.metrics[ref]
+
+			var inputs []InputTrace
+			explaining := explain != nil && key == explain.key
+			if explaining {
+				results.inputs = &inputs
+			}
+			val, isNull := evaluateMetric(ctx, logger, metricDef, key, results, e.datasets)
+			if !isNull {
+				// store the computed value
+				results.set(ref.Metric, val)
+			}
+			results.inputs = nil
+			if explaining {
+				explain.traces = append(explain.traces, MetricTrace{
+					Score:     ref.Score,
+					Metric:    ref.Metric,
+					Operation: metricDef.Operation.Type,
+					Value:     val,
+					Null:      isNull,
+					Inputs:    inputs,
+				})
+			}
+		}
+
+		if rows != nil {
+			row := ScoredRow{Key: key, Metrics: e.plan.outputMetrics(results.values[e.plan.Target.Name])}
+			select {
+			case rows <- row:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// keysFor returns the keys needed to evaluate key: its company's years, or
+// every key when the plan compares companies with their peers.
+func keysFor(
 	key CompanyYearKey,
-	topoOrder []string,
-	metricMap map[string]c.Metric,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
-) map[string]float64 {
-	metricResults := make(map[string]float64)
-	for _, metricName := range topoOrder {
-		metricDef := metricMap[metricName]
-		val, isNull := evaluateMetric(ctx, logger, metricDef, key, metricResults, datasets)
-		if !isNull {
-			// store the computed value
-			metricResults[metricName] = val
+	plan *Plan,
+	datasets map[string]*Table,
+) []CompanyYearKey {
+	keys := []CompanyYearKey{key}
+	for _, other := range getAllDataCompanyKeys(datasets) {
+		if other == key || (!plan.crossSectional() && other.CompanyID != key.CompanyID) {
+			continue
 		}
+		keys = append(keys, other)
 	}
-	return metricResults
+	return keys
+}
+
+// computeScoresForKey evaluates every metric of the plan for one key, the
+// scores the target depends on included, and returns the target's metrics.
+// The company's other years, and its peers for cross-sectional metrics,
+// are evaluated alongside.
+func computeScoresForKey(
+	ctx context.Context,
+	logger *zap.Logger,
+	key CompanyYearKey,
+	plan *Plan,
+	datasets map[string]*Table,
+) map[string]float64 {
+	e := newEvaluation(plan, datasets, keysFor(key, plan, datasets))
+	e.run(ctx, logger, 1, nil, nil)
+	return e.results[key].values[plan.Target.Name]
 }
 
-// CalculateScore from file data
+// ExplainKey evaluates the plan for one key and returns, in evaluation
+// order, every metric with the inputs its operation read.
+func ExplainKey(
+	ctx context.Context,
+	logger *zap.Logger,
+	key CompanyYearKey,
+	plan *Plan,
+	datasets map[string]*Table,
+) []MetricTrace {
+	explain := &explainState{key: key}
+	newEvaluation(plan, datasets, keysFor(key, plan, datasets)).run(ctx, logger, 1, nil, explain)
+	return explain.traces
+}
+
+// CalculateScore from file data. Only the selected output metrics are
+// computed and returned, or every output metric when selected is empty.
 func CalculateScore(
 	ctx context.Context,
 	logger *zap.Logger,
-	configFileName string,
+	scoreConfig *c.Config,
+	lookup c.Lookup,
 	dataService *DataLoaderService,
-) (*c.Config, []ScoredRow, error) {
-
-	scoreConfig, err := c.InitScoreConfig(configFileName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("error initializing score config: %w", err)
-	}
+	selected []string,
+) ([]ScoredRow, error) {
 
 	logger.Sugar().Infow("Loaded config",
-		"configFileName", configFileName,
+		"config", scoreConfig.Name,
 		"dataService", dataService,
 	)
-	// Build the dependency graph & get topological order
-	graph, inDegree := buildDependencyGraph(logger, scoreConfig)
-	topoOrder, err := topologicalSort(logger, scoreConfig, graph, inDegree)
+	// Resolve referenced scores & get the topological order across them
+	plan, err := BuildSelectedPlan(logger, scoreConfig, lookup, dataService.Datasets(), selected)
+	if errors.Is(err, ErrUnknownMetric) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed topological sort: %v", err)
+		return nil, fmt.Errorf("failed topological sort: %v", err)
 	}
-	metricMap := BuildMetricMap(scoreConfig)
 
 	// Load all CSVs (or other files) from "data/" using the injected service
-	combined, err := dataService.LoadAllData(ctx, Dir)
+	datasets, err := loadDatasets(ctx, dataService, plan)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to load data from folder: %w", err)
-	}
-
-	datasets := make(map[string]map[CompanyYearKey]map[string]float64)
-	for logicalName, csvKey := range DatasetKeys {
-		data, ok := combined[csvKey]
-		if !ok {
-			return nil, nil, fmt.Errorf("missing dataset for key=%q", csvKey)
-		}
-		datasets[logicalName] = data
+		return nil, err
 	}
 
 	allKeys := getAllDataCompanyKeys(datasets)
 
-	scoredResults := parallelComputeScores(ctx, logger, allKeys, topoOrder, metricMap, datasets, NumWorkers)
+	scoredResults := parallelComputeScores(ctx, logger, allKeys, plan, datasets, NumWorkers)
 
 	logger.Sugar().Infow("Scoring results",
 		"results", scoredResults,
 		"dataService", dataService,
 	)
 
-	return scoreConfig, scoredResults, nil
+	return scoredResults, nil
+}
+
+// loadDatasets reads every declared dataset from Dir, projected to the
+// fields the plan reads.
+func loadDatasets(
+	ctx context.Context,
+	dataService *DataLoaderService,
+	plan *Plan,
+) (map[string]*Table, error) {
+	datasets, err := dataService.Project(plan.DatasetFields()).LoadDatasets(ctx, Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data from folder: %w", err)
+	}
+	return datasets, nil
+}
+
+// ExplainScore computes a score for a single company and year and explains
+// how every metric, including those of referenced scores, was derived.
+func ExplainScore(
+	ctx context.Context,
+	logger *zap.Logger,
+	scoreConfig *c.Config,
+	lookup c.Lookup,
+	dataService *DataLoaderService,
+	key CompanyYearKey,
+) ([]MetricTrace, error) {
+	plan, err := BuildPlan(logger, scoreConfig, lookup, dataService.Datasets())
+	if err != nil {
+		return nil, fmt.Errorf("failed topological sort: %v", err)
+	}
+	datasets, err := loadDatasets(ctx, dataService, plan)
+	if err != nil {
+		return nil, err
+	}
+	return ExplainKey(ctx, logger, key, plan, datasets), nil
 }
 
 func StreamScores(ctx context.Context,
 	logger *zap.Logger,
 	allKeys []CompanyYearKey,
-	topoOrder []string,
-	metricMap map[string]c.Metric,
-	datasets map[string]map[CompanyYearKey]map[string]float64,
+	plan *Plan,
+	datasets map[string]*Table,
 	numWorkers int) (<-chan ScoredRow, error) {
 	out := make(chan ScoredRow)
 	go func() {
 		defer close(out)
-		jobs := make(chan CompanyYearKey, len(allKeys))
-		results := make(chan RowResult, len(allKeys))
-		var wg sync.WaitGroup
-		wg.Add(numWorkers)
-
-		for i := 0; i < numWorkers; i++ {
-			go func() {
-				defer wg.Done()
-				for key := range jobs {
-					metricsMap := computeScoresForKey(ctx, logger, key, topoOrder, metricMap, datasets)
-					results <- RowResult{
-						Row: ScoredRow{
-							Key:     key,
-							Metrics: metricsMap,
-						},
-						Err: nil,
-					}
-				}
-			}()
-		}
-
-		for _, key := range allKeys {
-			jobs <- key
-		}
-		close(jobs)
-
-		go func() {
-			wg.Wait()
-			close(results)
-		}()
-
-		for r := range results {
-			if r.Err != nil {
-				logger.Sugar().Errorf("Error computing score for %s %d: %v", r.Row.Key.CompanyID, r.Row.Key.Year, r.Err)
-				continue
-			}
-			select {
-			case <-ctx.Done():
-				return
-			case out <- r.Row:
-			}
-		}
+		// rows are only final in the last phase, so cross-sectional plans
+		// start streaming once the earlier phases are done
+		newEvaluation(plan, datasets, allKeys).run(ctx, logger, numWorkers, out, nil)
 	}()
 	return out, nil
 }
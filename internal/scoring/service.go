@@ -2,6 +2,7 @@ package scoring
 
 import (
 	"context"
+	"errors"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,7 +18,10 @@ import (
 type GrpcScoringServer struct {
 	pb.UnimplementedScoringServiceServer
 	Logger         *zap.Logger
+	Configs        *c.Registry
 	ConfigFileName string
+	// Data loads the datasets scores read, shared by every request.
+	Data *DataLoaderService
 }
 
 func (s *GrpcScoringServer) CalculateScores(ctx context.Context, req *pb.CalculateRequest) (*pb.CalculateResponse, error) {
@@ -30,7 +34,17 @@ func (s *GrpcScoringServer) CalculateScores(ctx context.Context, req *pb.Calcula
 		requestID = req.GetRequest().GetRequestId() // fallback
 	}
 
-	_, scoredResults, err := CalculateScore(ctx, s.Logger, s.ConfigFileName, NewDataLoaderService(NewLoaderRegistry()))
+	scoreConfig, err := resolveConfig(s.Configs, req.GetConfigFile(), s.ConfigFileName)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	columns, err := OutputColumns(scoreConfig, req.GetMetrics())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	scoredResults, err := CalculateScore(ctx, s.Logger, scoreConfig, s.Configs.Get, s.Data, req.GetMetrics())
 	if err != nil {
 		s.Logger.Error("Failed to calculate scores", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "Failed to calculate scores: %v", err)
@@ -41,6 +55,7 @@ func (s *GrpcScoringServer) CalculateScores(ctx context.Context, req *pb.Calcula
 		companyScore := &pb.CompanyScore{
 			CompanyId: sr.Key.CompanyID,
 			Year:      int32(sr.Key.Year),
+			Period:    int32(sr.Key.Period),
 			Metrics:   make(map[string]float64),
 		}
 		for metricName, metricVal := range sr.Metrics {
@@ -54,10 +69,16 @@ func (s *GrpcScoringServer) CalculateScores(ctx context.Context, req *pb.Calcula
 		attribute.String("request.details", req.String()),
 	)
 
+	pbColumns := make([]*pb.Column, len(columns))
+	for i, col := range columns {
+		pbColumns[i] = &pb.Column{Metric: col.Metric, DisplayName: col.Header}
+	}
+
 	return &pb.CalculateResponse{
 		Success: true,
 		Message: "Score calculation successful",
 		Scores:  scores,
+		Columns: pbColumns,
 		Response: &pb.BaseResponse{
 			Upstream:  "scoring-service",
 			RequestId: requestID,
@@ -82,39 +103,29 @@ func (s *GrpcScoringServer) CalculateScoresStream(req *pb.CalculateRequest, stre
 	span.SetAttributes(attribute.String("request.id", requestID))
 	s.Logger.Info("Starting streaming score calculation", zap.String("request_id", requestID))
 
-	scoreConfig, err := c.InitScoreConfig(s.ConfigFileName)
+	scoreConfig, err := resolveConfig(s.Configs, req.GetConfigFile(), s.ConfigFileName)
 	if err != nil {
-		s.Logger.Error("Failed to initialize score config", zap.Error(err))
-		return status.Errorf(codes.Internal, "failed to initialize score config: %v", err)
+		s.Logger.Warn("Unknown score config", zap.String("config", req.GetConfigFile()))
+		return status.Error(codes.NotFound, err.Error())
 	}
 
-	graph, inDegree := buildDependencyGraph(s.Logger, scoreConfig)
-	topoOrder, err := topologicalSort(s.Logger, scoreConfig, graph, inDegree)
+	plan, err := BuildSelectedPlan(s.Logger, scoreConfig, s.Configs.Get, s.Data.Datasets(), req.GetMetrics())
+	if errors.Is(err, ErrUnknownMetric) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		s.Logger.Error("Failed topological sort", zap.Error(err))
 		return status.Errorf(codes.Internal, "failed topological sort: %v", err)
 	}
 
-	metricMap := BuildMetricMap(scoreConfig)
-
-	dataService := NewDataLoaderService(NewLoaderRegistry())
-	combined, err := dataService.LoadAllData(ctx, Dir)
+	datasets, err := loadDatasets(ctx, s.Data, plan)
 	if err != nil {
-		s.Logger.Error("Failed to load data from folder", zap.Error(err))
-		return status.Errorf(codes.Internal, "failed to load data from folder: %v", err)
-	}
-	datasets := make(map[string]map[CompanyYearKey]map[string]float64)
-	for logicalName, csvKey := range DatasetKeys {
-		data, ok := combined[csvKey]
-		if !ok {
-			s.Logger.Error("Missing dataset", zap.String("key", csvKey))
-			return status.Errorf(codes.Internal, "missing dataset for key %q", csvKey)
-		}
-		datasets[logicalName] = data
+		s.Logger.Error("Failed to load datasets", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
 	}
 	allKeys := getAllDataCompanyKeys(datasets)
 
-	scoreCh, err := StreamScores(ctx, s.Logger, allKeys, topoOrder, metricMap, datasets, NumWorkers)
+	scoreCh, err := StreamScores(ctx, s.Logger, allKeys, plan, datasets, NumWorkers)
 	if err != nil {
 		s.Logger.Error("Failed to stream scores", zap.Error(err))
 		return status.Errorf(codes.Internal, "failed to stream scores: %v", err)
@@ -124,6 +135,7 @@ func (s *GrpcScoringServer) CalculateScoresStream(req *pb.CalculateRequest, stre
 		companyScore := &pb.CompanyScore{
 			CompanyId: score.Key.CompanyID,
 			Year:      int32(score.Key.Year),
+			Period:    int32(score.Key.Period),
 			Metrics:   make(map[string]float64),
 		}
 		for metricName, metricVal := range score.Metrics {
@@ -137,3 +149,106 @@ func (s *GrpcScoringServer) CalculateScoresStream(req *pb.CalculateRequest, stre
 	s.Logger.Info("Finished streaming scores", zap.String("request_id", requestID))
 	return nil
 }
+
+func (s *GrpcScoringServer) ValidateConfig(ctx context.Context, req *pb.ValidateConfigRequest) (*pb.ValidateConfigResponse, error) {
+	tracer := otel.Tracer("score-app")
+	_, span := tracer.Start(ctx, "ValidateConfig")
+	defer span.End()
+
+	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
+	if !ok {
+		requestID = req.GetRequest().GetRequestId()
+	}
+	span.SetAttributes(attribute.String("request.id", requestID))
+
+	var scoreConfig *c.Config
+	var issues []*pb.ValidationIssue
+	if req.GetConfigYaml() != "" {
+		parsed, err := c.ParseConfig([]byte(req.GetConfigYaml()))
+		if err != nil {
+			issues = append(issues, &pb.ValidationIssue{Message: err.Error()})
+		}
+		scoreConfig = parsed
+	} else {
+		resolved, err := resolveConfig(s.Configs, req.GetConfigFile(), s.ConfigFileName)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		scoreConfig = resolved
+	}
+
+	if scoreConfig != nil {
+		for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(s.Data.Datasets()), s.Configs.Get) {
+			issues = append(issues, &pb.ValidationIssue{
+				File:    e.File,
+				Line:    int32(e.Line),
+				Column:  int32(e.Column),
+				Metric:  e.Metric,
+				Message: e.Message,
+			})
+		}
+	}
+
+	return &pb.ValidateConfigResponse{
+		Valid:  len(issues) == 0,
+		Issues: issues,
+		Response: &pb.BaseResponse{
+			Upstream:  "scoring-service",
+			RequestId: requestID,
+			Status:    "OK",
+		},
+	}, nil
+}
+
+func (s *GrpcScoringServer) DescribeScore(ctx context.Context, req *pb.DescribeScoreRequest) (*pb.DescribeScoreResponse, error) {
+	tracer := otel.Tracer("score-app")
+	_, span := tracer.Start(ctx, "DescribeScore")
+	defer span.End()
+
+	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
+	if !ok {
+		requestID = req.GetRequest().GetRequestId()
+	}
+	span.SetAttributes(attribute.String("request.id", requestID))
+
+	scoreConfig, err := resolveConfig(s.Configs, req.GetConfigFile(), s.ConfigFileName)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	desc := DescribeScore(scoreConfig, s.Data.Datasets().KeyGrain())
+	metrics := make([]*pb.MetricDescription, len(desc.Metrics))
+	for i, m := range desc.Metrics {
+		metrics[i] = &pb.MetricDescription{
+			Name:        m.Name,
+			DisplayName: m.DisplayName,
+			Operation:   m.Operation,
+			Metadata:    pbMetadata(m.Metadata),
+			Precision:   int32(m.Precision),
+			Rounding:    m.Rounding,
+		}
+	}
+
+	return &pb.DescribeScoreResponse{
+		Name:     desc.Name,
+		Metadata: pbMetadata(desc.Metadata),
+		Metrics:  metrics,
+		Grain:    desc.Grain,
+		Response: &pb.BaseResponse{
+			Upstream:  "scoring-service",
+			RequestId: requestID,
+			Status:    "OK",
+		},
+	}, nil
+}
+
+func pbMetadata(md c.Metadata) *pb.Metadata {
+	return &pb.Metadata{
+		Description:    md.Description,
+		Unit:           md.Unit,
+		Owner:          md.Owner,
+		Tags:           md.Tags,
+		MethodologyUrl: md.MethodologyURL,
+		Version:        md.Version,
+	}
+}
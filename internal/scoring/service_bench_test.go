@@ -5,15 +5,22 @@ import (
 	"testing"
 
 	"esgbook-software-engineer-technical-test-2024/middleware"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
 	pb "esgbook-software-engineer-technical-test-2024/protos/modules/scoring/generated"
 )
 
 func BenchmarkCalculateScoresGRPC(b *testing.B) {
 	logger, _ := middleware.InitializeLogger()
+	configs, err := config.NewRegistry(logger, "", nil)
+	if err != nil {
+		b.Fatalf("failed to load configs: %v", err)
+	}
 
 	grpcHandler := &GrpcScoringServer{
 		Logger:         logger,
+		Configs:        configs,
 		ConfigFileName: "score_1.yaml",
+		Data:           testDataService(b),
 	}
 
 	req := &pb.CalculateRequest{}
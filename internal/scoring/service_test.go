@@ -0,0 +1,63 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
+	pb "esgbook-software-engineer-technical-test-2024/protos/modules/scoring/generated"
+)
+
+func TestCalculateScoresUnknownConfig(t *testing.T) {
+	configs, err := config.NewRegistry(zap.NewNop(), "", nil)
+	require.NoError(t, err)
+
+	s := &GrpcScoringServer{
+		Logger:         zap.NewNop(),
+		Configs:        configs,
+		ConfigFileName: "score_1.yaml",
+		Data:           testDataService(t),
+	}
+
+	_, err = s.CalculateScores(context.Background(), &pb.CalculateRequest{ConfigFile: "score_404"})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestResolveConfig(t *testing.T) {
+	configs, err := config.NewRegistry(zap.NewNop(), "", nil)
+	require.NoError(t, err)
+
+	cfg, err := resolveConfig(configs, "", "score_1.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "score_1", cfg.Name)
+
+	cfg, err = resolveConfig(configs, "score_1", "other.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "score_1", cfg.Name)
+
+	_, err = resolveConfig(configs, "score_2", "score_1.yaml")
+	assert.ErrorIs(t, err, ErrUnknownConfig)
+}
+
+func TestCalculateScoresUnknownMetric(t *testing.T) {
+	configs, err := config.NewRegistry(zap.NewNop(), "", nil)
+	require.NoError(t, err)
+
+	s := &GrpcScoringServer{
+		Logger:         zap.NewNop(),
+		Configs:        configs,
+		ConfigFileName: "score_1.yaml",
+		Data:           testDataService(t),
+	}
+
+	_, err = s.CalculateScores(context.Background(), &pb.CalculateRequest{Metrics: []string{"metric_9"}})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
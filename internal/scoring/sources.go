@@ -0,0 +1,162 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// source is one input of a dataset: an object of its bucket, or the DSN of
+// its database as the key of an object without a bucket.
+type source struct {
+	bucket blob.Bucket
+	object blob.Object
+	// driver is the driver of a database source.
+	driver string
+	// compression is the compression of the object, empty when it has
+	// none.
+	compression string
+}
+
+// open reads the object, decompressed, so every loader reads compressed
+// objects like plain ones.
+func (s source) open(ctx context.Context) (io.ReadCloser, error) {
+	rc, err := s.bucket.Open(ctx, s.object.Key)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(rc, s.compression)
+}
+
+// String names the source in errors. A DSN may hold credentials, so it is
+// never shown.
+func (s source) String() string {
+	if s.bucket == nil {
+		return "its " + s.driver + " database"
+	}
+	return s.object.Key
+}
+
+// RegisterBucket serves the locations under url, such as s3://lake, from
+// bucket instead of a client built from the environment, for buckets that
+// need their own credentials or endpoint.
+func (s *DataLoaderService) RegisterBucket(url string, bucket blob.Bucket) {
+	if s.buckets == nil {
+		s.buckets = make(map[string]blob.Bucket)
+	}
+	s.buckets[strings.TrimSuffix(url, "/")] = bucket
+}
+
+// sources lists the objects matching the location of ds, in key order.
+func (s *DataLoaderService) sources(ctx context.Context, dataDir string, ds c.Dataset) ([]source, error) {
+	if ds.Database != nil {
+		return []source{{object: blob.Object{Key: ds.Database.Source(dataDir)}, driver: ds.Database.Driver}}, nil
+	}
+	bucket, pattern, err := s.bucket(ctx, dataDir, ds.Location)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %q: %w", ds.Name, err)
+	}
+	objects, err := blob.Glob(ctx, bucket, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location %q of dataset %q: %w", ds.Location, ds.Name, err)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no files match %q for dataset %q", ds.Location, ds.Name)
+	}
+	sources := make([]source, len(objects))
+	for i, obj := range objects {
+		sources[i] = source{bucket: bucket, object: obj, compression: ds.FileCompression(obj.Key)}
+	}
+	return sources, nil
+}
+
+func (s *DataLoaderService) bucket(ctx context.Context, dataDir, location string) (blob.Bucket, string, error) {
+	for url, bucket := range s.buckets {
+		if pattern, ok := strings.CutPrefix(location, url+"/"); ok {
+			return bucket, pattern, nil
+		}
+	}
+	return blob.Open(ctx, dataDir, location)
+}
+
+// datasetCache keeps the last load of each dataset with the versions of the
+// objects it was read from. Loads projected to different fields, such as
+// those of two configs, are kept apart so they do not evict each other.
+// Datasets read from a database are not cached, as they have no versions.
+type datasetCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedDataset
+}
+
+func newDatasetCache() *datasetCache {
+	return &datasetCache{entries: make(map[string]cachedDataset)}
+}
+
+type cachedDataset struct {
+	ds         c.Dataset
+	grain      string
+	fiscalYear c.FiscalYear
+	objects    []blob.Object
+	data       *Table
+}
+
+// get returns the data of the last load of ds, when it was declared, keyed
+// and read the same way. The data is shared and must not be modified.
+func (dc *datasetCache) get(dataDir string, ds c.Dataset, catalog *c.Datasets, sources []source) (*Table, bool) {
+	if dc == nil || ds.Database != nil {
+		return nil, false
+	}
+	dc.mu.Lock()
+	entry, ok := dc.entries[cacheKey(dataDir, ds)]
+	dc.mu.Unlock()
+	if !ok || entry.grain != catalog.KeyGrain() ||
+		!reflect.DeepEqual(entry.ds, ds) || !reflect.DeepEqual(entry.fiscalYear, catalog.FiscalYear) ||
+		!slices.Equal(entry.objects, objectsOf(sources)) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (dc *datasetCache) put(dataDir string, ds c.Dataset, catalog *c.Datasets, sources []source, data *Table) {
+	if dc == nil || ds.Database != nil {
+		return
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.entries[cacheKey(dataDir, ds)] = cachedDataset{
+		ds:         ds,
+		grain:      catalog.KeyGrain(),
+		fiscalYear: catalog.FiscalYear,
+		objects:    objectsOf(sources),
+		data:       data,
+	}
+}
+
+// cacheKey identifies the load of ds from dataDir, projected to its
+// fields.
+func cacheKey(dataDir string, ds c.Dataset) string {
+	fields := "*"
+	if !ds.InfersFields() {
+		names := make([]string, len(ds.Fields))
+		for i, f := range ds.Fields {
+			names[i] = f.Name
+		}
+		fields = strings.Join(names, ",")
+	}
+	return dataDir + "\x00" + ds.Name + "\x00" + fields
+}
+
+func objectsOf(sources []source) []blob.Object {
+	objects := make([]blob.Object, len(sources))
+	for i, src := range sources {
+		objects[i] = src.object
+	}
+	return objects
+}
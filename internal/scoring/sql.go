@@ -0,0 +1,180 @@
+package scoring
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// sqlDrivers maps the declared database drivers to the database/sql driver
+// names they register, each adding itself here. SQLite is always linked;
+// Postgres only when the service is built with the postgres tag.
+var sqlDrivers = map[string]string{}
+
+// SQLLoader reads a SQL dataset through database/sql. The rows of its query
+// or table are read with a cursor, so the result never has to fit in memory.
+type SQLLoader struct{}
+
+func (SQLLoader) loadData(ctx context.Context, src source, ds c.Dataset, emit func(observation)) error {
+	return loadDatasetSQL(ctx, src.object.Key, ds, emit)
+}
+
+func loadDatasetSQL(ctx context.Context, dsn string, ds c.Dataset, emit func(observation)) error {
+	driver, ok := sqlDrivers[ds.Database.Driver]
+	if !ok {
+		return fmt.Errorf("the %[1]s driver is not built in, build with -tags %[1]s", ds.Database.Driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, sqlQuery(ds))
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read columns: %v", err)
+	}
+	idxCompany := indexOf(columns, ds.Keys[0])
+	idxDate := indexOf(columns, ds.Date.Column)
+	if idxCompany == -1 || (ds.Date.Column != "" && idxDate == -1) {
+		return fmt.Errorf("missing required columns (%s, %s)", ds.Keys[0], ds.Date.Column)
+	}
+
+	// without a fields list every other column is read as a number
+	fields := ds.Fields
+	inferred := ds.InfersFields()
+	if inferred {
+		for i, column := range columns {
+			if i != idxCompany && i != idxDate {
+				fields = append(fields, c.Field{Name: column, Type: c.FieldNumber})
+			}
+		}
+	}
+	idxFields := make([]int, len(fields))
+	for i, field := range fields {
+		if idxFields[i] = indexOf(columns, field.Column()); idxFields[i] == -1 {
+			return fmt.Errorf("missing column for field %q", field.Name)
+		}
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		companyID := sqlString(values[idxCompany])
+		var parsedTime time.Time
+		if idxDate >= 0 {
+			parsedTime, err = sqlDate(companyID, values[idxDate], ds)
+		} else {
+			parsedTime, err = rowDate(companyID, "", ds)
+		}
+		if err != nil {
+			log.Printf("Skipping row for %s: %v", companyID, err)
+			continue
+		}
+
+		numericVals := map[string]float64{}
+		for i, field := range fields {
+			raw := values[idxFields[i]]
+			if raw == nil {
+				continue
+			}
+			v, err := parseField(sqlString(raw), field.Type)
+			if err != nil {
+				// inferred columns may hold text, such as a country name
+				if !inferred {
+					log.Printf("Skipping %s of company_id=%s: %v", field.Name, companyID, err)
+				}
+				continue
+			}
+			numericVals[field.Name] = v
+		}
+
+		emit(observation{
+			CompanyID: companyID,
+			Date:      parsedTime,
+			Values:    numericVals,
+		})
+	}
+	return rows.Err()
+}
+
+// sqlQuery is the declared query, or a scan of the declared table selecting
+// the key, the date and the field columns only.
+func sqlQuery(ds c.Dataset) string {
+	db := ds.Database
+	if db.Query != "" {
+		return db.Query
+	}
+	if ds.InfersFields() {
+		return "SELECT * FROM " + quoteIdentifier(db.Table)
+	}
+	columns := []string{quoteIdentifier(ds.Keys[0])}
+	if ds.Date.Column != "" {
+		columns = append(columns, quoteIdentifier(ds.Date.Column))
+	}
+	for _, field := range ds.Fields {
+		columns = append(columns, quoteIdentifier(field.Column()))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), quoteIdentifier(db.Table))
+}
+
+// quoteIdentifier quotes every part of a possibly schema-qualified name,
+// such as public.emissions.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// sqlDate reads the date of a row. Date and timestamp columns are dates
+// already; any other column is parsed in the declared format.
+func sqlDate(companyID string, v any, ds c.Dataset) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return checkDate(companyID, t.UTC())
+	}
+	return rowDate(companyID, sqlString(v), ds)
+}
+
+// sqlString renders a scanned value the way it would appear in a CSV cell,
+// so every format shares the same parsing.
+func sqlString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
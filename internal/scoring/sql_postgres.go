@@ -0,0 +1,13 @@
+//go:build postgres
+
+package scoring
+
+import (
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func init() {
+	sqlDrivers[c.DriverPostgres] = "pgx"
+}
@@ -0,0 +1,11 @@
+package scoring
+
+import (
+	_ "modernc.org/sqlite"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func init() {
+	sqlDrivers[c.DriverSQLite] = "sqlite"
+}
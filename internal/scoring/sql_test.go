@@ -0,0 +1,77 @@
+package scoring
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestLoadDatasetSQL(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "scores.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE emissions (company_id TEXT, reported TEXT, scope1 REAL, scope2 REAL, country TEXT);
+		INSERT INTO emissions VALUES
+			('1000', '2023-03-31', 4, 6, 'FR'),
+			('1000', '2023-09-30', 5, NULL, 'FR'),
+			('1001', '2024-01-31', 3, NULL, 'DE'),
+			('1001', 'soon', 9, 9, 'DE');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	t.Setenv("SCORES_DB", "scores.db")
+	ds := c.Dataset{
+		Name:     "emissions",
+		Database: &c.Database{Driver: c.DriverSQLite, DSN: "${SCORES_DB}", Table: "emissions"},
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "reported", Format: "2006-01-02"},
+		Fields: []c.Field{
+			{Name: "scope1", Type: c.FieldNumber},
+			{Name: "scope2", Type: c.FieldNumber},
+		},
+	}
+	dataService := testDataService(t)
+
+	data, err := dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		// the later row is taken whole, with its NULL scope2 missing
+		{CompanyID: "1000", Year: 2023}: {"scope1": 5},
+		{CompanyID: "1001", Year: 2024}: {"scope1": 3},
+	}, rowsOf(data))
+
+	// the table scan only selects the projected columns
+	ds.Fields = append(ds.Fields, c.Field{Name: "scope3", Type: c.FieldNumber})
+	data, err = dataService.Project(map[string]map[string]bool{"emissions": {"scope1": true}}).LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scope1"}, data.Fields())
+	assert.Equal(t, map[string]float64{"scope1": 5}, rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+
+	_, err = dataService.LoadDataset(context.Background(), dir, ds)
+	assert.ErrorContains(t, err, `failed to load dataset "emissions" from its sqlite database`)
+
+	// without a fields list every numeric column is read
+	ds.Fields = nil
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"scope1": 3}, rowsOf(data)[CompanyYearKey{CompanyID: "1001", Year: 2024}])
+
+	ds.Database = &c.Database{
+		Driver: c.DriverSQLite,
+		DSN:    filepath.Join(dir, "scores.db"),
+		Query:  "SELECT company_id AS id, reported, scope1 * 2 AS doubled FROM emissions WHERE country = 'FR'",
+	}
+	ds.Keys = []string{"id"}
+	ds.Fields = []c.Field{{Name: "scope1", Type: c.FieldNumber, Path: "doubled"}}
+	data, err = dataService.LoadDataset(context.Background(), dir, ds)
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"scope1": 10},
+	}, rowsOf(data))
+}
@@ -0,0 +1,605 @@
+package scoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/stream"
+)
+
+// StreamStore holds the streamed datasets of a catalog, aggregated as their
+// events are applied, like the rows of a file. It lives in memory. The rows
+// of change streams can be saved to a checkpoint, restored when the service
+// restarts; other streamed datasets only hold the events after the offsets
+// their consumer group committed.
+type StreamStore struct {
+	mu       sync.RWMutex
+	topics   map[string]c.Dataset
+	datasets map[string]streamed
+	// applied is the offset of the last event applied of each partition.
+	applied map[topicPartition]int64
+}
+
+// ErrTiedRows is wrapped by the error of an event whose row ties with others
+// of its company and period under the error tie-break policy. The event is
+// still applied: the period is left out of the dataset until an event
+// resolves the tie, rather than failing every load of it like a file would.
+var ErrTiedRows = errors.New("tied rows")
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// streamed is the state of one streamed dataset.
+type streamed interface {
+	// apply applies an event and returns the companies it changed, with an
+	// error wrapping ErrTiedRows when the event was applied but a period it
+	// changed is left out for its tied rows.
+	apply(msg stream.Message) ([]string, error)
+	// result reduces the events applied, without the periods whose rows
+	// tie.
+	result() *Table
+}
+
+// rowStream is a dataset streamed as rows, folded into its aggregates as
+// they come.
+type rowStream struct {
+	ds  c.Dataset
+	agg *aggregator
+}
+
+func (rs rowStream) apply(msg stream.Message) ([]string, error) {
+	var obj map[string]any
+	if err := json.Unmarshal(msg.Value, &obj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("failed to unmarshal event: expected an object")
+	}
+	if lookupPath(obj, rs.ds.Keys[0]) == nil && len(msg.Key) > 0 {
+		obj[rs.ds.Keys[0]] = string(msg.Key)
+	}
+	row, ok := jsonObservation(obj, rs.ds)
+	if !ok {
+		return nil, fmt.Errorf("invalid row for dataset %q", rs.ds.Name)
+	}
+	rs.agg.add(row)
+	if _, err := rs.agg.values(rs.agg.slot(row.CompanyID, row.Date)); err != nil {
+		return []string{row.CompanyID}, fmt.Errorf("%w: %w", ErrTiedRows, err)
+	}
+	return []string{row.CompanyID}, nil
+}
+
+func (rs rowStream) result() *Table {
+	result, _ := rs.agg.partial()
+	return result
+}
+
+// NewStreamStore returns a store without events for the streamed datasets
+// of catalog.
+func NewStreamStore(catalog *c.Datasets) (*StreamStore, error) {
+	periods, err := newPeriods(catalog.FiscalYear)
+	if err != nil {
+		return nil, err
+	}
+	st := &StreamStore{
+		topics:   make(map[string]c.Dataset),
+		datasets: make(map[string]streamed),
+		applied:  make(map[topicPartition]int64),
+	}
+	for _, ds := range catalog.Datasets {
+		if ds.Stream == nil {
+			continue
+		}
+		st.topics[ds.Stream.Topic] = ds
+		grain := aggregationGrain(ds, catalog)
+		if ds.Stream.Envelope == c.EnvelopeDebezium {
+			st.datasets[ds.Name] = newChangeStream(ds, periods, grain)
+		} else {
+			st.datasets[ds.Name] = rowStream{ds: ds, agg: newAggregator(ds, periods, grain)}
+		}
+	}
+	return st, nil
+}
+
+// Topics lists the topics of the streamed datasets, sorted.
+func (st *StreamStore) Topics() []string {
+	topics := make([]string, 0, len(st.topics))
+	for topic := range st.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Apply applies an event to the dataset streaming its topic and returns the
+// companies it changed, none when it changed nothing. An event at or before
+// the last offset applied of its partition is a redelivery and is not
+// applied again. An event leaving tied rows is applied, and its error wraps
+// ErrTiedRows.
+func (st *StreamStore) Apply(msg stream.Message) ([]string, error) {
+	ds, ok := st.topics[msg.Topic]
+	if !ok {
+		return nil, fmt.Errorf("no dataset streams topic %q", msg.Topic)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	if last, ok := st.applied[tp]; ok && msg.Offset <= last {
+		return nil, nil
+	}
+	companies, err := st.datasets[ds.Name].apply(msg)
+	if err != nil && !errors.Is(err, ErrTiedRows) {
+		return nil, err
+	}
+	st.applied[tp] = msg.Offset
+	return companies, err
+}
+
+// dataset reduces the events applied so far to a streamed dataset, leaving
+// out the periods whose rows tie. A nil store holds no events.
+func (st *StreamStore) dataset(name string) (*Table, error) {
+	if st == nil {
+		return newTable(nil, 0), nil
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	ds, ok := st.datasets[name]
+	if !ok {
+		return newTable(nil, 0), nil
+	}
+	return ds.result(), nil
+}
+
+// checkpoint is the state of the change streams of a StreamStore, with the
+// offsets of their topics applied.
+type checkpoint struct {
+	Offsets  []checkpointOffset     `json:"offsets"`
+	Datasets map[string]changeState `json:"datasets"`
+}
+
+type checkpointOffset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// SaveCheckpoint writes the rows of the change streams, with the source
+// positions and offsets applied, to a file, replacing it whole.
+func (st *StreamStore) SaveCheckpoint(path string) error {
+	st.mu.RLock()
+	cp := checkpoint{Datasets: make(map[string]changeState)}
+	for name, ds := range st.datasets {
+		if cs, ok := ds.(*changeStream); ok {
+			cp.Datasets[name] = cs.state()
+		}
+	}
+	for tp, offset := range st.applied {
+		if _, ok := cp.Datasets[st.topics[tp.topic].Name]; ok {
+			cp.Offsets = append(cp.Offsets, checkpointOffset{Topic: tp.topic, Partition: tp.partition, Offset: offset})
+		}
+	}
+	data, err := json.Marshal(cp)
+	st.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint restores the change streams saved to a file. A missing
+// file restores nothing, and datasets no longer streamed as changes are
+// ignored.
+func (st *StreamStore) LoadCheckpoint(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("failed to unmarshal checkpoint %s: %w", path, err)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for name, state := range cp.Datasets {
+		if cs, ok := st.datasets[name].(*changeStream); ok {
+			cs.restore(state)
+		}
+	}
+	for _, o := range cp.Offsets {
+		if _, ok := st.datasets[st.topics[o.Topic].Name].(*changeStream); ok {
+			st.applied[topicPartition{topic: o.Topic, partition: o.Partition}] = o.Offset
+		}
+	}
+	return nil
+}
+
+// Ingester applies the events of the topics of a StreamStore, one at a
+// time. The offset of an event is committed once it is applied and the
+// scores it affects are recomputed, so events are applied at least once.
+// Events that cannot be decoded are logged and skipped. Events leaving tied
+// rows are logged, and their companies rescored without the tied periods.
+type Ingester struct {
+	Logger   *zap.Logger
+	Consumer stream.Consumer
+	Store    *StreamStore
+	// Rescore recomputes the scores of the companies an event changed in
+	// a dataset. It is retried until it succeeds, before the event is
+	// committed.
+	Rescore func(ctx context.Context, dataset string, companies []string) error
+	// Checkpoint saves the store. When set, offsets are only committed once
+	// a checkpoint holds their events, so a service restoring it resumes
+	// where it stopped, and checkpoints are saved at most every
+	// CheckpointInterval. It is retried until it succeeds.
+	Checkpoint         func() error
+	CheckpointInterval time.Duration
+	// RetryDelay is the wait between attempts of Rescore and Checkpoint, a
+	// second by default.
+	RetryDelay time.Duration
+}
+
+// Run consumes events until ctx is done or the consumer is closed.
+// Events waiting for a checkpoint are committed once CheckpointInterval
+// elapses, even when no later event arrives.
+func (in *Ingester) Run(ctx context.Context) error {
+	// pending is the last event applied of each partition not committed
+	// yet, waiting for a checkpoint
+	pending := make(map[topicPartition]stream.Message)
+	var checkpointed time.Time
+	for {
+		var deadline time.Time
+		if len(pending) > 0 {
+			deadline = checkpointed.Add(in.CheckpointInterval)
+		}
+		msg, elapsed, err := in.fetch(ctx, deadline)
+		if ctx.Err() != nil || errors.Is(err, stream.ErrClosed) {
+			return nil
+		}
+		if elapsed {
+			if err := in.checkpoint(ctx, pending); err != nil {
+				return err
+			}
+			checkpointed = time.Now()
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch event: %w", err)
+		}
+		if err := in.apply(ctx, msg); err != nil {
+			return nil
+		}
+
+		if in.Checkpoint == nil {
+			if err := in.commit(ctx, msg); err != nil {
+				return err
+			}
+			continue
+		}
+		pending[topicPartition{topic: msg.Topic, partition: msg.Partition}] = msg
+		if time.Since(checkpointed) < in.CheckpointInterval {
+			continue
+		}
+		if err := in.checkpoint(ctx, pending); err != nil {
+			return err
+		}
+		checkpointed = time.Now()
+	}
+}
+
+// fetch fetches the next event, waiting for one until deadline when it is
+// set. elapsed reports that the deadline passed without an event.
+func (in *Ingester) fetch(ctx context.Context, deadline time.Time) (msg stream.Message, elapsed bool, err error) {
+	if deadline.IsZero() {
+		msg, err = in.Consumer.Fetch(ctx)
+		return msg, false, err
+	}
+	fetchCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	msg, err = in.Consumer.Fetch(fetchCtx)
+	if err != nil && ctx.Err() == nil && fetchCtx.Err() != nil {
+		return msg, true, nil
+	}
+	return msg, false, err
+}
+
+// checkpoint saves a checkpoint and commits the pending events it holds.
+// It only fails when an offset cannot be committed, and returns nil once
+// ctx is done.
+func (in *Ingester) checkpoint(ctx context.Context, pending map[topicPartition]stream.Message) error {
+	err := in.retry(ctx, in.Checkpoint, func(err error) {
+		in.Logger.Error("Failed to save checkpoint, retrying", zap.Error(err))
+	})
+	if err != nil {
+		return nil
+	}
+	for tp, msg := range pending {
+		if err := in.commit(ctx, msg); err != nil {
+			return err
+		}
+		delete(pending, tp)
+	}
+	return nil
+}
+
+// commit commits the offset of msg, returning nil once ctx is done.
+func (in *Ingester) commit(ctx context.Context, msg stream.Message) error {
+	if err := in.Consumer.Commit(ctx, msg); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to commit offset %d of %s/%d: %w", msg.Offset, msg.Topic, msg.Partition, err)
+	}
+	return nil
+}
+
+// apply applies msg and rescores the companies it changed. It only fails
+// once ctx is done.
+func (in *Ingester) apply(ctx context.Context, msg stream.Message) error {
+	companies, err := in.Store.Apply(msg)
+	if errors.Is(err, ErrTiedRows) {
+		in.Logger.Warn("Leaving out tied rows",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err))
+	} else if err != nil {
+		in.Logger.Warn("Skipping event",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err))
+		return nil
+	}
+	if len(companies) == 0 || in.Rescore == nil {
+		return nil
+	}
+	dataset := in.Store.topics[msg.Topic].Name
+	return in.retry(ctx, func() error { return in.Rescore(ctx, dataset, companies) }, func(err error) {
+		in.Logger.Error("Failed to rescore, retrying",
+			zap.Strings("companies", companies),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err))
+	})
+}
+
+// retry calls fn until it succeeds, reporting every failure. It only fails
+// once ctx is done.
+func (in *Ingester) retry(ctx context.Context, fn func() error, report func(error)) error {
+	delay := in.RetryDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		report(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// LiveScores keeps the scores of every served config up to date with the
+// streamed datasets. Plans are built once per version of the configs, and
+// every config keeps the datasets it was last scored with: an event only
+// loads again the streamed dataset it changed, and only the configs reading
+// that dataset are scored again. File and database datasets are read when a
+// config is first scored, and again once the configs are reloaded.
+//
+// Only the companies an event changed are scored again, every year of them
+// so time-series metrics follow; plans with cross-sectional metrics compare
+// companies with each other and are scored again whole.
+type LiveScores struct {
+	logger      *zap.Logger
+	configs     *c.Registry
+	dataService *DataLoaderService
+
+	// rescoring serialises Rescore, which owns version and live.
+	rescoring sync.Mutex
+	version   uint64
+	live      map[string]*liveScore
+
+	mu     sync.RWMutex
+	scores map[string]map[CompanyYearKey]ScoredRow
+}
+
+// liveScore is the plan of a served config and the datasets it was last
+// scored with, before their grains were joined.
+type liveScore struct {
+	plan   *Plan
+	fields map[string]map[string]bool
+	data   *DataLoaderService
+	loaded map[string]*Table
+	// stale are the streamed datasets changed since they were loaded, by
+	// events the config did not read.
+	stale map[string]bool
+}
+
+// NewLiveScores scores the configs of configs with the datasets of
+// dataService.
+func NewLiveScores(logger *zap.Logger, configs *c.Registry, dataService *DataLoaderService) *LiveScores {
+	return &LiveScores{
+		logger:      logger,
+		configs:     configs,
+		dataService: dataService,
+		scores:      make(map[string]map[CompanyYearKey]ScoredRow),
+	}
+}
+
+// Rescore recomputes the scores of companies after an event changed the
+// streamed dataset. Configs not scored yet are scored whole. A config whose
+// plan cannot be built is logged and skipped; failing to load the datasets
+// is returned.
+func (ls *LiveScores) Rescore(ctx context.Context, dataset string, companies []string) error {
+	ls.rescoring.Lock()
+	defer ls.rescoring.Unlock()
+	ls.plan()
+
+	changed := make(map[string]bool, len(companies))
+	for _, company := range companies {
+		changed[company] = true
+	}
+
+	names := make([]string, 0, len(ls.live))
+	for name := range ls.live {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		live := ls.live[name]
+		whole := live.loaded == nil || live.plan.crossSectional()
+		if live.loaded != nil {
+			if _, reads := live.fields[dataset]; !reads {
+				continue
+			}
+			live.stale[dataset] = true
+		}
+		datasets, err := live.load(ctx)
+		if err != nil {
+			return err
+		}
+
+		keys := getAllDataCompanyKeys(datasets)
+		if !whole {
+			affected := keys[:0]
+			for _, key := range keys {
+				if changed[key.CompanyID] {
+					affected = append(affected, key)
+				}
+			}
+			keys = affected
+		}
+		rows := parallelComputeScores(ctx, ls.logger, keys, live.plan, datasets, NumWorkers)
+		ls.logger.Debug("Rescored live score", zap.String("config", name), zap.Int("keys", len(keys)))
+
+		ls.mu.Lock()
+		scores := ls.scores[name]
+		if whole {
+			scores = make(map[CompanyYearKey]ScoredRow, len(rows))
+			ls.scores[name] = scores
+		} else {
+			for key := range scores {
+				if changed[key.CompanyID] {
+					delete(scores, key)
+				}
+			}
+		}
+		for _, row := range rows {
+			scores[row.Key] = row
+		}
+		ls.mu.Unlock()
+	}
+	return nil
+}
+
+// plan builds the plans of the served configs again once they are
+// reloaded. Configs with a new plan are scored whole by the next Rescore.
+func (ls *LiveScores) plan() {
+	version := ls.configs.Version()
+	if ls.live != nil && version == ls.version {
+		return
+	}
+	live := make(map[string]*liveScore)
+	for _, name := range ls.configs.Names() {
+		scoreConfig, ok := ls.configs.Get(name)
+		if !ok {
+			continue
+		}
+		plan, err := BuildPlan(ls.logger, scoreConfig, ls.configs.Get, ls.dataService.Datasets())
+		if err != nil {
+			ls.logger.Error("Failed to plan live score", zap.String("config", name), zap.Error(err))
+			continue
+		}
+		fields := plan.DatasetFields()
+		live[name] = &liveScore{
+			plan:   plan,
+			fields: fields,
+			data:   ls.dataService.Project(fields),
+			stale:  make(map[string]bool),
+		}
+	}
+	ls.live, ls.version = live, version
+}
+
+// load returns the datasets of the config, joined at the grain of the
+// keys. The first load reads every dataset; later ones only the stale
+// streamed datasets.
+func (live *liveScore) load(ctx context.Context) (map[string]*Table, error) {
+	if live.loaded == nil {
+		loaded, err := live.data.loadAll(ctx, Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load data from folder: %w", err)
+		}
+		live.loaded = loaded
+	} else {
+		for name := range live.stale {
+			ds, ok := live.data.Datasets().Get(name)
+			if !ok {
+				continue
+			}
+			data, err := live.data.LoadDataset(ctx, Dir, ds)
+			if err != nil {
+				return nil, err
+			}
+			live.loaded[name] = data
+		}
+	}
+	clear(live.stale)
+	return live.data.join(live.loaded), nil
+}
+
+// Scores returns the latest scores of a config, sorted by key, and false
+// when it has not been scored yet.
+func (ls *LiveScores) Scores(name string) ([]ScoredRow, bool) {
+	if scoreConfig, ok := ls.configs.Get(name); ok {
+		name = scoreConfig.Name
+	}
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	scores, ok := ls.scores[name]
+	if !ok {
+		return nil, false
+	}
+	rows := make([]ScoredRow, 0, len(scores))
+	for _, row := range scores {
+		rows = append(rows, row)
+	}
+	sortScoredRows(rows)
+	return rows, true
+}
@@ -0,0 +1,568 @@
+package scoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/stream"
+)
+
+const liveConfig = `name: live
+metrics:
+  - name: total
+    operation:
+      type: sum
+      parameters:
+        - source: emissions.emi_1
+`
+
+const rankedConfig = `name: ranked
+metrics:
+  - name: total
+    operation:
+      type: sum
+      parameters:
+        - source: emissions.emi_1
+  - name: rank
+    operation:
+      type: percentile_rank
+      parameters:
+        - source: self.total
+`
+
+func TestIngester(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "live.yaml"), []byte(liveConfig), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ranked.yaml"), []byte(rankedConfig), 0o644))
+	configs, err := c.NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:        "emissions",
+		Stream:      &c.Stream{Topic: "emissions-events"},
+		Keys:        []string{"company_id"},
+		Date:        c.DateColumn{Column: "date", Format: c.DateFormatYear},
+		Aggregation: c.AggregateSum,
+		Fields:      []c.Field{{Name: "emi_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"emissions-events"}, store.Topics())
+
+	dataService := NewDataLoaderService(NewLoaderRegistry(), catalog, store)
+	live := NewLiveScores(zap.NewNop(), configs, dataService)
+
+	broker := stream.NewMemory()
+	broker.Produce("emissions-events", []byte("1000"), []byte(`{"date": 2023, "emi_1": 4}`))
+	broker.Produce("emissions-events", nil, []byte(`{"company_id": "1001", "date": 2023, "emi_1": 10}`))
+	broker.Produce("emissions-events", []byte("1000"), []byte(`not json`))
+	broker.Produce("emissions-events", []byte("1000"), []byte(`{"date": 2023, "emi_1": 2}`))
+
+	// every rescore sees its event applied but not committed yet, and the
+	// first rescore of company 1001 fails
+	var mu sync.Mutex
+	var rescored []string
+	var committed []int64
+	failed := false
+	rescore := func(ctx context.Context, dataset string, companies []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if companies[0] == "1001" && !failed {
+			failed = true
+			return errors.New("bucket unavailable")
+		}
+		rescored = append(rescored, companies...)
+		committed = append(committed, broker.Committed("scoring", "emissions-events"))
+		return live.Rescore(ctx, dataset, companies)
+	}
+	run := func(group string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		ingester := &Ingester{
+			Logger:     zap.NewNop(),
+			Consumer:   broker.Consumer(group, store.Topics()...),
+			Store:      store,
+			Rescore:    rescore,
+			RetryDelay: time.Millisecond,
+		}
+		go func() { done <- ingester.Run(ctx) }()
+		require.Eventually(t, func() bool {
+			return broker.Committed(group, "emissions-events") == 4
+		}, 5*time.Second, time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+	}
+
+	run("scoring")
+	assert.Equal(t, []string{"1000", "1001", "1000"}, rescored)
+	// the undecodable event is skipped and committed
+	assert.Equal(t, []int64{0, 1, 3}, committed)
+
+	data, err := dataService.LoadDataset(context.Background(), "", catalog.Datasets[0])
+	require.NoError(t, err)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"emi_1": 6},
+		{CompanyID: "1001", Year: 2023}: {"emi_1": 10},
+	}, rowsOf(data))
+
+	scores, ok := live.Scores("live.yaml")
+	require.True(t, ok)
+	assert.Equal(t, []ScoredRow{
+		{Key: CompanyYearKey{CompanyID: "1000", Year: 2023}, Metrics: map[string]float64{"total": 6}},
+		{Key: CompanyYearKey{CompanyID: "1001", Year: 2023}, Metrics: map[string]float64{"total": 10}},
+	}, scores)
+	// ranks are recomputed for every company when one changes
+	scores, ok = live.Scores("ranked")
+	require.True(t, ok)
+	assert.Equal(t, 0.0, scores[0].Metrics["rank"])
+	assert.Equal(t, 1.0, scores[1].Metrics["rank"])
+
+	// a redelivery, here to another group sharing the store, is not
+	// applied twice
+	run("replay")
+	assert.Len(t, rescored, 3)
+	data, err = dataService.LoadDataset(context.Background(), "", catalog.Datasets[0])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"emi_1": 6}, rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+
+	_, ok = live.Scores("unknown")
+	assert.False(t, ok)
+}
+
+func TestLiveScoresRescore(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "live.yaml"), []byte(liveConfig), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "waste.yaml"), []byte(`name: waste
+metrics:
+  - name: total
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+`), 0o644))
+	configs, err := c.NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+
+	lake := blob.NewMemory()
+	lake.Put("waste.csv", []byte("company_id,date,was_1\n1000,2023,3\n"))
+	bucket := &countingBucket{Bucket: lake}
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:        "emissions",
+		Stream:      &c.Stream{Topic: "emissions-events"},
+		Keys:        []string{"company_id"},
+		Date:        c.DateColumn{Column: "date", Format: c.DateFormatYear},
+		Aggregation: c.AggregateSum,
+		Fields:      []c.Field{{Name: "emi_1", Type: c.FieldNumber}},
+	}, {
+		Name:     "waste",
+		Location: "s3://lake/waste.csv",
+		Keys:     []string{"company_id"},
+		Date:     c.DateColumn{Column: "date", Format: c.DateFormatYear},
+		Fields:   []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	dataService := NewDataLoaderService(NewLoaderRegistry(), catalog, store)
+	dataService.RegisterBucket("s3://lake", bucket)
+	live := NewLiveScores(zap.NewNop(), configs, dataService)
+
+	offset := int64(0)
+	event := func(value string) {
+		t.Helper()
+		msg := stream.Message{Topic: "emissions-events", Offset: offset, Value: []byte(value)}
+		offset++
+		companies, err := store.Apply(msg)
+		require.NoError(t, err)
+		require.NoError(t, live.Rescore(context.Background(), "emissions", companies))
+	}
+	companies := func(name string) []string {
+		t.Helper()
+		scores, ok := live.Scores(name)
+		require.True(t, ok)
+		var ids []string
+		for _, row := range scores {
+			ids = append(ids, row.Key.CompanyID)
+		}
+		return ids
+	}
+
+	// every config is scored whole at first, listing the file of each
+	event(`{"company_id": "1000", "date": 2023, "emi_1": 4}`)
+	assert.Equal(t, 2, bucket.listed)
+	assert.Equal(t, []string{"1000"}, companies("live"))
+	assert.Equal(t, []string{"1000"}, companies("waste"))
+
+	// later events only load the streamed dataset again, for the configs
+	// reading it
+	event(`{"company_id": "1001", "date": 2023, "emi_1": 10}`)
+	assert.Equal(t, 2, bucket.listed)
+	assert.Equal(t, []string{"1000", "1001"}, companies("live"))
+	assert.Equal(t, []string{"1000"}, companies("waste"))
+	assert.Empty(t, live.live["waste"].stale)
+
+	// reloaded configs are planned and scored whole again
+	require.NoError(t, configs.Reload())
+	event(`{"company_id": "1002", "date": 2023, "emi_1": 1}`)
+	assert.Equal(t, 4, bucket.listed)
+	assert.Equal(t, []string{"1000", "1001", "1002"}, companies("live"))
+	assert.Equal(t, []string{"1000", "1001", "1002"}, companies("waste"))
+}
+
+func TestLoadDatasetWithoutStreams(t *testing.T) {
+	ds := c.Dataset{
+		Name:   "emissions",
+		Stream: &c.Stream{Topic: "emissions-events"},
+		Keys:   []string{"company_id"},
+		Date:   c.DateColumn{Column: "date"},
+	}
+	dataService := testDataService(t)
+	data, err := dataService.LoadDataset(context.Background(), "", ds)
+	require.NoError(t, err)
+	assert.Zero(t, data.Len())
+}
+
+func TestChangeStream(t *testing.T) {
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:        "waste",
+		Stream:      &c.Stream{Topic: "erp.public.waste", Envelope: c.EnvelopeDebezium},
+		Keys:        []string{"company_id"},
+		Date:        c.DateColumn{Column: "reported_at"},
+		Aggregation: c.AggregateLatest,
+		Fields:      []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	dataService := NewDataLoaderService(NewLoaderRegistry(), catalog, store)
+
+	offset := int64(0)
+	apply := func(key, value string) []string {
+		t.Helper()
+		msg := stream.Message{Topic: "erp.public.waste", Offset: offset, Key: []byte(key)}
+		if value != "" {
+			msg.Value = []byte(value)
+		}
+		offset++
+		companies, err := store.Apply(msg)
+		require.NoError(t, err)
+		return companies
+	}
+	load := func() map[CompanyYearKey]map[string]float64 {
+		t.Helper()
+		data, err := dataService.LoadDataset(context.Background(), "", catalog.Datasets[0])
+		require.NoError(t, err)
+		return rowsOf(data)
+	}
+
+	// a snapshot, one event with its schema
+	assert.Equal(t, []string{"1000"}, apply(`{"id": 1}`,
+		`{"op": "r", "after": {"id": 1, "company_id": "1000", "reported_at": "2023-03-31", "was_1": 5}, "source": {"lsn": 100}}`))
+	assert.Equal(t, []string{"1000"}, apply(`{"schema": {"type": "struct"}, "payload": {"id": 2}}`,
+		`{"schema": {"type": "struct"}, "payload": {"op": "r", "after": {"id": 2, "company_id": "1000", "reported_at": "2023-09-30", "was_1": 7}, "source": {"lsn": 100}}}`))
+	assert.Equal(t, []string{"1001"}, apply(`{"id": 3}`,
+		`{"op": "c", "after": {"id": 3, "company_id": "1001", "reported_at": "2023-06-30", "was_1": 1}, "source": {"lsn": 110}}`))
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"was_1": 7},
+		{CompanyID: "1001", Year: 2023}: {"was_1": 1},
+	}, load())
+
+	// deleting the latest row of the year selects the row before it, and
+	// the tombstone following the delete changes nothing
+	assert.Equal(t, []string{"1000"}, apply(`{"id": 2}`,
+		`{"op": "d", "before": {"id": 2, "company_id": "1000", "reported_at": "2023-09-30", "was_1": 7}, "after": null, "source": {"lsn": 120}}`))
+	assert.Empty(t, apply(`{"id": 2}`, ""))
+	assert.Equal(t, map[string]float64{"was_1": 5}, load()[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+
+	// an update moving a row to another company and year leaves the key
+	// it was in
+	assert.Equal(t, []string{"1001", "1000"}, apply(`{"id": 3}`,
+		`{"op": "u", "before": {"id": 3}, "after": {"id": 3, "company_id": "1000", "reported_at": "2024-06-30", "was_1": 2}, "source": {"lsn": 130}}`))
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"was_1": 5},
+		{CompanyID: "1000", Year: 2024}: {"was_1": 2},
+	}, load())
+
+	// changes replayed by a restarted connector, at positions already
+	// applied, are skipped
+	assert.Empty(t, apply(`{"id": 2}`,
+		`{"op": "r", "after": {"id": 2, "company_id": "1000", "reported_at": "2023-09-30", "was_1": 7}, "source": {"lsn": 100}}`))
+	assert.Empty(t, apply(`{"id": 3}`,
+		`{"op": "u", "after": {"id": 3, "company_id": "1000", "reported_at": "2024-06-30", "was_1": 9}, "source": {"lsn": 130}}`))
+	assert.Equal(t, map[string]float64{"was_1": 2}, load()[CompanyYearKey{CompanyID: "1000", Year: 2024}])
+
+	for _, tc := range []struct{ key, value, wantErr string }{
+		{key: "", value: `{"op": "c", "after": {"id": 4}}`, wantErr: "change event has no key"},
+		{key: `{"id": 4}`, value: `{"op": "c"}`, wantErr: `"c" change event has no row after it`},
+		{key: `{"id": 4}`, value: `{"op": "x"}`, wantErr: `unknown change operation "x"`},
+		{key: `{"id": 4}`, value: `[1]`, wantErr: "failed to unmarshal change event"},
+	} {
+		_, err := store.Apply(stream.Message{Topic: "erp.public.waste", Offset: offset, Key: []byte(tc.key), Value: []byte(tc.value)})
+		assert.ErrorContains(t, err, tc.wantErr)
+	}
+
+	// a checkpoint restores the rows, positions and offsets
+	path := filepath.Join(t.TempDir(), "streams.json")
+	require.NoError(t, store.SaveCheckpoint(path))
+	restored, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	require.NoError(t, restored.LoadCheckpoint(path))
+	want := load()
+	data, err := restored.dataset("waste")
+	require.NoError(t, err)
+	assert.Equal(t, want, rowsOf(data))
+	companies, err := restored.Apply(stream.Message{Topic: "erp.public.waste", Offset: 0, Key: []byte(`{"id": 9}`),
+		Value: []byte(`{"op": "c", "after": {"id": 9, "company_id": "1002", "reported_at": "2023-01-31"}}`)})
+	require.NoError(t, err)
+	assert.Empty(t, companies)
+	companies, err = restored.Apply(stream.Message{Topic: "erp.public.waste", Offset: offset, Key: []byte(`{"id": 1}`),
+		Value: []byte(`{"op": "r", "after": {"id": 1, "company_id": "1000", "reported_at": "2023-03-31", "was_1": 5}, "source": {"lsn": 100}}`)})
+	require.NoError(t, err)
+	assert.Empty(t, companies)
+	require.NoError(t, restored.LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json")))
+
+	// a truncate removes every row, and earlier changes replayed after it
+	// are skipped
+	assert.Equal(t, []string{"1000"}, apply("", `{"op": "t", "source": {"lsn": 140}}`))
+	assert.Empty(t, load())
+	assert.Empty(t, apply(`{"id": 1}`,
+		`{"op": "c", "after": {"id": 5, "company_id": "1000", "reported_at": "2023-03-31", "was_1": 5}, "source": {"lsn": 135}}`))
+	assert.Equal(t, []string{"1000"}, apply(`{"id": 1}`,
+		`{"op": "c", "after": {"id": 1, "company_id": "1000", "reported_at": "2023-03-31", "was_1": 6}, "source": {"lsn": 150}}`))
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1000", Year: 2023}: {"was_1": 6},
+	}, load())
+}
+
+func TestChangeStreamTies(t *testing.T) {
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:        "waste",
+		Stream:      &c.Stream{Topic: "erp.public.waste", Envelope: c.EnvelopeDebezium},
+		Keys:        []string{"company_id"},
+		Date:        c.DateColumn{Column: "reported_at"},
+		Aggregation: c.AggregateLatest,
+		TieBreak:    c.TieError,
+		Fields:      []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	_, err = store.Apply(stream.Message{Topic: "erp.public.waste", Offset: 0, Key: []byte("1"),
+		Value: []byte(`{"op": "c", "after": {"company_id": "1000", "reported_at": "2023-03-31", "was_1": 1}}`)})
+	require.NoError(t, err)
+	companies, err := store.Apply(stream.Message{Topic: "erp.public.waste", Offset: 1, Key: []byte("2"),
+		Value: []byte(`{"op": "c", "after": {"company_id": "1000", "reported_at": "2023-03-31", "was_1": 2}}`)})
+	assert.ErrorIs(t, err, ErrTiedRows)
+	assert.ErrorContains(t, err, `dataset "waste", company 1000, year 2023`)
+	assert.Equal(t, []string{"1000"}, companies)
+
+	// the tied year is left out, and the event applied
+	data, err := store.dataset("waste")
+	require.NoError(t, err)
+	assert.Zero(t, data.Len())
+	companies, err = store.Apply(stream.Message{Topic: "erp.public.waste", Offset: 1, Key: []byte("2"),
+		Value: []byte(`{"op": "c", "after": {"company_id": "1000", "reported_at": "2023-03-31", "was_1": 2}}`)})
+	require.NoError(t, err)
+	assert.Empty(t, companies)
+
+	// deleting one of the tied rows resolves the conflict
+	_, err = store.Apply(stream.Message{Topic: "erp.public.waste", Offset: 2, Key: []byte("2"), Value: []byte(`{"op": "d"}`)})
+	require.NoError(t, err)
+	data, err = store.dataset("waste")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"was_1": 1}, rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+}
+
+func TestIngesterTiedRows(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "waste.yaml"), []byte(`name: waste
+metrics:
+  - name: total
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+`), 0o644))
+	configs, err := c.NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+
+	for _, envelope := range []string{"", c.EnvelopeDebezium} {
+		t.Run("envelope="+envelope, func(t *testing.T) {
+			catalog := &c.Datasets{Datasets: []c.Dataset{{
+				Name:        "waste",
+				Stream:      &c.Stream{Topic: "waste-events", Envelope: envelope},
+				Keys:        []string{"company_id"},
+				Date:        c.DateColumn{Column: "reported_at"},
+				Aggregation: c.AggregateLatest,
+				TieBreak:    c.TieError,
+				Fields:      []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+			}}}
+			store, err := NewStreamStore(catalog)
+			require.NoError(t, err)
+			live := NewLiveScores(zap.NewNop(), configs, NewDataLoaderService(NewLoaderRegistry(), catalog, store))
+
+			// two rows of company 1000 disagree on the same date, then a row
+			// of company 1001 comes
+			broker := stream.NewMemory()
+			for id, row := range []string{
+				`{"company_id": "1000", "reported_at": "2023-03-31", "was_1": 1}`,
+				`{"company_id": "1000", "reported_at": "2023-03-31", "was_1": 2}`,
+				`{"company_id": "1001", "reported_at": "2023-03-31", "was_1": 5}`,
+			} {
+				if envelope == c.EnvelopeDebezium {
+					row = `{"op": "c", "after": ` + row + `}`
+				}
+				broker.Produce("waste-events", []byte(strconv.Itoa(id)), []byte(row))
+			}
+
+			var logs bytes.Buffer
+			core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&logs), zap.WarnLevel)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			ingester := &Ingester{
+				Logger:     zap.New(core),
+				Consumer:   broker.Consumer("scoring", store.Topics()...),
+				Store:      store,
+				Rescore:    live.Rescore,
+				RetryDelay: time.Millisecond,
+			}
+			go func() { done <- ingester.Run(ctx) }()
+			require.Eventually(t, func() bool {
+				return broker.Committed("scoring", "waste-events") == 3
+			}, 5*time.Second, time.Millisecond)
+			cancel()
+			require.NoError(t, <-done)
+
+			// the tie is logged once, and rescoring never fails
+			var entries []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &entry))
+				entries = append(entries, entry)
+			}
+			require.Len(t, entries, 1)
+			assert.Equal(t, "Leaving out tied rows", entries[0]["msg"])
+			assert.Equal(t, float64(1), entries[0]["offset"])
+
+			scores, ok := live.Scores("waste")
+			require.True(t, ok)
+			require.Len(t, scores, 1)
+			assert.Equal(t, "1001", scores[0].Key.CompanyID)
+		})
+	}
+}
+
+func TestIngesterCheckpoint(t *testing.T) {
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:   "waste",
+		Stream: &c.Stream{Topic: "erp.public.waste", Envelope: c.EnvelopeDebezium},
+		Keys:   []string{"company_id"},
+		Date:   c.DateColumn{Column: "reported_at"},
+		Fields: []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	broker := stream.NewMemory()
+	for id := range 3 {
+		broker.Produce("erp.public.waste", []byte(`{"id": `+strconv.Itoa(id)+`}`),
+			[]byte(`{"op": "c", "after": {"company_id": "1000", "reported_at": "2023-03-31", "was_1": 1}}`))
+	}
+
+	// offsets are committed only once a checkpoint holds their events, the
+	// first of which fails
+	path := filepath.Join(t.TempDir(), "streams.json")
+	var mu sync.Mutex
+	var checkpointed []int64
+	attempts := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	ingester := &Ingester{
+		Logger:   zap.NewNop(),
+		Consumer: broker.Consumer("scoring", store.Topics()...),
+		Store:    store,
+		Checkpoint: func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts == 1 {
+				return errors.New("disk full")
+			}
+			checkpointed = append(checkpointed, broker.Committed("scoring", "erp.public.waste"))
+			return store.SaveCheckpoint(path)
+		},
+		CheckpointInterval: time.Hour,
+		RetryDelay:         time.Millisecond,
+	}
+	go func() { done <- ingester.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return broker.Committed("scoring", "erp.public.waste") == 1
+	}, 5*time.Second, time.Millisecond)
+	broker.Produce("erp.public.waste", []byte(`{"id": 9}`), []byte(`{"op": "x"}`))
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	// the later events wait for the next checkpoint, an hour later
+	assert.Equal(t, int64(1), broker.Committed("scoring", "erp.public.waste"))
+	assert.Equal(t, []int64{0}, checkpointed)
+	restored, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	require.NoError(t, restored.LoadCheckpoint(path))
+	data, err := restored.dataset("waste")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"was_1": 1}, rowsOf(data)[CompanyYearKey{CompanyID: "1000", Year: 2023}])
+}
+
+func TestIngesterCheckpointInterval(t *testing.T) {
+	catalog := &c.Datasets{Datasets: []c.Dataset{{
+		Name:   "waste",
+		Stream: &c.Stream{Topic: "erp.public.waste", Envelope: c.EnvelopeDebezium},
+		Keys:   []string{"company_id"},
+		Date:   c.DateColumn{Column: "reported_at"},
+		Fields: []c.Field{{Name: "was_1", Type: c.FieldNumber}},
+	}}}
+	store, err := NewStreamStore(catalog)
+	require.NoError(t, err)
+	broker := stream.NewMemory()
+	for id := range 3 {
+		broker.Produce("erp.public.waste", []byte(`{"id": `+strconv.Itoa(id)+`}`),
+			[]byte(`{"op": "c", "after": {"company_id": "1000", "reported_at": "2023-03-31", "was_1": 1}}`))
+	}
+
+	// the events after the first checkpoint are committed once the
+	// interval elapses, though the topic goes quiet
+	var checkpoints atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	ingester := &Ingester{
+		Logger:   zap.NewNop(),
+		Consumer: broker.Consumer("scoring", store.Topics()...),
+		Store:    store,
+		Checkpoint: func() error {
+			checkpoints.Add(1)
+			return nil
+		},
+		CheckpointInterval: 50 * time.Millisecond,
+	}
+	go func() { done <- ingester.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return broker.Committed("scoring", "erp.public.waste") == 3
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(2), checkpoints.Load())
+}
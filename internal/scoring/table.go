@@ -0,0 +1,185 @@
+package scoring
+
+import (
+	"sort"
+)
+
+// Table is a dataset as the evaluator reads it: a row per key, holding a
+// value per field in one flat slice, so memory grows with the keys and
+// fields without a map per key. A table is held whole in memory; nothing
+// bounds the keys it holds. The rows of a dataset joined at a finer
+// grain share the values of the period covering them. A Table is not
+// modified once built, and is shared between loads.
+type Table struct {
+	fields []string
+	// columns is the index of each field in a row.
+	columns map[string]int
+	// rows is the row of each key, in values.
+	rows   map[CompanyYearKey]int
+	keys   []CompanyYearKey
+	values []float64
+	// set has a bit per value, set when the row has a value of the field.
+	set []uint64
+}
+
+// newTable returns a table without rows of fields, with room for n rows.
+func newTable(fields []string, n int) *Table {
+	columns := make(map[string]int, len(fields))
+	for i, name := range fields {
+		columns[name] = i
+	}
+	return &Table{
+		fields:  fields,
+		columns: columns,
+		rows:    make(map[CompanyYearKey]int, n),
+		keys:    make([]CompanyYearKey, 0, n),
+		values:  make([]float64, 0, n*len(fields)),
+		set:     make([]uint64, 0, (n*len(fields)+63)/64),
+	}
+}
+
+// tableOf builds a table from rows of values by field.
+func tableOf(rows map[CompanyYearKey]map[string]float64) *Table {
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row {
+			seen[name] = true
+		}
+	}
+	fields := make([]string, 0, len(seen))
+	for name := range seen {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+
+	t := newTable(fields, len(rows))
+	for key, row := range rows {
+		r := t.grow()
+		for name, v := range row {
+			t.put(r, t.columns[name], v)
+		}
+		t.commit(key)
+	}
+	return t
+}
+
+// grow adds a row without values and returns its index. The row is only
+// keyed once committed; a row grown again before replaces it.
+func (t *Table) grow() int {
+	r := len(t.keys)
+	start, end := r*len(t.fields), (r+1)*len(t.fields)
+	t.values = append(t.values[:start], make([]float64, len(t.fields))...)
+	for len(t.set)*64 < end {
+		t.set = append(t.set, 0)
+	}
+	for i := start; i < end; i++ {
+		t.set[i/64] &^= 1 << (i % 64)
+	}
+	return r
+}
+
+// put sets the value of column f of row r.
+func (t *Table) put(r, f int, v float64) {
+	i := r*len(t.fields) + f
+	t.values[i] = v
+	t.set[i/64] |= 1 << (i % 64)
+}
+
+// commit keys the row last grown.
+func (t *Table) commit(key CompanyYearKey) {
+	t.rows[key] = len(t.keys)
+	t.keys = append(t.keys, key)
+}
+
+// Len is the number of keys with a row.
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.keys)
+}
+
+// Keys lists the keys with a row. The slice is shared and must not be
+// modified.
+func (t *Table) Keys() []CompanyYearKey {
+	if t == nil {
+		return nil
+	}
+	return t.keys
+}
+
+// Fields lists the fields of the rows, sorted.
+func (t *Table) Fields() []string {
+	if t == nil {
+		return nil
+	}
+	return t.fields
+}
+
+// Has reports whether key has a row.
+func (t *Table) Has(key CompanyYearKey) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.rows[key]
+	return ok
+}
+
+// Value returns the value of field in the row of key, false when the key
+// has no row or the row no value of the field.
+func (t *Table) Value(key CompanyYearKey, field string) (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	r, ok := t.rows[key]
+	if !ok {
+		return 0, false
+	}
+	f, ok := t.columns[field]
+	if !ok {
+		return 0, false
+	}
+	i := r*len(t.fields) + f
+	if t.set[i/64]&(1<<(i%64)) == 0 {
+		return 0, false
+	}
+	return t.values[i], true
+}
+
+// Row returns the values of the row of key by field, false when the key
+// has no row.
+func (t *Table) Row(key CompanyYearKey) (map[string]float64, bool) {
+	if !t.Has(key) {
+		return nil, false
+	}
+	row := make(map[string]float64, len(t.fields))
+	for _, name := range t.fields {
+		if v, ok := t.Value(key, name); ok {
+			row[name] = v
+		}
+	}
+	return row, true
+}
+
+// at returns the rows of t for the keys of a finer grain than its own,
+// each reading the row of the period of grain covering it. The values are
+// shared with t.
+func (t *Table) at(keys map[CompanyYearKey]bool, grain string) *Table {
+	if t == nil {
+		return newTable(nil, 0)
+	}
+	joined := &Table{
+		fields:  t.fields,
+		columns: t.columns,
+		rows:    make(map[CompanyYearKey]int, len(keys)),
+		values:  t.values,
+		set:     t.set,
+	}
+	for key := range keys {
+		if r, ok := t.rows[coarsen(key, grain)]; ok {
+			joined.rows[key] = r
+			joined.keys = append(joined.keys, key)
+		}
+	}
+	return joined
+}
@@ -0,0 +1,142 @@
+package scoring
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// tablesOf builds the tables of datasets given as rows of values by field.
+func tablesOf(datasets map[string]map[CompanyYearKey]map[string]float64) map[string]*Table {
+	tables := make(map[string]*Table, len(datasets))
+	for name, rows := range datasets {
+		tables[name] = tableOf(rows)
+	}
+	return tables
+}
+
+// rowsOf returns the rows of a table as values by field.
+func rowsOf(t *Table) map[CompanyYearKey]map[string]float64 {
+	rows := make(map[CompanyYearKey]map[string]float64, t.Len())
+	for _, key := range t.Keys() {
+		rows[key], _ = t.Row(key)
+	}
+	return rows
+}
+
+func TestTable(t *testing.T) {
+	key := CompanyYearKey{CompanyID: "1000", Year: 2023}
+	table := tableOf(map[CompanyYearKey]map[string]float64{
+		key:                             {"emi_1": 4},
+		{CompanyID: "1001", Year: 2023}: {"emi_1": 0, "emi_2": 2},
+	})
+	assert.Equal(t, 2, table.Len())
+	assert.Equal(t, []string{"emi_1", "emi_2"}, table.Fields())
+
+	v, ok := table.Value(key, "emi_1")
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, v)
+	// a row without a value of a field is not a zero value
+	_, ok = table.Value(key, "emi_2")
+	assert.False(t, ok)
+	_, ok = table.Value(key, "emi_3")
+	assert.False(t, ok)
+	_, ok = table.Value(CompanyYearKey{CompanyID: "1002", Year: 2023}, "emi_1")
+	assert.False(t, ok)
+	assert.True(t, table.Has(key))
+
+	// rows joined at a finer grain share the values of their period
+	q1 := CompanyYearKey{CompanyID: "1000", Year: 2023, Period: 1}
+	q2 := CompanyYearKey{CompanyID: "1000", Year: 2023, Period: 2}
+	joined := table.at(map[CompanyYearKey]bool{q1: true, q2: true, {CompanyID: "1002", Year: 2023, Period: 1}: true}, c.GrainYear)
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		q1: {"emi_1": 4},
+		q2: {"emi_1": 4},
+	}, rowsOf(joined))
+
+	var missing *Table
+	assert.Zero(t, missing.Len())
+	assert.False(t, missing.Has(key))
+	assert.Zero(t, missing.at(map[CompanyYearKey]bool{q1: true}, c.GrainYear).Len())
+}
+
+func TestAggregatorPartial(t *testing.T) {
+	ds := c.Dataset{
+		Name:        "waste",
+		Keys:        []string{"company_id"},
+		Date:        c.DateColumn{Column: "reported_at"},
+		Aggregation: c.AggregateLatest,
+		TieBreak:    c.TieError,
+	}
+	periods, err := newPeriods(c.FiscalYear{})
+	require.NoError(t, err)
+	agg := newAggregator(ds, periods, c.GrainYear)
+	date := time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC)
+	agg.add(observation{CompanyID: "1000", Date: date, Values: map[string]float64{"was_1": 1}})
+	agg.add(observation{CompanyID: "1000", Date: date, Values: map[string]float64{"was_1": 2}})
+	agg.add(observation{CompanyID: "1001", Date: date, Values: map[string]float64{"was_1": 5}})
+
+	_, err = agg.result()
+	assert.ErrorContains(t, err, `dataset "waste", company 1000, year 2023`)
+	table, tied := agg.partial()
+	assert.Equal(t, map[CompanyYearKey]map[string]float64{
+		{CompanyID: "1001", Year: 2023}: {"was_1": 5},
+	}, rowsOf(table))
+	assert.Contains(t, tied, CompanyYearKey{CompanyID: "1000", Year: 2023})
+}
+
+// BenchmarkAggregatorRetained reports the memory a dataset of 100k keys
+// keeps once aggregated, in bytes per key, against a map of values per
+// key. Memory grows linearly with the keys, so this is the cost of each.
+func BenchmarkAggregatorRetained(b *testing.B) {
+	const companies, years, fields = 25_000, 4, 8
+	ds := c.Dataset{Name: "emissions", Keys: []string{"company_id"}, Date: c.DateColumn{Column: "date", Format: c.DateFormatYear}}
+	periods, err := newPeriods(c.FiscalYear{})
+	require.NoError(b, err)
+	names := make([]string, fields)
+	for f := range names {
+		names[f] = fmt.Sprintf("emi_%d", f+1)
+	}
+
+	retained := func(build func() any) uint64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		kept := build()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(kept)
+		return after.HeapAlloc - before.HeapAlloc
+	}
+	aggregated := func() *Table {
+		agg := newAggregator(ds, periods, c.GrainYear)
+		values := make(map[string]float64, fields)
+		for i := range companies {
+			company := fmt.Sprintf("%06d", i)
+			for year := range years {
+				for f, name := range names {
+					values[name] = float64(i + year + f)
+				}
+				agg.add(observation{CompanyID: company, Date: time.Date(2020+year, 12, 31, 0, 0, 0, 0, time.UTC), Values: values})
+			}
+		}
+		table, err := agg.result()
+		require.NoError(b, err)
+		return table
+	}
+
+	var table, maps uint64
+	for i := 0; i < b.N; i++ {
+		table = retained(func() any { return aggregated() })
+		maps = retained(func() any { return rowsOf(aggregated()) })
+	}
+	keys := float64(companies * years)
+	b.ReportMetric(float64(table)/keys, "table-B/key")
+	b.ReportMetric(float64(maps)/keys, "map-B/key")
+}
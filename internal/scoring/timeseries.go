@@ -0,0 +1,211 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// Time-series operations read the same source across years of one company.
+// Earlier years are always evaluated first (see evaluateCompany),
+// so self.<metric> and <score>.<metric> sources work as well as dataset
+// fields. Their window parameters (n, window, years) are literal whole
+// numbers. Null semantics follow the positional rule, null if any input is
+// null, except rolling_avg, which skips nulls like evalAvg, and
+// carry_forward, which exists to replace a null.
+
+// windowParam reads a literal whole-number parameter, defaulting to def
+// when the optional parameter is absent.
+func windowParam(op c.Operation, i int, name string, def int) (int, error) {
+	if i >= len(op.Parameters) || (op.Parameters[i].Value == nil && op.Parameters[i].Source == "") {
+		if def > 0 {
+			return def, nil
+		}
+		return 0, fmt.Errorf("missing %s", name)
+	}
+	p := op.Parameters[i]
+	if p.Value == nil {
+		return 0, fmt.Errorf("%s must be a literal value", name)
+	}
+	n := *p.Value
+	if n < 1 || n != math.Trunc(n) {
+		return 0, fmt.Errorf("%s must be a positive whole number, got %g", name, n)
+	}
+	return int(n), nil
+}
+
+// evalLag is x as of n years earlier (n defaults to 1).
+func evalLag(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalLag] not enough parameters")
+	}
+	n, err := windowParam(op, 1, "n", 1)
+	if err != nil {
+		return 0, true, fmt.Errorf("[evalLag] %w", err)
+	}
+	val, isNull := resolveParamAt(logger, op.Parameters[0], key, key.Year-n, results, datasets)
+	return val, isNull, nil
+}
+
+// evalYoYChange is x minus x of the previous year.
+func evalYoYChange(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalYoYChange] not enough parameters")
+	}
+	current, prior, ok := currentAndPrior(logger, op.Parameters[0], key, 1, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	return current - prior, false, nil
+}
+
+// evalYoYPctChange is the change of x since the previous year as a
+// percentage of the previous year's value.
+func evalYoYPctChange(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalYoYPctChange] not enough parameters")
+	}
+	current, prior, ok := currentAndPrior(logger, op.Parameters[0], key, 1, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	if prior == 0 {
+		return 0, true, fmt.Errorf("[evalYoYPctChange] previous year value is zero")
+	}
+	return (current - prior) / math.Abs(prior) * 100, false, nil
+}
+
+// evalRollingAvg averages the non-null values of x over the window years
+// ending with the current one.
+func evalRollingAvg(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalRollingAvg] not enough parameters")
+	}
+	window, err := windowParam(op, 1, "window", 0)
+	if err != nil {
+		return 0, true, fmt.Errorf("[evalRollingAvg] %w", err)
+	}
+
+	var total float64
+	var count int
+	for year := key.Year - window + 1; year <= key.Year; year++ {
+		val, isNull := resolveParamAt(logger, op.Parameters[0], key, year, results, datasets)
+		if !isNull {
+			total += val
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, true, nil
+	}
+	return total / float64(count), false, nil
+}
+
+// evalCAGR is the compound annual growth rate of x over the last years
+// years, as a fraction: (x / x[years ago]) ^ (1 / years) - 1.
+func evalCAGR(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalCAGR] not enough parameters")
+	}
+	years, err := windowParam(op, 1, "years", 0)
+	if err != nil {
+		return 0, true, fmt.Errorf("[evalCAGR] %w", err)
+	}
+	end, start, ok := currentAndPrior(logger, op.Parameters[0], key, years, results, datasets)
+	if !ok {
+		return 0, true, nil
+	}
+	if start <= 0 || end < 0 {
+		return 0, true, fmt.Errorf("[evalCAGR] growth from %g to %g is undefined", start, end)
+	}
+	return math.Pow(end/start, 1/float64(years)) - 1, false, nil
+}
+
+// evalCarryForward is x, or when x is null the value of the most recent
+// earlier year of the company that has one.
+func evalCarryForward(
+	ctx context.Context,
+	logger *zap.Logger,
+	op c.Operation,
+	key CompanyYearKey,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, bool, error) {
+
+	if len(op.Parameters) < 1 {
+		return 0, true, fmt.Errorf("[evalCarryForward] not enough parameters")
+	}
+	earliest, ok := results.earliestYear()
+	if !ok {
+		earliest = key.Year
+	}
+	for year := key.Year; year >= earliest; year-- {
+		val, isNull := resolveParamAt(logger, op.Parameters[0], key, year, results, datasets)
+		if !isNull {
+			return val, false, nil
+		}
+	}
+	return 0, true, nil
+}
+
+// currentAndPrior resolves p for the current year and years earlier,
+// returning false if either is null.
+func currentAndPrior(
+	logger *zap.Logger,
+	p c.Parameter,
+	key CompanyYearKey,
+	years int,
+	results *Results,
+	datasets map[string]*Table,
+) (float64, float64, bool) {
+	current, currentNull := resolveParamAt(logger, p, key, key.Year, results, datasets)
+	prior, priorNull := resolveParamAt(logger, p, key, key.Year-years, results, datasets)
+	if currentNull || priorNull {
+		return 0, 0, false
+	}
+	return current, prior, true
+}
@@ -0,0 +1,172 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+// tsDatasets holds five years of company 1. waste.was_1 is missing in 2022
+// and 2023 has no row at all.
+func tsDatasets() map[string]*Table {
+	key := func(year int) CompanyYearKey { return CompanyYearKey{CompanyID: "1", Year: year} }
+	return tablesOf(map[string]map[CompanyYearKey]map[string]float64{
+		"waste": {
+			key(2020): {"was_1": 100},
+			key(2021): {"was_1": 110},
+			key(2022): {"was_2": 1},
+			key(2024): {"was_1": 121},
+		},
+		"emissions": {
+			key(2023): {"emi_1": 5},
+		},
+	})
+}
+
+func TestTimeSeriesOperations(t *testing.T) {
+	x := c.Parameter{Source: "waste.was_1"}
+
+	tests := []struct {
+		name     string
+		op       string
+		params   []c.Parameter
+		year     int
+		want     float64
+		wantNull bool
+		wantErr  bool
+	}{
+		{name: "lag default", op: "lag", params: []c.Parameter{x}, year: 2021, want: 100},
+		{name: "lag n", op: "lag", params: []c.Parameter{x, literal(4)}, year: 2024, want: 100},
+		{name: "lag before history", op: "lag", params: []c.Parameter{x}, year: 2020, wantNull: true},
+		{name: "lag source as n", op: "lag", params: []c.Parameter{x, {Source: "waste.was_2"}}, year: 2021, wantErr: true},
+
+		{name: "yoy_change", op: "yoy_change", params: []c.Parameter{x}, year: 2021, want: 10},
+		{name: "yoy_change missing prior", op: "yoy_change", params: []c.Parameter{x}, year: 2024, wantNull: true},
+		{name: "yoy_change prior default", op: "yoy_change", params: []c.Parameter{withDefault("was_1", 0)}, year: 2024, want: 121},
+
+		{name: "yoy_pct_change", op: "yoy_pct_change", params: []c.Parameter{x}, year: 2021, want: 10},
+		{name: "yoy_pct_change zero prior", op: "yoy_pct_change", params: []c.Parameter{withDefault("was_1", 0)}, year: 2024, wantErr: true},
+
+		{name: "rolling_avg", op: "rolling_avg", params: []c.Parameter{x, literal(2)}, year: 2021, want: 105},
+		{name: "rolling_avg skips null", op: "rolling_avg", params: []c.Parameter{x, literal(3)}, year: 2022, want: 105},
+		{name: "rolling_avg all null", op: "rolling_avg", params: []c.Parameter{x, literal(2)}, year: 2023, wantNull: true},
+		{name: "rolling_avg bad window", op: "rolling_avg", params: []c.Parameter{x, literal(1.5)}, year: 2023, wantErr: true},
+
+		{name: "cagr", op: "cagr", params: []c.Parameter{x, literal(4)}, year: 2024, want: 0.04880884817015163},
+		{name: "cagr missing start", op: "cagr", params: []c.Parameter{x, literal(2)}, year: 2024, wantNull: true},
+
+		{name: "carry_forward current", op: "carry_forward", params: []c.Parameter{x}, year: 2021, want: 110},
+		{name: "carry_forward missing field", op: "carry_forward", params: []c.Parameter{x}, year: 2022, want: 110},
+		{name: "carry_forward missing row", op: "carry_forward", params: []c.Parameter{x}, year: 2023, want: 110},
+		{name: "carry_forward nothing earlier", op: "carry_forward", params: []c.Parameter{{Source: "emissions.emi_9"}}, year: 2024, wantNull: true},
+	}
+
+	datasets := tsDatasets()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := CompanyYearKey{CompanyID: "1", Year: tt.year}
+			results := &Results{
+				score:   "score",
+				values:  map[string]map[string]float64{"score": {}},
+				history: map[int]*Results{},
+			}
+			for year := 2020; year < tt.year; year++ {
+				results.history[year] = &Results{score: "score", values: map[string]map[string]float64{"score": {}}}
+			}
+
+			op := c.Operation{Type: tt.op, Parameters: tt.params}
+			got, isNull, err := Operations[tt.op](context.Background(), zap.NewNop(), op, key, results, datasets)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNull, isNull)
+			if !tt.wantNull {
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesReadsEarlierMetrics(t *testing.T) {
+	scoreConfig, err := c.ParseConfig([]byte(`name: trend
+metrics:
+  - name: filled
+    operation:
+      type: carry_forward
+      parameters:
+        - source: waste.was_1
+  - name: change
+    operation:
+      type: yoy_change
+      parameters:
+        - source: self.filled
+`))
+	require.NoError(t, err)
+	require.Empty(t, ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil))
+
+	plan, err := BuildPlan(zap.NewNop(), scoreConfig, nil, testDatasets(t))
+	require.NoError(t, err)
+
+	rows := parallelComputeScores(context.Background(), zap.NewNop(),
+		getAllDataCompanyKeys(tsDatasets()), plan, tsDatasets(), 2)
+
+	got := make(map[int]map[string]float64)
+	for _, row := range rows {
+		got[row.Key.Year] = row.Metrics
+	}
+	assert.Equal(t, map[int]map[string]float64{
+		2020: {"filled": 100},
+		2021: {"filled": 110, "change": 10},
+		2022: {"filled": 110, "change": 0},
+		2023: {"filled": 110, "change": 0},
+		2024: {"filled": 121, "change": 11},
+	}, got)
+
+	// a single key evaluates the company's earlier years first
+	metrics := computeScoresForKey(context.Background(), zap.NewNop(),
+		CompanyYearKey{CompanyID: "1", Year: 2024}, plan, tsDatasets())
+	assert.Equal(t, map[string]float64{"filled": 121, "change": 11}, metrics)
+}
+
+func TestValidateConfigWindowParams(t *testing.T) {
+	yamlContent := `name: windows
+metrics:
+  - name: metric_1
+    operation:
+      type: rolling_avg
+      parameters:
+        - source: waste.was_1
+        - source: waste.was_2
+  - name: metric_2
+    operation:
+      type: lag
+      parameters:
+        - source: waste.was_1
+        - value: 0
+  - name: metric_3
+    operation:
+      type: cagr
+      parameters:
+        - source: waste.was_1
+          param: x
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`8:11: metric_1: param "window" of rolling_avg must be a literal value`,
+		`14:11: metric_2: param "n" of lag must be a positive whole number, got 0`,
+		`17:13: metric_3: cagr expects at least 2 parameters, got 1`,
+	}, got)
+}
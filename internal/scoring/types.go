@@ -1,6 +1,11 @@
 package scoring
 
-import "time"
+import (
+	"time"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/blob"
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
 
 const (
 	Dir        = "data"
@@ -18,65 +23,210 @@ type RowResult struct {
 	Err error
 }
 
+// CompanyYearKey identifies one period of a company: a fiscal year and, at
+// quarter or month grain, the quarter or month of that year counted from 1.
+// Period is 0 at year grain. Static datasets are keyed by the company alone.
 type CompanyYearKey struct {
 	CompanyID string
 	Year      int
+	Period    int
 }
 
-type rowData struct {
-	Date    time.Time
-	Numeric map[string]float64
-}
-
-// just to test the json file
-type RawJSONRow struct {
-	CompanyID string   `json:"company_id"`
-	DateStr   string   `json:"date"`
-	Dis1      *float64 `json:"dis_1,omitempty"`
-	Dis2      *float64 `json:"dis_2,omitempty"`
-	Dis3      *float64 `json:"dis_3,omitempty"`
-	Dis4      *float64 `json:"dis_4,omitempty"`
+// observation is one row of a dataset file: the company, the date and the
+// fields that have a value.
+type observation struct {
+	CompanyID string
+	Date      time.Time
+	Values    map[string]float64
 }
 
 type LoaderRegistry struct {
 	registry map[string]DataLoader
 }
 
-// GetLoader returns the DataLoader for a given file extension, if found.
-func (lr *LoaderRegistry) GetLoader(ext string) (DataLoader, bool) {
-	loader, ok := lr.registry[ext]
+// GetLoader returns the DataLoader for a given dataset format, if found.
+func (lr *LoaderRegistry) GetLoader(format string) (DataLoader, bool) {
+	loader, ok := lr.registry[format]
 	return loader, ok
 }
 
-// RegisterLoader lets you add or overwrite a DataLoader for a specific extension.
-func (lr *LoaderRegistry) RegisterLoader(ext string, loader DataLoader) {
-	lr.registry[ext] = loader
+// RegisterLoader lets you add or overwrite a DataLoader for a specific format.
+func (lr *LoaderRegistry) RegisterLoader(format string, loader DataLoader) {
+	lr.registry[format] = loader
 }
 
-// NewLoaderRegistry initializes a default registry with a CSV loader.
+// NewLoaderRegistry initializes a default registry with the CSV, JSON,
+// NDJSON, Parquet and SQL loaders.
 func NewLoaderRegistry() *LoaderRegistry {
 	return &LoaderRegistry{
 		registry: map[string]DataLoader{
-			".csv":  CSVLoader{},
-			".json": JSONLoader{},
-			// ".sql": RepoLoader{ DB: *pgpool },
+			c.FormatCSV:     CSVLoader{},
+			c.FormatJSON:    JSONLoader{},
+			c.FormatNDJSON:  NDJSONLoader{},
+			c.FormatParquet: ParquetLoader{},
+			c.FormatSQL:     SQLLoader{},
 		},
 	}
 }
 
-// DataLoaderService orchestrates reading multiple files from a directory
-// using the loaders from the LoaderRegistry.
-// Load all files in directory
+// DataLoaderService reads the declared datasets from a directory using the
+// loaders from the LoaderRegistry.
 type DataLoaderService struct {
 	registry *LoaderRegistry
+	datasets *c.Datasets
+
+	// fields are the fields read of each declared dataset, or every
+	// declared field when nil.
+	fields map[string]map[string]bool
+	// buckets serve the locations under their URL.
+	buckets map[string]blob.Bucket
+	// cache keeps unchanged datasets between loads; nil disables it.
+	cache *datasetCache
+	// streams holds the streamed datasets, which have no rows when nil.
+	streams *StreamStore
+}
+
+// NewDataLoaderService loads the datasets declared in datasets, reading
+// streamed datasets from streams. The loads of unchanged datasets are kept
+// between calls, so a service is meant to be shared by every request.
+func NewDataLoaderService(lr *LoaderRegistry, datasets *c.Datasets, streams *StreamStore) *DataLoaderService {
+	return &DataLoaderService{registry: lr, datasets: datasets, cache: newDatasetCache(), streams: streams}
+}
+
+// Datasets returns the catalog of the datasets the service loads.
+func (s *DataLoaderService) Datasets() *c.Datasets {
+	return s.datasets
+}
+
+// Project returns a service reading only the given fields of each dataset,
+// such as the DatasetFields of a plan. Loaders skip the columns of every
+// other declared field; datasets without a fields list are read whole.
+func (s *DataLoaderService) Project(fields map[string]map[string]bool) *DataLoaderService {
+	projected := *s
+	projected.fields = fields
+	return &projected
+}
+
+// project narrows the declared fields of ds to the projected ones. The
+// key and date columns are still read, so the keys do not change.
+func (s *DataLoaderService) project(ds c.Dataset) c.Dataset {
+	if s.fields == nil || ds.InfersFields() {
+		return ds
+	}
+	fields := make([]c.Field, 0, len(ds.Fields))
+	for _, f := range ds.Fields {
+		if s.fields[ds.Name][f.Name] {
+			fields = append(fields, f)
+		}
+	}
+	ds.Fields = fields
+	return ds
+}
+
+// Results holds the metric values computed so far for one key, for every
+// score of a Plan. self. references resolve against the score currently
+// being evaluated.
+type Results struct {
+	score  string
+	values map[string]map[string]float64
+	// datasets are the logical datasets, which win over scores of the same
+	// name.
+	datasets *c.Datasets
+
+	// history holds the results of the earlier years of the same company,
+	// so time-series operations can read metrics across years.
+	history map[int]*Results
+
+	// inputs collects the operation inputs of the metric being evaluated
+	// when explaining; nil otherwise.
+	inputs *[]InputTrace
+}
+
+func newResults(plan *Plan) *Results {
+	values := make(map[string]map[string]float64, len(plan.Scores))
+	for name := range plan.Scores {
+		values[name] = make(map[string]float64)
+	}
+	return &Results{score: plan.Target.Name, values: values, datasets: plan.datasets}
+}
+
+// scoreFor resolves a source prefix to the score it addresses, if any.
+func (r *Results) scoreFor(prefix string) (string, bool) {
+	if prefix == "self" {
+		return r.score, true
+	}
+	if r.datasets.Has(prefix) {
+		return "", false
+	}
+	_, ok := r.values[prefix]
+	return prefix, ok
+}
+
+func (r *Results) get(score, metric string) (float64, bool) {
+	val, ok := r.values[score][metric]
+	return val, ok
+}
+
+// at returns the results of another year of the same company, resolving
+// self. against the score currently being evaluated. Years not evaluated
+// yet have no metrics.
+func (r *Results) at(year int) *Results {
+	past, ok := r.history[year]
+	if !ok {
+		values := make(map[string]map[string]float64, len(r.values))
+		for name := range r.values {
+			values[name] = nil
+		}
+		return &Results{score: r.score, values: values, datasets: r.datasets}
+	}
+	return &Results{score: r.score, values: past.values, datasets: r.datasets, history: past.history}
+}
+
+// earliestYear is the earliest year in the history of the company.
+func (r *Results) earliestYear() (int, bool) {
+	earliest, ok := 0, false
+	for year := range r.history {
+		if !ok || year < earliest {
+			earliest, ok = year, true
+		}
+	}
+	return earliest, ok
+}
+
+// set stores a metric of the score currently being evaluated.
+func (r *Results) set(metric string, val float64) {
+	r.values[r.score][metric] = val
+}
+
+// record notes an operation input when the evaluation is being explained.
+func (r *Results) record(input InputTrace) {
+	if r.inputs != nil {
+		*r.inputs = append(*r.inputs, input)
+	}
 }
 
-func NewDataLoaderService(lr *LoaderRegistry) *DataLoaderService {
-	return &DataLoaderService{registry: lr}
+// InputTrace is one input read by an operation. Literal inputs come from a
+// parameter value; Defaulted inputs are the parameter default standing in
+// for a null source. Year is set when the input was read from another year
+// than the one being scored.
+type InputTrace struct {
+	Source    string  `json:"source,omitempty"`
+	Year      int     `json:"year,omitempty"`
+	Value     float64 `json:"value"`
+	Null      bool    `json:"null,omitempty"`
+	Literal   bool    `json:"literal,omitempty"`
+	Defaulted bool    `json:"defaulted,omitempty"`
 }
 
-var DatasetKeys = map[string]string{
-	"disclosure": "disclosure_data",
-	"waste":      "waste_data",
-	"emissions":  "emissions_data",
+// MetricTrace explains how one metric was computed for a key. Barrier
+// metrics are cross-sectional: their inputs are the key's own, the value
+// depends on its peers too.
+type MetricTrace struct {
+	Score     string       `json:"score"`
+	Metric    string       `json:"metric"`
+	Operation string       `json:"operation"`
+	Value     float64      `json:"value"`
+	Null      bool         `json:"null,omitempty"`
+	Barrier   bool         `json:"barrier,omitempty"`
+	Inputs    []InputTrace `json:"inputs"`
 }
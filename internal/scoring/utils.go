@@ -1,6 +1,7 @@
 package scoring
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -26,6 +27,65 @@ func parseDateOrYear(raw string) (time.Time, error) {
 	return time.Date(yearInt, time.January, 1, 0, 0, 0, 0, time.UTC), nil
 }
 
+// parseDate parses raw in a declared date format: bare years, quarters, a
+// Go time layout, or either a 2006-01-02 date or a year when no format is
+// declared.
+func parseDate(raw, format string) (time.Time, error) {
+	switch format {
+	case "":
+		return parseDateOrYear(raw)
+	case c.DateFormatYear:
+		yearInt, err := strconv.Atoi(raw)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid year %q: %v", raw, err)
+		}
+		return time.Date(yearInt, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	case c.DateFormatQuarter:
+		// a quarter is dated by its last day
+		yearStr, quarterStr, ok := strings.Cut(strings.Replace(raw, "-Q", "Q", 1), "Q")
+		yearInt, yearErr := strconv.Atoi(yearStr)
+		quarter, quarterErr := strconv.Atoi(quarterStr)
+		if !ok || yearErr != nil || quarterErr != nil || quarter < 1 || quarter > 4 {
+			return time.Time{}, fmt.Errorf("invalid quarter %q, expected 2006-Q1 or 2006Q1", raw)
+		}
+		return time.Date(yearInt, time.Month(3*quarter+1), 0, 0, 0, 0, 0, time.UTC), nil
+	default:
+		t, err := time.Parse(format, raw)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q, expected %s: %v", raw, format, err)
+		}
+		return t, nil
+	}
+}
+
+// parseField parses a raw value of a declared field type. Booleans are 1
+// and 0.
+func parseField(raw, fieldType string) (float64, error) {
+	switch fieldType {
+	case c.FieldInteger:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q", raw)
+		}
+		return float64(v), nil
+	case c.FieldBoolean:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid boolean %q", raw)
+		}
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", raw)
+		}
+		return v, nil
+	}
+}
+
 func validateData(companyID string, year int) error {
 	if companyID == "" {
 		return fmt.Errorf("missing company_id field")
@@ -36,6 +96,24 @@ func validateData(companyID string, year int) error {
 	return nil
 }
 
+// ErrUnknownConfig is returned when a request names a score config that is
+// not registered.
+var ErrUnknownConfig = errors.New("unknown score config")
+
+// resolveConfig looks up the requested score config, falling back to the
+// server default when the request does not name one.
+func resolveConfig(configs *c.Registry, requested, fallback string) (*c.Config, error) {
+	name := requested
+	if name == "" {
+		name = fallback
+	}
+	scoreConfig, ok := configs.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q, known configs: %v", ErrUnknownConfig, name, configs.Names())
+	}
+	return scoreConfig, nil
+}
+
 func BuildMetricMap(scoreConfig *c.Config) map[string]c.Metric {
 	m := make(map[string]c.Metric)
 	for _, met := range scoreConfig.Metrics {
@@ -0,0 +1,468 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/expr"
+)
+
+// ValidationError is a single problem found in a score config, positioned
+// at the YAML node that caused it.
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Metric  string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Metric != "" {
+		b.WriteString(e.Metric)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors is every problem found in one validation pass.
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// DatasetCatalog lists the datasets a config may reference and the fields
+// each one provides. A nil field set means the fields are not known and
+// only the dataset name is checked.
+type DatasetCatalog map[string]map[string]bool
+
+// CatalogFromDatasets derives a catalog from loaded data.
+func CatalogFromDatasets(datasets map[string]*Table) DatasetCatalog {
+	catalog := make(DatasetCatalog, len(datasets))
+	for name, ds := range datasets {
+		fields := make(map[string]bool, len(ds.Fields()))
+		for _, field := range ds.Fields() {
+			fields[field] = true
+		}
+		catalog[name] = fields
+	}
+	return catalog
+}
+
+// CatalogFromDefinitions derives a catalog from dataset declarations. The
+// fields of datasets without a fields list are not known.
+func CatalogFromDefinitions(datasets *c.Datasets) DatasetCatalog {
+	catalog := make(DatasetCatalog, len(datasets.Datasets))
+	for _, ds := range datasets.Datasets {
+		if ds.InfersFields() {
+			catalog[ds.Name] = nil
+			continue
+		}
+		fields := make(map[string]bool, len(ds.Fields))
+		for _, f := range ds.Fields {
+			fields[f.Name] = true
+		}
+		catalog[ds.Name] = fields
+	}
+	return catalog
+}
+
+// Has reports whether the catalog lists a dataset under name.
+func (dc DatasetCatalog) Has(name string) bool {
+	_, ok := dc[name]
+	return ok
+}
+
+// ValidateConfig statically checks a score config and reports every problem
+// in one pass: unknown operations, arity mismatches, duplicate metrics,
+// malformed or undefined sources, misused param names, malformed groups and
+// dependency cycles.
+// Dataset and field references are checked against catalog when it is not
+// nil; its datasets win over scores of the same name. <score>.<metric>
+// references, and cycles running through other scores, are checked against
+// the scores lookup resolves.
+func ValidateConfig(scoreConfig *c.Config, catalog DatasetCatalog, lookup c.Lookup) ValidationErrors {
+	var errs ValidationErrors
+	report := func(pos c.Position, metric, format string, args ...any) {
+		errs = append(errs, ValidationError{
+			File:    scoreConfig.File,
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Metric:  metric,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if scoreConfig.Name == "" {
+		report(c.Position{}, "", "score config has no name")
+	}
+	if len(scoreConfig.Metrics) == 0 {
+		report(c.Position{}, "", "score config has no metrics")
+	}
+
+	var walkGroups func(groups []c.Group)
+	walkGroups = func(groups []c.Group) {
+		for _, g := range groups {
+			if problem := g.Problem(); problem != "" {
+				report(g.Pos, g.Name, "%s", problem)
+			}
+			walkGroups(g.Children)
+		}
+	}
+	walkGroups(scoreConfig.Groups)
+
+	defined := make(map[string]c.Position, len(scoreConfig.Metrics))
+	for _, m := range scoreConfig.Metrics {
+		if m.Name == "" {
+			report(m.Pos, "", "metric has no name")
+			continue
+		}
+		if first, dup := defined[m.Name]; dup {
+			report(m.Pos, m.Name, "duplicate metric name, first defined at line %d", first.Line)
+			continue
+		}
+		defined[m.Name] = m.Pos
+	}
+
+	validatePresentation(scoreConfig, report)
+	validateMetadata(scoreConfig.Metadata, c.Position{}, "", report)
+	for _, m := range scoreConfig.Metrics {
+		validateMetadata(m.Metadata, m.Pos, m.Name, report)
+	}
+
+	for _, m := range scoreConfig.Metrics {
+		op := m.Operation
+		params := op.Parameters
+
+		sig, known := Signatures[op.Type]
+		switch {
+		case op.Type == "":
+			report(m.Pos, m.Name, "missing operation type")
+		case !known:
+			report(op.Pos, m.Name, "unknown operation %q, expected one of %s", op.Type, knownOperations())
+		default:
+			bound, bindErrs := bindParameters(op, sig)
+			for _, e := range bindErrs {
+				report(e.Pos, m.Name, "%s", e.Message)
+			}
+			for i, p := range bound {
+				if i >= len(sig.Params) || !sig.Params[i].Literal || (p.Source == "" && p.Value == nil) {
+					continue
+				}
+				name := sig.Params[i].Name
+				if p.Value == nil {
+					report(p.Pos, m.Name, "param %q of %s must be a literal value", name, op.Type)
+					continue
+				}
+				if check := sig.Params[i].Check; check != nil {
+					if err := check(*p.Value); err != nil {
+						report(p.Pos, m.Name, "param %q of %s %v", name, op.Type, err)
+					}
+				}
+			}
+		}
+
+		switch {
+		case op.Type == c.RollupOperation:
+			if !contains(missingPolicies, op.Missing) {
+				report(op.Pos, m.Name, "unknown missing policy %q, expected one of %s",
+					op.Missing, strings.Join(missingPolicies[1:], ", "))
+			}
+		case op.Missing != "" && known:
+			report(op.Pos, m.Name, "%s does not take a missing policy", op.Type)
+		}
+
+		for _, p := range params {
+			switch {
+			case op.Type == "weighted_sum" && p.Weight == nil:
+				report(p.Pos, m.Name, "weighted_sum parameter %q has no weight", p.Source)
+			case op.Type == c.RollupOperation && p.Weight != nil && *p.Weight < 0:
+				report(p.Pos, m.Name, "weight of %q must not be negative, got %g", p.Source, *p.Weight)
+			case op.Type != "weighted_sum" && op.Type != c.RollupOperation && p.Weight != nil && known:
+				report(p.Pos, m.Name, "%s does not take weights", op.Type)
+			}
+
+			if p.Value != nil {
+				if p.Source != "" {
+					report(p.Pos, m.Name, "parameter has both a source and a value")
+				}
+				if p.Default != nil {
+					report(p.Pos, m.Name, "default has no effect on a literal value")
+				}
+				continue
+			}
+
+			validateSource(p, m.Name, defined, catalog, lookup, report)
+		}
+
+		validateExpression(op, m.Name, defined, catalog, lookup, report)
+	}
+
+	scores := reachableScores(scoreConfig, lookup, catalog)
+	graph, _, nodes := buildDependencyGraph(zap.NewNop(), scores, catalog)
+	for _, cycle := range findCycles(nodes, graph) {
+		home := -1
+		for i, ref := range cycle {
+			if ref.Score == scoreConfig.Name {
+				home = i
+				break
+			}
+		}
+		if home < 0 {
+			continue // cycle between other scores, reported against them
+		}
+		metric := cycle[home].Metric
+		report(defined[metric], metric, "dependency cycle: %s", formatCycle(cycle, scoreConfig.Name))
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+func validateSource(
+	p c.Parameter,
+	metric string,
+	defined map[string]c.Position,
+	catalog DatasetCatalog,
+	lookup c.Lookup,
+	report func(pos c.Position, metric, format string, args ...any),
+) {
+	if p.Source == "" {
+		report(p.Pos, metric, "parameter has no source or value")
+		return
+	}
+
+	prefix, name, ok := splitSource(p.Source)
+	if !ok {
+		report(p.Pos, metric, "malformed source %q, expected <dataset>.<field>, self.<metric> or <score>.<metric>", p.Source)
+		return
+	}
+
+	if prefix == "self" {
+		if _, ok := defined[name]; !ok {
+			report(p.Pos, metric, "source %q references undefined metric %q", p.Source, name)
+		}
+		return
+	}
+
+	if !catalog.Has(prefix) && lookup != nil {
+		if other, ok := lookup(prefix); ok {
+			for _, m := range other.Metrics {
+				if m.Name == name {
+					return
+				}
+			}
+			report(p.Pos, metric, "source %q references undefined metric %q of score %q", p.Source, name, prefix)
+			return
+		}
+	}
+
+	if catalog == nil {
+		return
+	}
+	fields, ok := catalog[prefix]
+	if !ok {
+		report(p.Pos, metric, "source %q references unknown dataset or score %q", p.Source, prefix)
+		return
+	}
+	if fields != nil && !fields[name] {
+		report(p.Pos, metric, "source %q references unknown field %q of dataset %q", p.Source, name, prefix)
+	}
+}
+
+// validateExpression checks the expression of an expr operation, reporting
+// parse errors and bad references at their column in the YAML source.
+func validateExpression(
+	op c.Operation,
+	metric string,
+	defined map[string]c.Position,
+	catalog DatasetCatalog,
+	lookup c.Lookup,
+	report func(pos c.Position, metric, format string, args ...any),
+) {
+	if op.Type != "expr" {
+		if op.Expression != "" {
+			report(op.ExprPos, metric, "%s does not take an expression, use type: expr", op.Type)
+		}
+		return
+	}
+	if op.Expression == "" {
+		report(op.Pos, metric, "expr requires an expression")
+		return
+	}
+	if op.ExprErr != nil {
+		var exprErr *expr.Error
+		if errors.As(op.ExprErr, &exprErr) {
+			report(exprPosition(op, exprErr.Offset), metric, "invalid expression: %s", exprErr.Message)
+		} else {
+			report(op.ExprPos, metric, "invalid expression: %v", op.ExprErr)
+		}
+		return
+	}
+	for _, ref := range op.Expr.Refs() {
+		p := c.Parameter{Source: ref.Source, Pos: exprPosition(op, ref.Offset)}
+		validateSource(p, metric, defined, catalog, lookup, report)
+	}
+}
+
+// exprPosition maps an offset in the expression to its YAML position. Only
+// single line expressions map exactly; others point at the expression.
+func exprPosition(op c.Operation, offset int) c.Position {
+	if op.ExprPos.Line == 0 || strings.Contains(op.Expression, "\n") {
+		return op.ExprPos
+	}
+	return c.Position{Line: op.ExprPos.Line, Column: op.ExprPos.Column + offset}
+}
+
+// ConfigValidator adapts ValidateConfig for the config registry so broken
+// configs are rejected before they are served.
+func ConfigValidator(catalog DatasetCatalog) c.ValidateFunc {
+	return func(scoreConfig *c.Config, lookup c.Lookup) error {
+		if errs := ValidateConfig(scoreConfig, catalog, lookup); len(errs) > 0 {
+			return errs
+		}
+		return nil
+	}
+}
+
+// validatePresentation checks the output and arithmetic options of every
+// metric and that the score has at least one output column, each with its
+// own header.
+func validatePresentation(scoreConfig *c.Config, report func(pos c.Position, metric, format string, args ...any)) {
+	headers := make(map[string]string)
+	outputs := 0
+	for _, m := range scoreConfig.Metrics {
+		switch m.Visibility {
+		case "", c.VisibilityPublic:
+		case c.VisibilityInternal:
+			if m.Output != nil && *m.Output {
+				report(m.Pos, m.Name, "output: true conflicts with visibility %q", m.Visibility)
+			}
+		default:
+			report(m.Pos, m.Name, "unknown visibility %q, expected %s or %s",
+				m.Visibility, c.VisibilityPublic, c.VisibilityInternal)
+		}
+
+		if m.Precision != nil && (*m.Precision < 0 || *m.Precision > maxPrecision) {
+			report(m.Pos, m.Name, "precision must be between 0 and %d, got %d", maxPrecision, *m.Precision)
+		}
+		if !contains(roundingModes, m.Rounding) {
+			report(m.Pos, m.Name, "unknown rounding %q, expected one of %s",
+				m.Rounding, strings.Join(roundingModes[1:], ", "))
+		}
+
+		switch {
+		case m.Arithmetic != "" && !contains(arithmetics, m.Arithmetic):
+			report(m.Pos, m.Name, "unknown arithmetic %q, expected one of %s",
+				m.Arithmetic, strings.Join(arithmetics[1:], ", "))
+		case m.Arithmetic == c.ArithmeticDecimal && DecimalOperations[m.Operation.Type] == nil && m.Operation.Type != "":
+			report(m.Pos, m.Name, "%s does not support decimal arithmetic", m.Operation.Type)
+		}
+
+		if m.Name == "" || !m.IsOutput() {
+			continue
+		}
+		outputs++
+		header := m.Header(m.Name)
+		if other, dup := headers[header]; dup {
+			if other == m.Name {
+				continue // duplicate metric, reported already
+			}
+			report(m.Pos, m.Name, "column %q is also the header of %q", header, other)
+			continue
+		}
+		headers[header] = m.Name
+	}
+	if outputs == 0 && len(scoreConfig.Metrics) > 0 {
+		report(c.Position{}, "", "score config has no output metrics")
+	}
+	if !contains(arithmetics, scoreConfig.Arithmetic) {
+		report(c.Position{}, "", "unknown arithmetic %q, expected one of %s",
+			scoreConfig.Arithmetic, strings.Join(arithmetics[1:], ", "))
+	}
+}
+
+// maxPrecision is the most decimal places a float64 output can honour.
+const maxPrecision = 15
+
+// roundingModes and arithmetics are the accepted values of the rounding
+// and arithmetic options; the empty string means the default.
+var (
+	roundingModes = []string{"", c.RoundHalfEven, c.RoundHalfUp, c.RoundTruncate}
+	arithmetics   = []string{"", c.ArithmeticFloat, c.ArithmeticDecimal}
+)
+
+var versionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,2}$`)
+
+// validateMetadata checks the metadata fields that have a format: an
+// http(s) methodology URL, a numeric version and distinct non-empty tags.
+func validateMetadata(md c.Metadata, pos c.Position, metric string, report func(pos c.Position, metric, format string, args ...any)) {
+	if md.MethodologyURL != "" {
+		u, err := url.Parse(md.MethodologyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			report(pos, metric, "methodology_url %q must be an http or https URL", md.MethodologyURL)
+		}
+	}
+	if md.Version != "" && !versionPattern.MatchString(md.Version) {
+		report(pos, metric, "version %q must look like 2, 1.4 or 1.4.0", md.Version)
+	}
+	seen := make(map[string]bool, len(md.Tags))
+	for _, tag := range md.Tags {
+		switch {
+		case strings.TrimSpace(tag) == "":
+			report(pos, metric, "empty tag")
+		case seen[tag]:
+			report(pos, metric, "duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+}
+
+// missingPolicies are the accepted values of a rollup's missing policy; the
+// empty string means the default.
+var missingPolicies = []string{"", c.MissingRenormalise, c.MissingZero, c.MissingNull}
+
+func knownOperations() string {
+	names := make([]string, 0, len(Operations)+len(CrossSectional))
+	for name := range Operations {
+		names = append(names, name)
+	}
+	for name := range CrossSectional {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func contains(slice []string, target string) bool {
+	return indexOf(slice, target) != -1
+}
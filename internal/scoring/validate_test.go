@@ -0,0 +1,231 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	c "esgbook-software-engineer-technical-test-2024/pkg/config"
+)
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	yamlContent := `name: broken
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_9
+        - source: self.metric_3
+  - name: metric_1
+    operation:
+      type: power
+      parameters:
+        - source: unknown.field
+  - name: metric_2
+    operation:
+      type: divide
+      parameters:
+        - source: self.missing
+          param: z
+  - name: metric_3
+    operation:
+      type: or
+      parameters:
+        - source: self.metric_1
+          param: x
+        - source: emissions.emi_1
+          param: x
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+	scoreConfig.File = "broken.yaml"
+
+	catalog := DatasetCatalog{
+		"waste":     {"was_1": true},
+		"emissions": {"emi_1": true},
+	}
+
+	errs := ValidateConfig(scoreConfig, catalog, nil)
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`broken.yaml:3:11: metric_1: dependency cycle: metric_1 -> metric_3 -> metric_1`,
+		`broken.yaml:7:11: metric_1: source "waste.was_9" references unknown field "was_9" of dataset "waste"`,
+		`broken.yaml:9:11: metric_1: duplicate metric name, first defined at line 3`,
+		`broken.yaml:11:13: metric_1: unknown operation "power", expected one of ` + knownOperations(),
+		`broken.yaml:13:11: metric_1: source "unknown.field" references unknown dataset or score "unknown"`,
+		`broken.yaml:16:13: metric_2: divide expects at least 2 parameters, got 1`,
+		`broken.yaml:18:11: metric_2: unknown param "z" for divide, expected one of [x y]`,
+		`broken.yaml:18:11: metric_2: source "self.missing" references undefined metric "missing"`,
+		`broken.yaml:26:11: metric_3: param "x" bound more than once`,
+	}, got)
+}
+
+func TestValidateConfigAcceptsScore1(t *testing.T) {
+	scoreConfig, err := c.InitScoreConfig("score_1.yaml")
+	require.NoError(t, err)
+
+	errs := ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil)
+	assert.Empty(t, errs)
+}
+
+func TestFindCyclesNamesPath(t *testing.T) {
+	scoreConfig := &c.Config{Metrics: []c.Metric{
+		{Name: "a", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "self.b"}}}},
+		{Name: "b", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "self.c"}}}},
+		{Name: "c", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "self.a"}}}},
+		{Name: "d", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "self.d"}}}},
+	}}
+
+	graph, _, nodes := buildDependencyGraph(zap.NewNop(), []*c.Config{scoreConfig}, testDatasets(t))
+	var got []string
+	for _, cycle := range findCycles(nodes, graph) {
+		got = append(got, formatCycle(cycle, scoreConfig.Name))
+	}
+	assert.Equal(t, []string{"a -> b -> c -> a", "d -> d"}, got)
+}
+
+func TestValidateConfigCrossScore(t *testing.T) {
+	score2 := &c.Config{Name: "score_2", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_1.metric_1"}}}},
+	}}
+	score1 := &c.Config{Name: "score_1", Metrics: []c.Metric{
+		{Name: "metric_1", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_2.metric_1"}}}},
+		{Name: "metric_2", Operation: c.Operation{Type: "sum", Parameters: []c.Parameter{{Source: "score_2.metric_9"}}}},
+	}}
+	lookup := func(name string) (*c.Config, bool) {
+		cfg, ok := map[string]*c.Config{"score_1": score1, "score_2": score2}[name]
+		return cfg, ok
+	}
+
+	var got []string
+	for _, e := range ValidateConfig(score1, CatalogFromDefinitions(testDatasets(t)), lookup) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`metric_2: source "score_2.metric_9" references undefined metric "metric_9" of score "score_2"`,
+		`metric_1: dependency cycle: metric_1 -> score_2.metric_1 -> metric_1`,
+	}, got)
+}
+
+func TestValidateConfigExpressions(t *testing.T) {
+	yamlContent := `name: formulas
+metrics:
+  - name: metric_1
+    operation:
+      type: expr
+      expression: "waste.was_1 + (self.metric_2"
+  - name: metric_2
+    operation:
+      type: expr
+      expression: waste.was_9 * sqrt(2)
+  - name: metric_3
+    operation:
+      type: expr
+      expression: if(self.nope > 0, waste.was_9, 0)
+  - name: metric_4
+    operation:
+      type: sum
+      expression: waste.was_1
+      parameters:
+        - source: waste.was_1
+  - name: metric_5
+    operation:
+      type: expr
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+
+	catalog := DatasetCatalog{"waste": {"was_1": true}}
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, catalog, nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`6:48: metric_1: invalid expression: expected ) to close ( at column 15`,
+		`10:33: metric_2: invalid expression: unknown function "sqrt"`,
+		`14:22: metric_3: source "self.nope" references undefined metric "nope"`,
+		`14:37: metric_3: source "waste.was_9" references unknown field "was_9" of dataset "waste"`,
+		`18:19: metric_4: sum does not take an expression, use type: expr`,
+		`23:13: metric_5: expr requires an expression`,
+	}, got)
+}
+
+func TestValidateConfigWeights(t *testing.T) {
+	yamlContent := `name: weights
+metrics:
+  - name: metric_1
+    operation:
+      type: weighted_sum
+      parameters:
+        - source: waste.was_1
+          weight: 0.25
+        - source: waste.was_2
+  - name: metric_2
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+          weight: 2
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+	require.NotNil(t, scoreConfig.Metrics[0].Operation.Parameters[0].Weight)
+	assert.Equal(t, 0.25, *scoreConfig.Metrics[0].Operation.Parameters[0].Weight)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`9:11: metric_1: weighted_sum parameter "waste.was_2" has no weight`,
+		`14:11: metric_2: sum does not take weights`,
+	}, got)
+}
+
+func TestValidateConfigLiteralsAndDefaults(t *testing.T) {
+	yamlContent := `name: literals
+metrics:
+  - name: metric_1
+    operation:
+      type: divide
+      parameters:
+        - source: waste.was_1
+          default: 0
+        - value: 1000
+  - name: metric_2
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+          value: 1
+        - value: 2
+          default: 3
+        - param: x
+`
+	scoreConfig, err := c.ParseConfig([]byte(yamlContent))
+	require.NoError(t, err)
+
+	p := scoreConfig.Metrics[0].Operation.Parameters
+	require.NotNil(t, p[0].Default)
+	require.NotNil(t, p[1].Value)
+	assert.Equal(t, 1000.0, *p[1].Value)
+
+	var got []string
+	for _, e := range ValidateConfig(scoreConfig, CatalogFromDefinitions(testDatasets(t)), nil) {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`14:11: metric_2: parameter has both a source and a value`,
+		`16:11: metric_2: default has no effect on a literal value`,
+		`18:11: metric_2: sum does not take named parameters, got param "x"`,
+		`18:11: metric_2: parameter has no source or value`,
+	}, got)
+}
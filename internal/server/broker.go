@@ -5,22 +5,27 @@ import (
 	"go.uber.org/zap"
 
 	"esgbook-software-engineer-technical-test-2024/internal/scoring"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
 	pb "esgbook-software-engineer-technical-test-2024/protos/modules/scoring/generated"
 )
 
 // Broker manages the gRPC service lifecycle
 type Broker struct {
 	Logger         *zap.Logger
+	Configs        *config.Registry
 	ConfigFileName string
 	Registry       *prometheus.Registry
+	Data           *scoring.DataLoaderService
 }
 
 // NewBroker initializes a new Broker
-func NewBroker(logger *zap.Logger, configFileName string, registry *prometheus.Registry) *Broker {
+func NewBroker(logger *zap.Logger, configs *config.Registry, configFileName string, registry *prometheus.Registry, data *scoring.DataLoaderService) *Broker {
 	return &Broker{
 		Logger:         logger,
+		Configs:        configs,
 		ConfigFileName: configFileName,
 		Registry:       registry,
+		Data:           data,
 	}
 }
 
@@ -28,6 +33,8 @@ func NewBroker(logger *zap.Logger, configFileName string, registry *prometheus.R
 func (b *Broker) GetScoringService() pb.ScoringServiceServer {
 	return &scoring.GrpcScoringServer{
 		Logger:         b.Logger,
+		Configs:        b.Configs,
 		ConfigFileName: b.ConfigFileName,
+		Data:           b.Data,
 	}
 }
@@ -12,7 +12,9 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc/reflection"
 
+	"esgbook-software-engineer-technical-test-2024/internal/scoring"
 	"esgbook-software-engineer-technical-test-2024/middleware"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
 	pb "esgbook-software-engineer-technical-test-2024/protos/modules/scoring/generated"
 	"esgbook-software-engineer-technical-test-2024/protos/protocol/grpc"
 	"esgbook-software-engineer-technical-test-2024/protos/protocol/grpc/middleware/grpctracing"
@@ -21,7 +23,7 @@ import (
 // isReady is used for liveness probes in Kubernetes
 var isReady atomic.Value
 
-func RunGRPCServer(ctx context.Context, zapLogger *zap.Logger, port string, reg *prometheus.Registry) error {
+func RunGRPCServer(ctx context.Context, zapLogger *zap.Logger, port string, reg *prometheus.Registry, configs *config.Registry, data *scoring.DataLoaderService) error {
 	// Initialize OpenTelemetry trace provider
 	if err := middleware.InitExporters(ctx); err != nil {
 		return errors.Wrap(err, "failed to initialize exporters")
@@ -35,7 +37,7 @@ func RunGRPCServer(ctx context.Context, zapLogger *zap.Logger, port string, reg
 	defer func() { _ = tp.Shutdown(ctx) }()
 	otel.SetTracerProvider(tp)
 
-	b := NewBroker(zapLogger, file, reg)
+	b := NewBroker(zapLogger, configs, file, reg, data)
 	zapLogger.Info("Broker initialized")
 
 	zapLogger.Info("Attempting to start gRPC server on", zap.String("port", port))
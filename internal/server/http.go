@@ -9,11 +9,12 @@ import (
 
 	s "esgbook-software-engineer-technical-test-2024/internal/scoring"
 	"esgbook-software-engineer-technical-test-2024/middleware"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
 )
 
 const file = "score_1.yaml"
 
-func RunHTTPServer(ctx context.Context, zapLogger *zap.Logger, port string) error {
+func RunHTTPServer(ctx context.Context, zapLogger *zap.Logger, port string, configs *config.Registry, data *s.DataLoaderService) error {
 	router := gin.New()
 	router.Use(otelgin.Middleware("score-app"))
 	router.Use(gin.Recovery())
@@ -22,10 +23,15 @@ func RunHTTPServer(ctx context.Context, zapLogger *zap.Logger, port string) erro
 	h := s.Handler{
 		Ctx:            ctx,
 		Logger:         zapLogger,
+		Configs:        configs,
 		ConfigFileName: file,
+		Data:           data,
 	}
 
 	router.GET("/run-scores", h.CalculateScoreHandler)
+	router.GET("/explain", h.ExplainHandler)
+	router.GET("/scores/:name", h.DescribeScoreHandler)
+	router.GET("/scores/:name/schema", h.ScoreSchemaHandler)
 	router.GET("/health", s.HealthCheckHandler)
 
 	// 4. Start serving in a blocking manner.
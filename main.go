@@ -4,11 +4,17 @@ import (
 	"context"
 	"log"
 	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	"esgbook-software-engineer-technical-test-2024/internal/cli"
+	"esgbook-software-engineer-technical-test-2024/internal/scoring"
 	"esgbook-software-engineer-technical-test-2024/middleware"
+	"esgbook-software-engineer-technical-test-2024/pkg/config"
+	"esgbook-software-engineer-technical-test-2024/pkg/stream"
 
 	"os"
 
@@ -19,6 +25,12 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		code := cli.Validate(os.Args[2:], os.Stdout)
+		cancel()
+		os.Exit(code)
+	}
+
 	zapLogger, err := middleware.InitializeLogger()
 	if err != nil {
 		panic("failed to initialize logging")
@@ -36,6 +48,36 @@ func main() {
 		serverPort = "8000"
 	}
 
+	configDir := os.Getenv("SCORE_CONFIG_DIR")
+	if configDir == "" {
+		configDir = "/app/config"
+	}
+	datasets, err := config.LoadDatasets(configDir)
+	if err != nil {
+		zapLogger.Fatal("Failed to load dataset catalog", zap.Error(err))
+	}
+	store, err := scoring.NewStreamStore(datasets)
+	if err != nil {
+		zapLogger.Fatal("Failed to create stream store", zap.Error(err))
+	}
+	data := scoring.NewDataLoaderService(scoring.NewLoaderRegistry(), datasets, store)
+
+	configs, err := config.NewRegistry(zapLogger, configDir, scoring.ConfigValidator(scoring.CatalogFromDefinitions(datasets)))
+	if err != nil {
+		zapLogger.Fatal("Failed to load score configs", zap.Error(err))
+	}
+	go func() {
+		if err := configs.Watch(ctx); err != nil {
+			zapLogger.Error("Score config watcher stopped", zap.Error(err))
+		}
+	}()
+
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		if err := startIngestion(ctx, zapLogger, strings.Split(brokers, ","), store, data, configs); err != nil {
+			zapLogger.Fatal("Failed to start stream ingestion", zap.Error(err))
+		}
+	}
+
 	// Initialize the multi-exporter (Tempo and Jaeger) and set the global tracer provider.
 	if err := middleware.InitExporters(ctx); err != nil {
 		zapLogger.Sugar().Error("Failed to initialize exporters", "err", err)
@@ -45,14 +87,14 @@ func main() {
 	errChan := make(chan error, 2)
 
 	go func() {
-		if err := server.RunGRPCServer(ctx, zapLogger, grpcPort, reg); err != nil {
+		if err := server.RunGRPCServer(ctx, zapLogger, grpcPort, reg, configs, data); err != nil {
 			zapLogger.Error("gRPC server error", zap.Error(err))
 			log.Fatal(err)
 		}
 	}()
 
 	go func() {
-		if err := server.RunHTTPServer(ctx, zapLogger, serverPort); err != nil {
+		if err := server.RunHTTPServer(ctx, zapLogger, serverPort, configs, data); err != nil {
 			zapLogger.Sugar().Error("Failed to bootstrap server", "err", err)
 			log.Fatal(err)
 		}
@@ -72,3 +114,47 @@ func main() {
 		zapLogger.Sugar().Error("Server exited with error", "err", err)
 	}
 }
+
+// startIngestion consumes the topics of the streamed datasets into store,
+// which data reads them from, keeping the live scores of every config up to
+// date.
+func startIngestion(ctx context.Context, zapLogger *zap.Logger, brokers []string, store *scoring.StreamStore, data *scoring.DataLoaderService, configs *config.Registry) error {
+	topics := store.Topics()
+	if len(topics) == 0 {
+		zapLogger.Info("No streamed datasets to consume")
+		return nil
+	}
+
+	// change streams restored from a checkpoint resume from the offsets it
+	// holds, which the group never committed past
+	checkpointPath := os.Getenv("STREAM_CHECKPOINT")
+	if checkpointPath != "" {
+		if err := store.LoadCheckpoint(checkpointPath); err != nil {
+			return err
+		}
+	}
+	group := os.Getenv("KAFKA_GROUP")
+	if group == "" {
+		group = "scoring-service"
+	}
+	consumer := stream.NewKafka(brokers, group, topics...)
+	live := scoring.NewLiveScores(zapLogger, configs, data)
+	ingester := &scoring.Ingester{
+		Logger:   zapLogger,
+		Consumer: consumer,
+		Store:    store,
+		Rescore:  live.Rescore,
+	}
+	if checkpointPath != "" {
+		ingester.Checkpoint = func() error { return store.SaveCheckpoint(checkpointPath) }
+		ingester.CheckpointInterval = 5 * time.Second
+	}
+	go func() {
+		defer consumer.Close()
+		zapLogger.Info("Consuming streamed datasets", zap.Strings("topics", topics), zap.String("group", group))
+		if err := ingester.Run(ctx); err != nil {
+			zapLogger.Error("Stream ingestion stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
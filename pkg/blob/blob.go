@@ -0,0 +1,105 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Object is one object of a bucket. Version changes whenever the content
+// does: it is the ETag of S3 objects, the generation of GCS objects and the
+// size and modification time of local files.
+type Object struct {
+	Key     string
+	Version string
+	Size    int64
+}
+
+// Bucket lists and reads objects by slash-separated keys.
+type Bucket interface {
+	// List returns every object whose key starts with prefix, in key order.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Open streams the content of an object.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// Glob lists the objects whose key matches a path.Match pattern, in key
+// order. Only the keys under the part of the pattern before its first
+// wildcard are listed.
+func Glob(ctx context.Context, bucket Bucket, pattern string) ([]Object, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+	objects, err := bucket.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	matched := objects[:0]
+	for _, obj := range objects {
+		if ok, _ := path.Match(pattern, obj.Key); ok {
+			matched = append(matched, obj)
+		}
+	}
+	return matched, nil
+}
+
+// Open resolves a location to its bucket and the pattern of keys within
+// it. s3://bucket/pattern and gs://bucket/pattern use the default
+// credentials of the environment; any other location is a local path,
+// relative to dir unless absolute.
+func Open(ctx context.Context, dir, location string) (Bucket, string, error) {
+	u, err := url.Parse(location)
+	if err == nil && (u.Scheme == "s3" || u.Scheme == "gs") {
+		pattern := strings.TrimPrefix(u.Path, "/")
+		switch u.Scheme {
+		case "s3":
+			cfg, err := awsconfig.LoadDefaultConfig(ctx)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to load AWS config: %w", err)
+			}
+			return NewS3(s3.NewFromConfig(cfg), u.Host), pattern, nil
+		default:
+			client, err := storage.NewClient(ctx)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to create GCS client: %w", err)
+			}
+			return NewGCS(client, u.Host), pattern, nil
+		}
+	}
+
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(dir, location)
+	}
+	// the bucket is rooted at the deepest directory without wildcards
+	parts := strings.Split(filepath.ToSlash(location), "/")
+	i := 0
+	for i < len(parts)-1 && !strings.ContainsAny(parts[i], `*?[\`) {
+		i++
+	}
+	root := strings.Join(parts[:i], "/")
+	switch {
+	case root == "" && strings.HasPrefix(location, "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+	return Dir(filepath.FromSlash(root)), strings.Join(parts[i:], "/"), nil
+}
+
+// sortObjects orders objects by key.
+func sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+}
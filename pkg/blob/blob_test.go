@@ -0,0 +1,265 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func keys(objects []Object) []string {
+	var keys []string
+	for _, obj := range objects {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+func read(t *testing.T, bucket Bucket, key string) string {
+	rc, err := bucket.Open(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// testBucket checks a bucket holding waste_2023.csv, waste_2024.csv and
+// exports/waste.csv.
+func testBucket(t *testing.T, bucket Bucket) {
+	ctx := context.Background()
+
+	objects, err := bucket.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exports/waste.csv", "waste_2023.csv", "waste_2024.csv"}, keys(objects))
+
+	objects, err = Glob(ctx, bucket, "waste_*.csv")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"waste_2023.csv", "waste_2024.csv"}, keys(objects))
+	assert.Equal(t, int64(len("a,b\n1,2\n")), objects[0].Size)
+	assert.NotEmpty(t, objects[0].Version)
+
+	objects, err = Glob(ctx, bucket, "*/waste.csv")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exports/waste.csv"}, keys(objects))
+
+	_, err = Glob(ctx, bucket, "waste_[.csv")
+	assert.Error(t, err)
+
+	assert.Equal(t, "a,b\n1,2\n", read(t, bucket, "waste_2023.csv"))
+	_, err = bucket.Open(ctx, "missing.csv")
+	assert.Error(t, err)
+}
+
+func putAll(put func(key, data string)) {
+	put("waste_2023.csv", "a,b\n1,2\n")
+	put("waste_2024.csv", "a,b\n3,4\n")
+	put("exports/waste.csv", "a,b\n5,6\n")
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	put := func(key, data string) {
+		path := filepath.Join(dir, filepath.FromSlash(key))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	putAll(put)
+	testBucket(t, Dir(dir))
+
+	objects, err := Dir(dir).List(context.Background(), "exports/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exports/waste.csv"}, keys(objects))
+	objects, err = Dir(dir).List(context.Background(), "imports/")
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	// a rewrite changes the version
+	before, err := Dir(dir).List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	put("waste_2023.csv", "a,b\n1,2\n")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "waste_2023.csv"), later, later))
+	after, err := Dir(dir).List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	assert.NotEqual(t, before[0].Version, after[0].Version)
+}
+
+func TestMemory(t *testing.T) {
+	bucket := NewMemory()
+	putAll(func(key, data string) { bucket.Put(key, []byte(data)) })
+	testBucket(t, bucket)
+
+	before, err := bucket.List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	bucket.Put("waste_2023.csv", []byte("a,b\n1,2\n"))
+	after, err := bucket.List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	assert.NotEqual(t, before[0].Version, after[0].Version)
+
+	bucket.Delete("waste_2023.csv")
+	after, err = bucket.List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	assert.Empty(t, after)
+}
+
+func TestOpenLocal(t *testing.T) {
+	tests := []struct {
+		location    string
+		wantRoot    string
+		wantPattern string
+	}{
+		{location: "waste_*.csv", wantRoot: "data", wantPattern: "waste_*.csv"},
+		{location: "exports/2024/waste.csv", wantRoot: "data/exports/2024", wantPattern: "waste.csv"},
+		{location: "exports/*/waste.csv", wantRoot: "data/exports", wantPattern: "*/waste.csv"},
+		{location: "/lake/waste_*.csv", wantRoot: "/lake", wantPattern: "waste_*.csv"},
+		{location: "/waste.csv", wantRoot: "/", wantPattern: "waste.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			bucket, pattern, err := Open(context.Background(), "data", tt.location)
+			require.NoError(t, err)
+			assert.Equal(t, Dir(filepath.FromSlash(tt.wantRoot)), bucket)
+			assert.Equal(t, tt.wantPattern, pattern)
+		})
+	}
+}
+
+// fakeS3 serves the ListObjectsV2 and GetObject calls of path-style
+// requests from a memory bucket.
+func fakeS3(t *testing.T, bucket *Memory) *httptest.Server {
+	type contents struct {
+		Key  string
+		ETag string
+		Size int64
+	}
+	type listResult struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []contents
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if name != "lake" {
+			http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+			return
+		}
+		if key == "" {
+			objects, err := bucket.List(r.Context(), r.URL.Query().Get("prefix"))
+			require.NoError(t, err)
+			result := listResult{Name: name, Prefix: r.URL.Query().Get("prefix"), KeyCount: len(objects)}
+			for _, obj := range objects {
+				result.Contents = append(result.Contents, contents{Key: obj.Key, ETag: `"` + obj.Version + `"`, Size: obj.Size})
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			require.NoError(t, xml.NewEncoder(w).Encode(result))
+			return
+		}
+		rc, err := bucket.Open(r.Context(), key)
+		if err != nil {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		defer rc.Close()
+		_, _ = io.Copy(w, rc)
+	}))
+}
+
+func TestS3(t *testing.T) {
+	memory := NewMemory()
+	putAll(func(key, data string) { memory.Put(key, []byte(data)) })
+	srv := fakeS3(t, memory)
+	defer srv.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	bucket := NewS3(client, "lake")
+	testBucket(t, bucket)
+
+	objects, err := bucket.List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	memoryObjects, err := memory.List(context.Background(), "waste_2023")
+	require.NoError(t, err)
+	assert.Equal(t, memoryObjects[0].Version, objects[0].Version, "the ETag, unquoted")
+}
+
+// fakeGCS serves the object listing and JSON API media downloads of a
+// memory bucket.
+func fakeGCS(t *testing.T, bucket *Memory) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/storage/v1/b/lake/o")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if rest == "" {
+			objects, err := bucket.List(r.Context(), r.URL.Query().Get("prefix"))
+			require.NoError(t, err)
+			items := []map[string]string{}
+			for _, obj := range objects {
+				items = append(items, map[string]string{
+					"name":       obj.Key,
+					"bucket":     "lake",
+					"generation": obj.Version,
+					"size":       strconv.FormatInt(obj.Size, 10),
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"kind": "storage#objects", "items": items}))
+			return
+		}
+		rc, err := bucket.Open(r.Context(), strings.TrimPrefix(rest, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer rc.Close()
+		_, _ = io.Copy(w, rc)
+	}))
+}
+
+func TestGCS(t *testing.T) {
+	memory := NewMemory()
+	putAll(func(key, data string) { memory.Put(key, []byte(data)) })
+	srv := fakeGCS(t, memory)
+	defer srv.Close()
+
+	client, err := storage.NewClient(context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+		storage.WithJSONReads(),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+	bucket := NewGCS(client, "lake")
+	testBucket(t, bucket)
+
+	objects, err := bucket.List(context.Background(), "waste_2024")
+	require.NoError(t, err)
+	memoryObjects, err := memory.List(context.Background(), "waste_2024")
+	require.NoError(t, err)
+	assert.Equal(t, memoryObjects[0].Version, objects[0].Version, "the generation")
+}
@@ -0,0 +1,70 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// Dir is a bucket of the files under a local directory, keyed by their
+// slash-separated path relative to it.
+type Dir string
+
+// List walks the directory holding prefix, so a prefix in a subdirectory
+// does not walk the whole tree.
+func (d Dir) List(ctx context.Context, prefix string) ([]Object, error) {
+	start := path.Dir(prefix)
+	if prefix == "" || prefix[len(prefix)-1] == '/' {
+		start = path.Clean(prefix)
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(filepath.Join(string(d), filepath.FromSlash(start)), func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(string(d), p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if len(key) < len(prefix) || key[:len(prefix)] != prefix {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:     key,
+			Version: strconv.FormatInt(info.Size(), 10) + "-" + strconv.FormatInt(info.ModTime().UnixNano(), 10),
+			Size:    info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+// Open opens the file of key. The returned *os.File also supports random
+// access.
+func (d Dir) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(key)))
+}
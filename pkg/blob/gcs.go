@@ -0,0 +1,46 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCS is a bucket of Google Cloud Storage.
+type GCS struct {
+	bucket *storage.BucketHandle
+}
+
+// NewGCS reads the bucket named bucket through client.
+func NewGCS(client *storage.Client, bucket string) *GCS {
+	return &GCS{bucket: client.Bucket(bucket)}
+}
+
+func (b *GCS) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, Object{
+			Key:     attrs.Name,
+			Version: strconv.FormatInt(attrs.Generation, 10),
+			Size:    attrs.Size,
+		})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (b *GCS) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.bucket.Object(key).NewReader(ctx)
+}
@@ -0,0 +1,72 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-process bucket, for tests. Every Put of a key bumps its
+// generation, like GCS.
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	next    int64
+}
+
+type memoryObject struct {
+	data       []byte
+	generation int64
+}
+
+// NewMemory returns an empty bucket.
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]memoryObject)}
+}
+
+// Put stores data under key, replacing any earlier content.
+func (m *Memory) Put(key string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	m.objects[key] = memoryObject{data: bytes.Clone(data), generation: m.next}
+}
+
+// Delete removes key.
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+}
+
+func (m *Memory) List(_ context.Context, prefix string) ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var objects []Object
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{
+				Key:     key,
+				Version: strconv.FormatInt(obj.generation, 10),
+				Size:    int64(len(obj.data)),
+			})
+		}
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (m *Memory) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("object %q: %w", key, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3 is a bucket of Amazon S3, or of any service speaking its API.
+type S3 struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3 reads the bucket named bucket through client.
+func NewS3(client *s3.Client, bucket string) *S3 {
+	return &S3{client: client, bucket: bucket}
+}
+
+func (b *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	pages := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, Object{
+				Key:     aws.ToString(obj.Key),
+				Version: strings.Trim(aws.ToString(obj.ETag), `"`),
+				Size:    aws.ToInt64(obj.Size),
+			})
+		}
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (b *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
@@ -4,56 +4,492 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"esgbook-software-engineer-technical-test-2024/pkg/expr"
 )
 
 //go:embed score_1.yaml
 var configFS embed.FS
 
 type Config struct {
-	Name    string
-	Metrics []Metric `mapstructure:"metrics"`
+	Name     string
+	Metadata `mapstructure:",squash"`
+	Metrics  []Metric `mapstructure:"metrics"`
+	// Arithmetic is the default arithmetic of the metrics, ArithmeticFloat
+	// when empty.
+	Arithmetic string `mapstructure:"arithmetic"`
+	// Groups are weighted roll-up trees over the metrics. ParseConfig adds
+	// a rollup metric to Metrics for every group, so each node of the tree
+	// is scored and output like any other metric.
+	Groups []Group `mapstructure:"groups"`
+
+	// File is the file the config was read from, for error reporting.
+	File string `mapstructure:"-"`
 }
 
 type Metric struct {
-	Name      string    `mapstructure:"name"`
-	Operation Operation `mapstructure:"operation"`
+	Name         string    `mapstructure:"name"`
+	Operation    Operation `mapstructure:"operation"`
+	Presentation `mapstructure:",squash"`
+	Metadata     `mapstructure:",squash"`
+	// Arithmetic overrides the score's arithmetic for this metric.
+	Arithmetic string `mapstructure:"arithmetic"`
+
+	Pos Position `mapstructure:"-"`
 }
 
 type Operation struct {
 	Type       string      `mapstructure:"type"`
 	Parameters []Parameter `mapstructure:"parameters"`
+	Expression string      `mapstructure:"expression"`
+	// Missing is the missing-child policy of a rollup.
+	Missing string `mapstructure:"missing"`
+
+	// Expr is Expression compiled at load time, or nil when it is empty or
+	// failed to parse, in which case ExprErr holds the parse error.
+	Expr    *expr.Expr `mapstructure:"-"`
+	ExprErr error      `mapstructure:"-"`
+
+	Pos Position `mapstructure:"-"`
+	// ExprPos is the position of the first character of Expression.
+	ExprPos Position `mapstructure:"-"`
+}
+
+// Metadata documents a score or metric for the consumers of its results.
+// Every field is optional.
+type Metadata struct {
+	Description string   `mapstructure:"description" json:"description,omitempty"`
+	Unit        string   `mapstructure:"unit" json:"unit,omitempty"`
+	Owner       string   `mapstructure:"owner" json:"owner,omitempty"`
+	Tags        []string `mapstructure:"tags" json:"tags,omitempty"`
+	// MethodologyURL links to the written methodology, an http(s) URL.
+	MethodologyURL string `mapstructure:"methodology_url" json:"methodology_url,omitempty"`
+	// Version of the methodology, such as 2 or 1.4.0.
+	Version string `mapstructure:"version" json:"version,omitempty"`
+}
+
+// IsZero reports whether no metadata is set.
+func (m Metadata) IsZero() bool {
+	return m.Description == "" && m.Unit == "" && m.Owner == "" &&
+		len(m.Tags) == 0 && m.MethodologyURL == "" && m.Version == ""
+}
+
+// Presentation controls whether and how a metric appears in score output.
+// Internal metrics are still computed when an output metric needs them.
+type Presentation struct {
+	// Output false hides the metric. nil means the metric is output.
+	Output *bool `mapstructure:"output,omitempty"`
+	// Visibility is VisibilityPublic (the default) or VisibilityInternal.
+	Visibility string `mapstructure:"visibility"`
+	// DisplayName is the column header, the metric name when empty.
+	DisplayName string `mapstructure:"display_name"`
+	// Order places the column: lower first, then columns without an order
+	// in the order the metrics are defined.
+	Order *int `mapstructure:"order,omitempty"`
+	// Precision is the number of decimal places the metric is output
+	// with, DefaultPrecision when nil.
+	Precision *int `mapstructure:"precision,omitempty"`
+	// Rounding is how the metric is rounded to Precision, RoundHalfEven
+	// when empty.
+	Rounding string `mapstructure:"rounding"`
+}
+
+// DefaultPrecision is the precision of metrics that do not set one.
+const DefaultPrecision = 2
+
+// Rounding modes.
+const (
+	// RoundHalfEven rounds ties to the even neighbour.
+	RoundHalfEven = "half-even"
+	// RoundHalfUp rounds ties away from zero.
+	RoundHalfUp = "half-up"
+	// RoundTruncate drops the extra digits, rounding towards zero.
+	RoundTruncate = "truncate"
+)
+
+// Arithmetic modes.
+const (
+	ArithmeticFloat = "float"
+	// ArithmeticDecimal computes the arithmetic operations exactly on the
+	// decimal values of their inputs.
+	ArithmeticDecimal = "decimal"
+)
+
+// OutputPrecision is the number of decimal places the metric is output
+// with.
+func (p Presentation) OutputPrecision() int {
+	if p.Precision != nil {
+		return *p.Precision
+	}
+	return DefaultPrecision
+}
+
+// OutputRounding is the rounding mode the metric is output with.
+func (p Presentation) OutputRounding() string {
+	if p.Rounding != "" {
+		return p.Rounding
+	}
+	return RoundHalfEven
+}
+
+// Metric visibilities.
+const (
+	VisibilityPublic   = "public"
+	VisibilityInternal = "internal"
+)
+
+// IsOutput reports whether the metric is a column of the score output.
+func (p Presentation) IsOutput() bool {
+	if p.Output != nil && !*p.Output {
+		return false
+	}
+	return p.Visibility != VisibilityInternal
+}
+
+// Header is the column header of the metric.
+func (p Presentation) Header(name string) string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return name
+}
+
+// Group is a node of a roll-up tree: either a group of weighted children
+// or a leaf naming a metric.
+//
+//	groups:
+//	  - name: overall
+//	    children:
+//	      - name: environmental
+//	        weight: 0.6
+//	        missing: zero
+//	        children:
+//	          - metric: metric_1
+//	            weight: 2
+//	          - metric: metric_2
+//	      - name: governance
+//	        weight: 0.4
+//	        children:
+//	          - metric: metric_3
+type Group struct {
+	Name string `mapstructure:"name"`
+	// Metric is the metric a leaf refers to.
+	Metric string `mapstructure:"metric"`
+	// Weight of the node within its parent. nil means 1.
+	Weight *float64 `mapstructure:"weight,omitempty"`
+	// Missing is how the group treats null children: MissingRenormalise
+	// (the default), MissingZero or MissingNull.
+	Missing      string  `mapstructure:"missing"`
+	Children     []Group `mapstructure:"children"`
+	Presentation `mapstructure:",squash"`
+	Metadata     `mapstructure:",squash"`
+
+	Pos Position `mapstructure:"-"`
+}
+
+// Missing-child policies of a group or rollup operation.
+const (
+	// MissingRenormalise spreads the weight of null children over the rest.
+	MissingRenormalise = "renormalise"
+	// MissingZero counts null children as zero.
+	MissingZero = "zero"
+	// MissingNull makes the parent null if any child is null.
+	MissingNull = "null"
+)
+
+// RollupOperation is the operation type of the metrics added for groups.
+const RollupOperation = "rollup"
+
+// IsLeaf reports whether g refers to a metric rather than grouping others.
+func (g Group) IsLeaf() bool {
+	return g.Metric != ""
+}
+
+// Problem describes what is structurally wrong with the node, or returns
+// the empty string when nothing is.
+func (g Group) Problem() string {
+	switch {
+	case g.IsLeaf() && len(g.Children) > 0:
+		return fmt.Sprintf("group %q has both a metric and children", g.Metric)
+	case g.IsLeaf() && g.Missing != "":
+		return fmt.Sprintf("leaf %q cannot have a missing policy", g.Metric)
+	case g.IsLeaf() && (g.Presentation != (Presentation{}) || !g.Metadata.IsZero()):
+		return fmt.Sprintf("leaf %q cannot set output options or metadata, set them on the metric", g.Metric)
+	case g.IsLeaf():
+		return ""
+	case g.Name == "":
+		return "group has no name or metric"
+	case len(g.Children) == 0:
+		return fmt.Sprintf("group %q has no children", g.Name)
+	}
+	return ""
 }
 
 type Parameter struct {
 	Source string `mapstructure:"source"`
 	Param  string `mapstructure:"param,omitempty"`
+	// Value is a literal used instead of Source. nil when not set.
+	Value *float64 `mapstructure:"value,omitempty"`
+	// Default replaces Source when it resolves to null. nil when not set.
+	Default *float64 `mapstructure:"default,omitempty"`
+	// Weight scales the parameter in weighted_sum. nil when not set.
+	Weight *float64 `mapstructure:"weight,omitempty"`
+
+	Pos Position `mapstructure:"-"`
 }
 
-func InitScoreConfig(fileName string) (*Config, error) {
-	//viper.SetConfigName("score_1")
-	//viper.SetConfigType("yaml")
-	//viper.AddConfigPath(".")
-	//viper.AddConfigPath("/app/config")
-	//
-	//if err := viper.ReadInConfig(); err != nil {
-	//	return nil, fmt.Errorf("error reading config file: %v", err)
-	//}
+// Position is the line and column of a node in the YAML source. The zero
+// value means the position is unknown.
+type Position struct {
+	Line   int
+	Column int
+}
 
+func InitScoreConfig(fileName string) (*Config, error) {
 	fileData, err := configFS.ReadFile(fileName)
 	if err != nil {
 		return nil, fmt.Errorf("error reading embedded config file: %v", err)
 	}
-	viper.SetConfigType("yaml")
-	if err := viper.ReadConfig(bytes.NewReader(fileData)); err != nil {
+	config, err := ParseConfig(fileData)
+	if err != nil {
+		return nil, err
+	}
+	config.File = fileName
+	return config, nil
+}
+
+// ParseConfig decodes a single score config from its YAML bytes. Every call
+// uses its own viper instance so configs can be parsed concurrently.
+func ParseConfig(fileData []byte) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(fileData)); err != nil {
 		return nil, fmt.Errorf("error loading config: %v", err)
 	}
 	config := &Config{}
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshalling config: %v", err)
 	}
 
-	//fmt.Printf("Parsed Config: %+v\n", config)
+	var doc yaml.Node
+	if err := yaml.Unmarshal(fileData, &doc); err == nil {
+		annotatePositions(config, &doc)
+	}
+	compileExpressions(config)
+	expandGroups(config)
+	return config, nil
+}
+
+// ParseConfigFile decodes the score config of a file, named after the file
+// when it sets no name.
+func ParseConfigFile(fileName string, fileData []byte) (*Config, error) {
+	config, err := ParseConfig(fileData)
+	if err != nil {
+		return nil, err
+	}
+	config.File = fileName
+	if config.Name == "" {
+		base := filepath.Base(fileName)
+		config.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
 	return config, nil
 }
+
+// compileExpressions parses every operation expression once so evaluation
+// never re-parses and parse errors surface when the config loads.
+func compileExpressions(config *Config) {
+	for i := range config.Metrics {
+		op := &config.Metrics[i].Operation
+		if op.Expression == "" {
+			continue
+		}
+		op.Expr, op.ExprErr = expr.Parse(op.Expression)
+	}
+}
+
+// expandGroups adds a rollup metric for every group, children before their
+// parents. A group's parameters are its children as self sources, so a
+// leaf reads its metric and a nested group reads the child group's metric.
+// Malformed nodes are skipped; Validate reports them.
+func expandGroups(config *Config) {
+	var expand func(g Group)
+	expand = func(g Group) {
+		if g.Problem() != "" || g.IsLeaf() {
+			return
+		}
+		op := Operation{Type: RollupOperation, Missing: g.Missing, Pos: g.Pos}
+		for _, child := range g.Children {
+			if child.Problem() != "" {
+				continue
+			}
+			expand(child)
+			name := child.Name
+			if child.IsLeaf() {
+				name = child.Metric
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Source: "self." + name,
+				Weight: child.Weight,
+				Pos:    child.Pos,
+			})
+		}
+		config.Metrics = append(config.Metrics, Metric{
+			Name:         g.Name,
+			Operation:    op,
+			Presentation: g.Presentation,
+			Metadata:     g.Metadata,
+			Pos:          g.Pos,
+		})
+	}
+	for _, g := range config.Groups {
+		expand(g)
+	}
+}
+
+// annotatePositions copies the YAML line and column of every metric,
+// operation and parameter onto the decoded config.
+func annotatePositions(config *Config, doc *yaml.Node) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	annotateGroups(config.Groups, mappingValue(doc.Content[0], "groups"))
+
+	metrics := mappingValue(doc.Content[0], "metrics")
+	if metrics == nil || metrics.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, mNode := range metrics.Content {
+		if i >= len(config.Metrics) {
+			break
+		}
+		m := &config.Metrics[i]
+		m.Pos = nodePosition(mNode)
+		if name := mappingValue(mNode, "name"); name != nil {
+			m.Pos = nodePosition(name)
+		}
+
+		opNode := mappingValue(mNode, "operation")
+		if opNode == nil {
+			continue
+		}
+		m.Operation.Pos = nodePosition(opNode)
+		if typ := mappingValue(opNode, "type"); typ != nil {
+			m.Operation.Pos = nodePosition(typ)
+		}
+		if exprNode := mappingValue(opNode, "expression"); exprNode != nil {
+			m.Operation.ExprPos = nodePosition(exprNode)
+			if exprNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+				m.Operation.ExprPos.Column++ // skip the opening quote
+			}
+		}
+
+		params := mappingValue(opNode, "parameters")
+		if params == nil || params.Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, pNode := range params.Content {
+			if j >= len(m.Operation.Parameters) {
+				break
+			}
+			m.Operation.Parameters[j].Pos = nodePosition(pNode)
+		}
+	}
+}
+
+// annotateGroups positions every group at its name, or its metric for a
+// leaf.
+func annotateGroups(groups []Group, node *yaml.Node) {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, gNode := range node.Content {
+		if i >= len(groups) {
+			break
+		}
+		g := &groups[i]
+		g.Pos = nodePosition(gNode)
+		if name := mappingValue(gNode, "name"); name != nil {
+			g.Pos = nodePosition(name)
+		} else if metric := mappingValue(gNode, "metric"); metric != nil {
+			g.Pos = nodePosition(metric)
+		}
+		annotateGroups(g.Children, mappingValue(gNode, "children"))
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func nodePosition(node *yaml.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+// IsScoreConfig reports whether data holds a score config rather than one of
+// the other YAML files (prometheus, loki, ...) kept alongside them.
+func IsScoreConfig(data []byte) (bool, error) {
+	var probe map[string]any
+	if err := yaml.Unmarshal(data, &probe); err != nil {
+		return false, fmt.Errorf("invalid yaml: %w", err)
+	}
+	_, ok := probe["metrics"]
+	return ok, nil
+}
+
+// Validate runs the structural checks every score config must pass before
+// it can be served: a name, at least one metric, unique metric names and an
+// operation type on every metric, expressions that parse and well-formed
+// groups.
+func Validate(config *Config) error {
+	if config.Name == "" {
+		return fmt.Errorf("score config has no name")
+	}
+	if len(config.Metrics) == 0 {
+		return fmt.Errorf("score config %q has no metrics", config.Name)
+	}
+	var groupErr error
+	var walk func(groups []Group)
+	walk = func(groups []Group) {
+		for _, g := range groups {
+			if problem := g.Problem(); problem != "" && groupErr == nil {
+				groupErr = fmt.Errorf("%s in %q", problem, config.Name)
+			}
+			walk(g.Children)
+		}
+	}
+	walk(config.Groups)
+	if groupErr != nil {
+		return groupErr
+	}
+
+	seen := make(map[string]bool, len(config.Metrics))
+	for i, m := range config.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("metric #%d in %q has no name", i+1, config.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("duplicate metric %q in %q", m.Name, config.Name)
+		}
+		seen[m.Name] = true
+		if m.Operation.Type == "" {
+			return fmt.Errorf("metric %q in %q has no operation type", m.Name, config.Name)
+		}
+		if m.Operation.ExprErr != nil {
+			return fmt.Errorf("metric %q in %q has an invalid expression: %v", m.Name, config.Name, m.Operation.ExprErr)
+		}
+	}
+	return nil
+}
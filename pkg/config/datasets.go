@@ -0,0 +1,608 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+//go:embed datasets.yaml
+var datasetsFS embed.FS
+
+// DatasetsFile is the name of the dataset catalog kept alongside the score
+// configs.
+const DatasetsFile = "datasets.yaml"
+
+// Dataset formats. JSON files hold an array of objects, NDJSON files one
+// object per line. Parquet columns are addressed by their dotted path. SQL
+// datasets are read from a database rather than files, and stream datasets
+// from the JSON object events of a topic.
+const (
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+	FormatSQL     = "sql"
+	FormatStream  = "stream"
+)
+
+// Compressions of dataset files. A file is decompressed as it is read, by
+// the compression of the dataset or else the one its name ends in, so
+// waste.csv.gz is a gzip compressed CSV file. None turns detection off.
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionBzip2  = "bzip2"
+	CompressionSnappy = "snappy"
+)
+
+// compressionExts maps the extensions of compressed files to their
+// compression.
+var compressionExts = map[string]string{
+	".gz":     CompressionGzip,
+	".gzip":   CompressionGzip,
+	".zst":    CompressionZstd,
+	".zstd":   CompressionZstd,
+	".bz2":    CompressionBzip2,
+	".snappy": CompressionSnappy,
+}
+
+// Envelopes of stream events other than plain rows.
+const EnvelopeDebezium = "debezium"
+
+// Database drivers of SQL datasets.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Field types. Every value is held as a float64; the type decides how the
+// raw value is parsed.
+const (
+	FieldNumber  = "number"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+)
+
+// Date formats of columns holding bare years and quarters such as 2023-Q1
+// or 2023Q1. Any other format is a Go time layout.
+const (
+	DateFormatYear    = "year"
+	DateFormatQuarter = "quarter"
+)
+
+// Grains, from the coarsest. Keys are evaluated at the grain of the
+// catalog; a dataset reported at a finer grain is rolled up to it with its
+// aggregation policies and one reported at a coarser grain applies to every
+// key it covers. Static datasets hold company attributes, such as the
+// sector, that apply to every period.
+const (
+	GrainStatic  = "static"
+	GrainYear    = "year"
+	GrainQuarter = "quarter"
+	GrainMonth   = "month"
+)
+
+// Aggregation policies, reducing the values a field has over the rows of
+// one period. Latest and earliest take the latest or earliest row of the
+// period whole, so a field that row has no value of has none. The other
+// policies ignore rows without a value for the field: latest_value and
+// earliest_value take the latest or earliest value of the field itself.
+const (
+	AggregateLatest        = "latest"
+	AggregateEarliest      = "earliest"
+	AggregateLatestValue   = "latest_value"
+	AggregateEarliestValue = "earliest_value"
+	AggregateSum           = "sum"
+	AggregateMean          = "mean"
+	AggregateMax           = "max"
+	AggregateCount         = "count"
+)
+
+// Tie-break policies, deciding between different values of rows with the
+// same date under the latest, earliest, latest_value and earliest_value
+// policies. Rows are ordered by file, in lexical order, then by position in
+// the file.
+const (
+	TieLast  = "last"
+	TieFirst = "first"
+	TieMax   = "max"
+	TieMin   = "min"
+	TieError = "error"
+)
+
+// DefaultYearEnd is the fiscal year end of companies without one: fiscal
+// years are calendar years.
+const DefaultYearEnd = "12-31"
+
+var (
+	formats      = []string{FormatCSV, FormatJSON, FormatNDJSON, FormatParquet, FormatSQL, FormatStream}
+	drivers      = []string{DriverPostgres, DriverSQLite}
+	fieldTypes   = []string{FieldNumber, FieldInteger, FieldBoolean}
+	aggregations = []string{AggregateLatest, AggregateEarliest, AggregateLatestValue, AggregateEarliestValue, AggregateSum, AggregateMean, AggregateMax, AggregateCount}
+	tieBreaks    = []string{TieLast, TieFirst, TieMax, TieMin, TieError}
+	grains       = []string{GrainStatic, GrainYear, GrainQuarter, GrainMonth}
+	compressions = []string{CompressionNone, CompressionGzip, CompressionZstd, CompressionBzip2, CompressionSnappy}
+)
+
+// Datasets is the catalog of the logical datasets score configs read from.
+// A dataset.field source resolves against these declarations.
+type Datasets struct {
+	// Grain is the grain keys are evaluated at, year by default.
+	Grain      string     `mapstructure:"grain"`
+	FiscalYear FiscalYear `mapstructure:"fiscal_year"`
+	Datasets   []Dataset  `mapstructure:"datasets"`
+}
+
+// KeyGrain is the grain keys are evaluated at.
+func (d *Datasets) KeyGrain() string {
+	if d.Grain == "" {
+		return GrainYear
+	}
+	return d.Grain
+}
+
+// FiscalYear decides the period a dated row belongs to. A fiscal year is
+// named after the calendar year it ends in, so with a 03-31 year end a row
+// dated 2023-05-19 belongs to 2024.
+type FiscalYear struct {
+	// End is the MM-DD year end of every company not listed in Companies.
+	End       string              `mapstructure:"end"`
+	Companies []CompanyFiscalYear `mapstructure:"companies"`
+}
+
+// CompanyFiscalYear is the MM-DD year end of one company.
+type CompanyFiscalYear struct {
+	Company string `mapstructure:"company"`
+	End     string `mapstructure:"end"`
+}
+
+// Dataset declares one logical dataset: where its files are, how to read
+// them and which fields it provides. A dataset without a fields list
+// provides every numeric field of its files.
+type Dataset struct {
+	// Name defaults to the file name of a location without wildcards,
+	// without its extensions.
+	Name string `mapstructure:"name"`
+	// Location is a path or glob, relative to the data directory, or an
+	// s3://bucket/ or gs://bucket/ URL with a glob of object keys. Every
+	// matching file is read.
+	Location string `mapstructure:"location"`
+	// Database is read instead of files when set.
+	Database *Database `mapstructure:"database"`
+	// Stream is consumed instead of files when set.
+	Stream *Stream `mapstructure:"stream"`
+	// Format defaults to sql for databases, stream for streams and to the
+	// extension of Location otherwise, before any compression extension.
+	Format string `mapstructure:"format"`
+	// Compression is detected from the name of each file when not set.
+	Compression string   `mapstructure:"compression"`
+	Keys        []string `mapstructure:"keys"`
+	// Grain is the period one row covers, year by default. Static datasets
+	// may leave out the date column.
+	Grain string     `mapstructure:"grain"`
+	Date  DateColumn `mapstructure:"date"`
+	// Aggregation is the policy of fields without one, latest by default.
+	Aggregation string `mapstructure:"aggregation"`
+	// TieBreak defaults to last, so a later row restates an earlier one.
+	TieBreak string  `mapstructure:"tie_break"`
+	Fields   []Field `mapstructure:"fields"`
+}
+
+// Database is the database a SQL dataset is read from, with either a query
+// or a table whose columns are all selected. Environment variables in the
+// DSN are expanded, so credentials can stay out of the catalog, and a
+// relative SQLite path is resolved against the data directory.
+type Database struct {
+	Driver string `mapstructure:"driver"`
+	DSN    string `mapstructure:"dsn"`
+	Query  string `mapstructure:"query"`
+	Table  string `mapstructure:"table"`
+}
+
+// Source is the DSN to open, with relative SQLite paths resolved against
+// dataDir.
+func (db *Database) Source(dataDir string) string {
+	dsn := os.ExpandEnv(db.DSN)
+	if db.Driver == DriverSQLite && dsn != ":memory:" && !strings.HasPrefix(dsn, "file:") && !filepath.IsAbs(dsn) {
+		return filepath.Join(dataDir, dsn)
+	}
+	return dsn
+}
+
+// Stream is the topic a dataset is consumed from. Without an envelope
+// every event is one JSON object holding a row, read like the objects of a
+// JSON file, and the key of the event is the company when the row has no
+// key column. With the debezium envelope events are change events of the
+// rows of a source table, keyed by its primary key, which insert, update
+// and delete rows.
+type Stream struct {
+	Topic    string `mapstructure:"topic"`
+	Envelope string `mapstructure:"envelope"`
+}
+
+// DateColumn is the column dating a row and its format. An empty format
+// accepts both 2006-01-02 dates and bare years.
+type DateColumn struct {
+	Column string `mapstructure:"column"`
+	Format string `mapstructure:"format"`
+}
+
+// Field is one value column of a dataset.
+type Field struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+	// Path is the column holding the field when it is not named after it.
+	// In JSON and Parquet it may be a dotted path into nested objects, such
+	// as metrics.emissions.scope1.
+	Path        string `mapstructure:"path"`
+	Aggregation string `mapstructure:"aggregation"`
+}
+
+// Column is the column, or JSON path, the field is read from.
+func (f Field) Column() string {
+	if f.Path != "" {
+		return f.Path
+	}
+	return f.Name
+}
+
+// FieldAggregation is the policy of f: its own, the dataset's or latest.
+func (ds Dataset) FieldAggregation(f Field) string {
+	switch {
+	case f.Aggregation != "":
+		return f.Aggregation
+	case ds.Aggregation != "":
+		return ds.Aggregation
+	default:
+		return AggregateLatest
+	}
+}
+
+// DataGrain is the grain of the dataset, year when none is set.
+func (ds Dataset) DataGrain() string {
+	if ds.Grain == "" {
+		return GrainYear
+	}
+	return ds.Grain
+}
+
+// GrainRank orders grains from the coarsest, static, at 0. Unknown grains
+// rank -1.
+func GrainRank(grain string) int {
+	return slices.Index(grains, grain)
+}
+
+// Ties is the tie-break policy of the dataset, last when none is set.
+func (ds Dataset) Ties() string {
+	if ds.TieBreak == "" {
+		return TieLast
+	}
+	return ds.TieBreak
+}
+
+// YearEnd returns the fiscal year end of company: its own, the default one
+// or the end of the calendar year.
+func (fy FiscalYear) YearEnd(company string) string {
+	for _, cfy := range fy.Companies {
+		if cfy.Company == company {
+			return cfy.End
+		}
+	}
+	if fy.End != "" {
+		return fy.End
+	}
+	return DefaultYearEnd
+}
+
+// ParseYearEnd parses a MM-DD fiscal year end.
+func ParseYearEnd(end string) (time.Month, int, error) {
+	// a leap year, so 02-29 parses
+	t, err := time.Parse("2006-01-02", "2000-"+end)
+	if err != nil || len(end) != len("01-02") {
+		return 0, 0, fmt.Errorf("fiscal year end %q must be MM-DD", end)
+	}
+	return t.Month(), t.Day(), nil
+}
+
+// Get returns the dataset declared under name. A nil catalog declares no
+// datasets.
+func (d *Datasets) Get(name string) (Dataset, bool) {
+	if d == nil {
+		return Dataset{}, false
+	}
+	for _, ds := range d.Datasets {
+		if ds.Name == name {
+			return ds, true
+		}
+	}
+	return Dataset{}, false
+}
+
+// Has reports whether a dataset is declared under name.
+func (d *Datasets) Has(name string) bool {
+	_, ok := d.Get(name)
+	return ok
+}
+
+// InfersFields reports whether the dataset has no fields list, so every
+// numeric column is read. An empty list declares no fields at all.
+func (ds Dataset) InfersFields() bool {
+	return ds.Fields == nil
+}
+
+// DataFormat is the declared format, or the extension of the location when
+// none is declared.
+func (ds Dataset) DataFormat() string {
+	if ds.Format != "" {
+		return ds.Format
+	}
+	if ds.Database != nil {
+		return FormatSQL
+	}
+	if ds.Stream != nil {
+		return FormatStream
+	}
+	location, _ := SplitCompression(ds.Location)
+	ext := strings.TrimPrefix(path.Ext(location), ".")
+	if ext == "jsonl" {
+		return FormatNDJSON
+	}
+	return ext
+}
+
+// FileCompression is the compression a file of the dataset is read with,
+// empty when it is not compressed.
+func (ds Dataset) FileCompression(name string) string {
+	switch ds.Compression {
+	case CompressionNone:
+		return ""
+	case "":
+		_, compression := SplitCompression(name)
+		return compression
+	}
+	return ds.Compression
+}
+
+// SplitCompression splits the compression extension off a file name:
+// emissions_2024.csv.zst is emissions_2024.csv compressed with zstd. A name
+// without one has no compression.
+func SplitCompression(name string) (string, string) {
+	ext := path.Ext(name)
+	if compression, ok := compressionExts[strings.ToLower(ext)]; ok {
+		return strings.TrimSuffix(name, ext), compression
+	}
+	return name, ""
+}
+
+// ParseDatasets decodes a dataset catalog from its YAML bytes.
+func ParseDatasets(fileData []byte) (*Datasets, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(fileData)); err != nil {
+		return nil, fmt.Errorf("error loading dataset catalog: %v", err)
+	}
+	datasets := &Datasets{}
+	if err := v.Unmarshal(datasets); err != nil {
+		return nil, fmt.Errorf("error unmarshalling dataset catalog: %v", err)
+	}
+	for i, ds := range datasets.Datasets {
+		if ds.Name == "" && ds.Location != "" && !strings.ContainsAny(ds.Location, `*?[\`) {
+			name, _ := SplitCompression(path.Base(ds.Location))
+			datasets.Datasets[i].Name = strings.TrimSuffix(name, path.Ext(name))
+		}
+	}
+	return datasets, nil
+}
+
+// LoadDatasets reads and validates the dataset catalog in dir. When dir has
+// none the catalog embedded in the binary is used.
+func LoadDatasets(dir string) (*Datasets, error) {
+	var fsys fs.FS = datasetsFS
+	if dir != "" {
+		if _, err := os.Stat(filepath.Join(dir, DatasetsFile)); err == nil {
+			fsys = os.DirFS(dir)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	fileData, err := fs.ReadFile(fsys, DatasetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dataset catalog: %v", err)
+	}
+	datasets, err := ParseDatasets(fileData)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDatasets(datasets); err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+// DefaultDatasets returns the dataset catalog embedded in the binary.
+func DefaultDatasets() (*Datasets, error) {
+	return LoadDatasets("")
+}
+
+// ValidateDatasets checks the grain and fiscal years and every declaration
+// of a catalog: unique names, a location or database, a known format, a
+// single key column, a known grain, a date column with a usable format
+// unless static, known aggregation and tie-break policies and unique, typed
+// fields that do not shadow the key or date and have no dots in their name.
+func ValidateDatasets(datasets *Datasets) error {
+	if len(datasets.Datasets) == 0 {
+		return fmt.Errorf("dataset catalog declares no datasets")
+	}
+	grain := datasets.KeyGrain()
+	if grain == GrainStatic || GrainRank(grain) < 0 {
+		return fmt.Errorf("unknown grain %q, expected one of year, quarter, month", grain)
+	}
+	if err := validateFiscalYear(datasets.FiscalYear); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(datasets.Datasets))
+	topics := make(map[string]string)
+	for i, ds := range datasets.Datasets {
+		if ds.Name == "" {
+			return fmt.Errorf("dataset #%d has no name", i+1)
+		}
+		if seen[ds.Name] {
+			return fmt.Errorf("duplicate dataset %q", ds.Name)
+		}
+		seen[ds.Name] = true
+		if err := validateDataset(ds); err != nil {
+			return fmt.Errorf("dataset %q: %w", ds.Name, err)
+		}
+		if ds.Stream == nil {
+			continue
+		}
+		if other, ok := topics[ds.Stream.Topic]; ok {
+			return fmt.Errorf("datasets %q and %q both stream topic %q", other, ds.Name, ds.Stream.Topic)
+		}
+		topics[ds.Stream.Topic] = ds.Name
+	}
+	return nil
+}
+
+func validateDataset(ds Dataset) error {
+	switch {
+	case ds.Database != nil && ds.Stream != nil:
+		return fmt.Errorf("both a database and a stream")
+	case ds.Database != nil:
+		if err := validateDatabase(ds); err != nil {
+			return err
+		}
+	case ds.Stream != nil:
+		if ds.Location != "" {
+			return fmt.Errorf("both a location and a stream")
+		}
+		if ds.Stream.Topic == "" {
+			return fmt.Errorf("stream has no topic")
+		}
+		if env := ds.Stream.Envelope; env != "" && env != EnvelopeDebezium {
+			return fmt.Errorf("unknown stream envelope %q, expected %s", env, EnvelopeDebezium)
+		}
+	default:
+		if ds.Location == "" {
+			return fmt.Errorf("no location")
+		}
+		if _, err := filepath.Match(ds.Location, ""); err != nil {
+			return fmt.Errorf("invalid location %q: %v", ds.Location, err)
+		}
+	}
+	if format := ds.DataFormat(); !slices.Contains(formats, format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(formats, ", "))
+	}
+	if (ds.DataFormat() == FormatSQL) != (ds.Database != nil) {
+		return fmt.Errorf("format %q requires a database and a database the %q format", FormatSQL, FormatSQL)
+	}
+	if (ds.DataFormat() == FormatStream) != (ds.Stream != nil) {
+		return fmt.Errorf("format %q requires a stream and a stream the %q format", FormatStream, FormatStream)
+	}
+	if ds.Compression != "" {
+		if ds.Location == "" {
+			return fmt.Errorf("compression %q without a location", ds.Compression)
+		}
+		if !slices.Contains(compressions, ds.Compression) {
+			return fmt.Errorf("unknown compression %q, expected one of %s", ds.Compression, strings.Join(compressions, ", "))
+		}
+	}
+	if len(ds.Keys) != 1 || ds.Keys[0] == "" {
+		return fmt.Errorf("expected exactly one key column, got %d", len(ds.Keys))
+	}
+	if GrainRank(ds.DataGrain()) < 0 {
+		return fmt.Errorf("unknown grain %q, expected one of %s", ds.Grain, strings.Join(grains, ", "))
+	}
+	if ds.Date.Column == "" && ds.DataGrain() != GrainStatic {
+		return fmt.Errorf("no date column")
+	}
+	if ds.Date.Column != "" && ds.Date.Column == ds.Keys[0] {
+		return fmt.Errorf("date column %q is also the key column", ds.Date.Column)
+	}
+	// the year is all scoring reads from a date
+	if f := ds.Date.Format; f != "" && f != DateFormatYear && f != DateFormatQuarter && !strings.Contains(f, "2006") {
+		return fmt.Errorf("date format %q must be %q, %q or a Go time layout with a year, such as 2006-01-02", f, DateFormatYear, DateFormatQuarter)
+	}
+	if ds.Aggregation != "" && !slices.Contains(aggregations, ds.Aggregation) {
+		return fmt.Errorf("unknown aggregation %q, expected one of %s", ds.Aggregation, strings.Join(aggregations, ", "))
+	}
+	if ds.TieBreak != "" && !slices.Contains(tieBreaks, ds.TieBreak) {
+		return fmt.Errorf("unknown tie_break %q, expected one of %s", ds.TieBreak, strings.Join(tieBreaks, ", "))
+	}
+	fields := make(map[string]bool, len(ds.Fields))
+	for i, f := range ds.Fields {
+		switch {
+		case f.Name == "":
+			return fmt.Errorf("field #%d has no name", i+1)
+		case strings.Contains(f.Name, "."):
+			return fmt.Errorf("field name %q cannot contain dots, set path to read a nested value", f.Name)
+		case fields[f.Name]:
+			return fmt.Errorf("duplicate field %q", f.Name)
+		case f.Name == ds.Keys[0] || f.Name == ds.Date.Column:
+			return fmt.Errorf("field %q is the key or date column", f.Name)
+		case !slices.Contains(fieldTypes, f.Type):
+			return fmt.Errorf("field %q has unknown type %q, expected one of %s", f.Name, f.Type, strings.Join(fieldTypes, ", "))
+		case f.Aggregation != "" && !slices.Contains(aggregations, f.Aggregation):
+			return fmt.Errorf("field %q has unknown aggregation %q, expected one of %s", f.Name, f.Aggregation, strings.Join(aggregations, ", "))
+		}
+		fields[f.Name] = true
+	}
+	return nil
+}
+
+// validateFiscalYear checks the year ends. Quarters and months are counted
+// from the day after the year end, so any day of the year can end it.
+func validateFiscalYear(fy FiscalYear) error {
+	check := func(end string) error {
+		_, _, err := ParseYearEnd(end)
+		return err
+	}
+	if fy.End != "" {
+		if err := check(fy.End); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool, len(fy.Companies))
+	for i, cfy := range fy.Companies {
+		switch {
+		case cfy.Company == "":
+			return fmt.Errorf("fiscal year #%d has no company", i+1)
+		case seen[cfy.Company]:
+			return fmt.Errorf("duplicate fiscal year for company %q", cfy.Company)
+		}
+		seen[cfy.Company] = true
+		if err := check(cfy.End); err != nil {
+			return fmt.Errorf("company %q: %w", cfy.Company, err)
+		}
+	}
+	return nil
+}
+
+// validateDatabase checks the driver, DSN and query or table of a SQL
+// dataset, which has no file location.
+func validateDatabase(ds Dataset) error {
+	db := ds.Database
+	switch {
+	case ds.Location != "":
+		return fmt.Errorf("both a location and a database")
+	case !slices.Contains(drivers, db.Driver):
+		return fmt.Errorf("unknown database driver %q, expected one of %s", db.Driver, strings.Join(drivers, ", "))
+	case db.DSN == "":
+		return fmt.Errorf("database has no dsn")
+	case (db.Query == "") == (db.Table == ""):
+		return fmt.Errorf("database needs exactly one of query and table")
+	}
+	return nil
+}
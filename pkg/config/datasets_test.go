@@ -0,0 +1,268 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultDatasets(t *testing.T) {
+	datasets, err := DefaultDatasets()
+	require.NoError(t, err)
+
+	waste, ok := datasets.Get("waste")
+	require.True(t, ok)
+	assert.Equal(t, FormatCSV, waste.DataFormat())
+	assert.Equal(t, []string{"company_id"}, waste.Keys)
+	assert.Len(t, waste.Fields, 4)
+	assert.True(t, datasets.Has("disclosure"))
+	assert.False(t, datasets.Has("waste_data"))
+}
+
+func TestLoadDatasetsFromDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DatasetsFile), []byte(`datasets:
+  - name: water
+    location: water/*.json
+    keys: [company_id]
+    date:
+      column: reported_at
+    fields:
+      - name: wat_1
+        type: number
+`), 0o644))
+
+	datasets, err := LoadDatasets(dir)
+	require.NoError(t, err)
+	require.Len(t, datasets.Datasets, 1)
+	assert.Equal(t, FormatJSON, datasets.Datasets[0].DataFormat())
+
+	// a directory without a catalog falls back to the embedded one
+	datasets, err = LoadDatasets(t.TempDir())
+	require.NoError(t, err)
+	assert.True(t, datasets.Has("emissions"))
+}
+
+func TestValidateDatasets(t *testing.T) {
+	valid := func() Dataset {
+		return Dataset{
+			Name:     "waste",
+			Location: "waste_*.csv",
+			Keys:     []string{"company_id"},
+			Date:     DateColumn{Column: "date", Format: "2006-01-02"},
+			Fields:   []Field{{Name: "was_1", Type: FieldNumber}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(ds *Dataset)
+		wantErr string
+	}{
+		{name: "valid", modify: func(ds *Dataset) {}},
+		{name: "no location", modify: func(ds *Dataset) { ds.Location = "" }, wantErr: `dataset "waste": no location`},
+		{name: "bad glob", modify: func(ds *Dataset) { ds.Location = "waste_[.csv" }, wantErr: `invalid location "waste_[.csv"`},
+		{name: "unknown format", modify: func(ds *Dataset) { ds.Location = "waste.xlsx" }, wantErr: `unknown format "xlsx", expected one of csv, json`},
+		{name: "database", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Database = &Database{Driver: DriverPostgres, DSN: "${SCORES_DSN}", Table: "public.waste"}
+		}},
+		{name: "database and location", modify: func(ds *Dataset) {
+			ds.Database = &Database{Driver: DriverSQLite, DSN: "scores.db", Table: "waste"}
+		}, wantErr: `both a location and a database`},
+		{name: "unknown driver", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Database = &Database{Driver: "mysql", DSN: "scores", Table: "waste"}
+		}, wantErr: `unknown database driver "mysql", expected one of postgres, sqlite`},
+		{name: "query and table", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Database = &Database{Driver: DriverSQLite, DSN: "scores.db", Table: "waste", Query: "SELECT * FROM waste"}
+		}, wantErr: `database needs exactly one of query and table`},
+		{name: "sql without database", modify: func(ds *Dataset) { ds.Format = FormatSQL }, wantErr: `format "sql" requires a database`},
+		{name: "stream", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "waste-events"}
+		}},
+		{name: "stream and location", modify: func(ds *Dataset) { ds.Stream = &Stream{Topic: "waste-events"} }, wantErr: `both a location and a stream`},
+		{name: "stream and database", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "waste-events"}
+			ds.Database = &Database{Driver: DriverSQLite, DSN: "scores.db", Table: "waste"}
+		}, wantErr: `both a database and a stream`},
+		{name: "stream without topic", modify: func(ds *Dataset) { ds.Location = ""; ds.Stream = &Stream{} }, wantErr: `stream has no topic`},
+		{name: "change stream", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "erp.public.waste", Envelope: EnvelopeDebezium}
+		}},
+		{name: "unknown envelope", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "erp.public.waste", Envelope: "maxwell"}
+		}, wantErr: `unknown stream envelope "maxwell", expected debezium`},
+		{name: "compressed files", modify: func(ds *Dataset) { ds.Location = "waste_*.csv.gz" }},
+		{name: "explicit compression", modify: func(ds *Dataset) { ds.Compression = CompressionZstd }},
+		{name: "unknown compression", modify: func(ds *Dataset) { ds.Compression = "lz4" },
+			wantErr: `unknown compression "lz4", expected one of none, gzip, zstd, bzip2, snappy`},
+		{name: "compressed stream", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "waste-events"}
+			ds.Compression = CompressionGzip
+		}, wantErr: `compression "gzip" without a location`},
+		{name: "csv stream", modify: func(ds *Dataset) {
+			ds.Location = ""
+			ds.Stream = &Stream{Topic: "waste-events"}
+			ds.Format = FormatCSV
+		}, wantErr: `format "stream" requires a stream`},
+		{name: "two keys", modify: func(ds *Dataset) { ds.Keys = []string{"company_id", "site"} }, wantErr: `expected exactly one key column, got 2`},
+		{name: "no date", modify: func(ds *Dataset) { ds.Date.Column = "" }, wantErr: `no date column`},
+		{name: "static without date", modify: func(ds *Dataset) { ds.Grain = GrainStatic; ds.Date = DateColumn{} }},
+		{name: "unknown grain", modify: func(ds *Dataset) { ds.Grain = "week" }, wantErr: `unknown grain "week", expected one of static, year, quarter, month`},
+		{name: "quarter dates", modify: func(ds *Dataset) { ds.Grain = GrainQuarter; ds.Date.Format = DateFormatQuarter }},
+		{name: "date is key", modify: func(ds *Dataset) { ds.Date.Column = "company_id" }, wantErr: `date column "company_id" is also the key column`},
+		{name: "date without year", modify: func(ds *Dataset) { ds.Date.Format = "01/02" }, wantErr: `date format "01/02" must be "year", "quarter" or a Go time layout`},
+		{name: "every numeric field", modify: func(ds *Dataset) { ds.Fields = nil }},
+		{name: "dotted name", modify: func(ds *Dataset) { ds.Fields[0].Name = "metrics.was_1" }, wantErr: `field name "metrics.was_1" cannot contain dots, set path to read a nested value`},
+		{name: "nested path", modify: func(ds *Dataset) { ds.Location = "waste.ndjson"; ds.Fields[0].Path = "metrics.was_1" }},
+		{name: "parquet", modify: func(ds *Dataset) { ds.Location = "exports/waste-*.parquet"; ds.Fields[0].Path = "metrics.was_1" }},
+		{name: "duplicate field", modify: func(ds *Dataset) { ds.Fields = append(ds.Fields, ds.Fields[0]) }, wantErr: `duplicate field "was_1"`},
+		{name: "field is key", modify: func(ds *Dataset) { ds.Fields[0].Name = "company_id" }, wantErr: `field "company_id" is the key or date column`},
+		{name: "unknown aggregation", modify: func(ds *Dataset) { ds.Aggregation = "median" }, wantErr: `unknown aggregation "median", expected one of latest, earliest, latest_value, earliest_value, sum, mean, max, count`},
+		{name: "unknown field aggregation", modify: func(ds *Dataset) { ds.Fields[0].Aggregation = "min" }, wantErr: `field "was_1" has unknown aggregation "min"`},
+		{name: "unknown tie_break", modify: func(ds *Dataset) { ds.TieBreak = "newest" }, wantErr: `unknown tie_break "newest", expected one of last, first, max, min, error`},
+		{name: "unknown type", modify: func(ds *Dataset) { ds.Fields[0].Type = "float" }, wantErr: `field "was_1" has unknown type "float", expected one of number, integer, boolean`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := valid()
+			tt.modify(&ds)
+			err := ValidateDatasets(&Datasets{Datasets: []Dataset{ds}})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	err := ValidateDatasets(&Datasets{Datasets: []Dataset{valid(), valid()}})
+	assert.EqualError(t, err, `duplicate dataset "waste"`)
+
+	streamed := func(name string) Dataset {
+		ds := valid()
+		ds.Name, ds.Location, ds.Stream = name, "", &Stream{Topic: "waste-events"}
+		return ds
+	}
+	err = ValidateDatasets(&Datasets{Datasets: []Dataset{streamed("waste"), streamed("waste_live")}})
+	assert.EqualError(t, err, `datasets "waste" and "waste_live" both stream topic "waste-events"`)
+	assert.EqualError(t, ValidateDatasets(&Datasets{}), "dataset catalog declares no datasets")
+}
+
+func TestCompressedFiles(t *testing.T) {
+	tests := []struct {
+		location, format, compression string
+	}{
+		{location: "waste.csv", format: FormatCSV},
+		{location: "waste.csv.gz", format: FormatCSV, compression: CompressionGzip},
+		{location: "water/*.json.zst", format: FormatJSON, compression: CompressionZstd},
+		{location: "water/*.jsonl.bz2", format: FormatNDJSON, compression: CompressionBzip2},
+		{location: "s3://lake/emissions/*.parquet.snappy", format: FormatParquet, compression: CompressionSnappy},
+		// the format of a compressed file without one has to be set
+		{location: "waste.gz", format: "", compression: CompressionGzip},
+	}
+	for _, tt := range tests {
+		ds := Dataset{Location: tt.location}
+		assert.Equal(t, tt.format, ds.DataFormat(), tt.location)
+		assert.Equal(t, tt.compression, ds.FileCompression(tt.location), tt.location)
+	}
+
+	// a compression set on the dataset applies to every file, or to none
+	assert.Equal(t, CompressionGzip, Dataset{Compression: CompressionGzip}.FileCompression("waste.csv"))
+	assert.Equal(t, "", Dataset{Compression: CompressionNone}.FileCompression("waste.csv.gz"))
+
+	// a dataset reading one file is named after it
+	datasets, err := ParseDatasets([]byte(`datasets:
+  - location: exports/emissions_2024.csv.zst
+    keys: [company_id]
+  - location: exports/waste_*.csv.gz
+    keys: [company_id]
+  - name: water
+    location: exports/water.json
+    keys: [company_id]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "emissions_2024", datasets.Datasets[0].Name)
+	assert.Equal(t, FormatCSV, datasets.Datasets[0].DataFormat())
+	assert.Equal(t, "", datasets.Datasets[1].Name)
+	assert.Equal(t, "water", datasets.Datasets[2].Name)
+}
+
+func TestDatabaseSource(t *testing.T) {
+	t.Setenv("SCORES_DB", "scores.db")
+	db := &Database{Driver: DriverSQLite, DSN: "${SCORES_DB}"}
+	assert.Equal(t, filepath.Join("data", "scores.db"), db.Source("data"))
+
+	db = &Database{Driver: DriverPostgres, DSN: "postgres://scores@localhost/scores"}
+	assert.Equal(t, "postgres://scores@localhost/scores", db.Source("data"))
+
+	assert.Equal(t, FormatSQL, Dataset{Database: db}.DataFormat())
+
+	datasets, err := ParseDatasets([]byte(`datasets:
+  - name: emissions
+    database:
+      driver: sqlite
+      dsn: scores.db
+      query: SELECT company_id, date, emi_1 FROM emissions
+    keys: [company_id]
+    date:
+      column: date
+`))
+	require.NoError(t, err)
+	require.NoError(t, ValidateDatasets(datasets))
+	assert.Equal(t, &Database{Driver: DriverSQLite, DSN: "scores.db", Query: "SELECT company_id, date, emi_1 FROM emissions"},
+		datasets.Datasets[0].Database)
+}
+
+func TestFiscalYear(t *testing.T) {
+	fy := FiscalYear{End: "03-31", Companies: []CompanyFiscalYear{{Company: "1000", End: "06-30"}}}
+	assert.Equal(t, "06-30", fy.YearEnd("1000"))
+	assert.Equal(t, "03-31", fy.YearEnd("1001"))
+	assert.Equal(t, DefaultYearEnd, FiscalYear{}.YearEnd("1001"))
+
+	month, day, err := ParseYearEnd("02-29")
+	require.NoError(t, err)
+	assert.Equal(t, time.February, month)
+	assert.Equal(t, 29, day)
+
+	valid := Dataset{
+		Name:     "waste",
+		Location: "waste.csv",
+		Keys:     []string{"company_id"},
+		Date:     DateColumn{Column: "date"},
+		Fields:   []Field{{Name: "was_1", Type: FieldNumber}},
+	}
+	tests := []struct {
+		fy      FiscalYear
+		wantErr string
+	}{
+		{fy: FiscalYear{End: "3-31"}, wantErr: `fiscal year end "3-31" must be MM-DD`},
+		{fy: FiscalYear{End: "13-01"}, wantErr: `fiscal year end "13-01" must be MM-DD`},
+		{fy: FiscalYear{Companies: []CompanyFiscalYear{{End: "03-31"}}}, wantErr: `fiscal year #1 has no company`},
+		{fy: FiscalYear{Companies: []CompanyFiscalYear{{Company: "1000", End: "03-31"}, {Company: "1000", End: "06-30"}}}, wantErr: `duplicate fiscal year for company "1000"`},
+		{fy: FiscalYear{Companies: []CompanyFiscalYear{{Company: "1000", End: "06-31"}}}, wantErr: `company "1000": fiscal year end "06-31" must be MM-DD`},
+	}
+	for _, tt := range tests {
+		err := ValidateDatasets(&Datasets{FiscalYear: tt.fy, Datasets: []Dataset{valid}})
+		assert.EqualError(t, err, tt.wantErr)
+	}
+
+	// quarters and months count from the day after any year end
+	quarters := &Datasets{Grain: GrainQuarter, FiscalYear: FiscalYear{End: "03-15"}, Datasets: []Dataset{valid}}
+	assert.NoError(t, ValidateDatasets(quarters))
+	quarters.FiscalYear.End = "02-28"
+	assert.NoError(t, ValidateDatasets(quarters))
+	quarters.Grain = GrainStatic
+	assert.EqualError(t, ValidateDatasets(quarters), `unknown grain "static", expected one of year, quarter, month`)
+}
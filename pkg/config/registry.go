@@ -0,0 +1,269 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce groups the burst of events editors and ConfigMap updates
+// produce for a single change into one reload.
+const reloadDebounce = 250 * time.Millisecond
+
+// Lookup resolves a score config by name.
+type Lookup func(name string) (*Config, bool)
+
+// ValidateFunc checks a config before it is swapped into the registry.
+// lookup resolves the other configs of the same reload, so references
+// between scores can be checked. When none is given the structural checks
+// in Validate are used.
+type ValidateFunc func(config *Config, lookup Lookup) error
+
+// snapshot is an immutable view of the configs served at a point in time.
+// version counts the reloads that led to it.
+type snapshot struct {
+	byName  map[string]*Config
+	byFile  map[string]*Config
+	version uint64
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{byName: map[string]*Config{}, byFile: map[string]*Config{}}
+}
+
+func (s *snapshot) get(name string) (*Config, bool) {
+	if cfg, ok := s.byName[name]; ok {
+		return cfg, true
+	}
+	cfg, ok := s.byFile[name]
+	return cfg, ok
+}
+
+func (s *snapshot) add(fileName string, cfg *Config) bool {
+	if _, dup := s.byName[cfg.Name]; dup {
+		return false
+	}
+	s.byName[cfg.Name] = cfg
+	s.byFile[fileName] = cfg
+	return true
+}
+
+func (s *snapshot) remove(fileName string) {
+	if cfg, ok := s.byFile[fileName]; ok {
+		delete(s.byName, cfg.Name)
+		delete(s.byFile, fileName)
+	}
+}
+
+// Registry discovers the score configs under a directory, keeps the last
+// valid version of each one and hot reloads them when files change.
+type Registry struct {
+	logger   *zap.Logger
+	dir      string
+	fsys     fs.FS
+	validate ValidateFunc
+
+	mu      sync.Mutex // serialises reloads
+	current atomic.Pointer[snapshot]
+}
+
+// NewRegistry builds a registry over dir and performs the initial load. When
+// dir is empty or does not exist the configs embedded in the binary are
+// served instead and Watch is a no-op.
+func NewRegistry(logger *zap.Logger, dir string, validate ValidateFunc) (*Registry, error) {
+	r := &Registry{
+		logger:   logger,
+		validate: validate,
+	}
+	if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+		r.dir = dir
+		r.fsys = os.DirFS(dir)
+	} else {
+		logger.Info("Score config directory not found, using embedded configs", zap.String("dir", dir))
+		r.fsys = configFS
+	}
+	r.current.Store(newSnapshot())
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get returns the config registered under name. Both the config name
+// (score_1) and its file name (score_1.yaml) are accepted.
+func (r *Registry) Get(name string) (*Config, bool) {
+	return r.current.Load().get(name)
+}
+
+// Version identifies the configs currently served. It changes with every
+// reload, so anything derived from the configs, such as their plans, can be
+// kept until it does.
+func (r *Registry) Version() uint64 {
+	return r.current.Load().version
+}
+
+// Names returns the sorted names of every config currently served.
+func (r *Registry) Names() []string {
+	snap := r.current.Load()
+	names := make([]string, 0, len(snap.byName))
+	for name := range snap.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reload rescans the directory. Files that fail to parse or validate keep
+// serving their previous version; files that disappeared are dropped.
+//
+// Configs are validated against the other configs of the same reload. When
+// one is rejected the rest are validated again, since a config referencing
+// the rejected one may no longer be valid. A previous version kept in place
+// of a rejected file must pass the same check or it is dropped too.
+func (r *Registry) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := fs.ReadDir(r.fsys, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read config directory %s: %w", r.dir, err)
+	}
+
+	prev := r.current.Load()
+	next := newSnapshot()
+	next.version = prev.version + 1
+	var files []string
+	fallback := make(map[string]bool)
+
+	// reject replaces a file's config with its previous version, or drops
+	// it when there is none or the previous version was rejected as well.
+	reject := func(fileName string, err error) {
+		next.remove(fileName)
+		if old, ok := prev.byFile[fileName]; ok && !fallback[fileName] && next.add(fileName, old) {
+			fallback[fileName] = true
+			r.logger.Error("Rejected score config, keeping last good version",
+				zap.String("file", fileName), zap.Error(err))
+			return
+		}
+		r.logger.Error("Rejected score config", zap.String("file", fileName), zap.Error(err))
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".yaml" {
+			continue
+		}
+		fileName := e.Name()
+
+		cfg, err := r.loadFile(fileName)
+		switch {
+		case err != nil:
+			reject(fileName, err)
+		case cfg == nil:
+			continue // not a score config
+		case !next.add(fileName, cfg):
+			r.logger.Error("Duplicate score config name, ignoring file",
+				zap.String("name", cfg.Name), zap.String("file", fileName))
+			continue
+		}
+		files = append(files, fileName)
+	}
+
+	for changed := r.validate != nil; changed; {
+		changed = false
+		for _, fileName := range files {
+			cfg, ok := next.byFile[fileName]
+			if !ok {
+				continue
+			}
+			if err := r.validate(cfg, next.get); err != nil {
+				reject(fileName, err)
+				changed = true
+			}
+		}
+	}
+
+	r.current.Store(next)
+	r.logger.Info("Score configs loaded", zap.Strings("names", r.Names()))
+	return nil
+}
+
+// loadFile parses one file and, when the registry has no ValidateFunc, runs
+// the structural checks on it. It returns a nil config without error for
+// YAML files that are not score configs, e.g. the prometheus or loki configs
+// living in the same directory.
+func (r *Registry) loadFile(fileName string) (*Config, error) {
+	data, err := fs.ReadFile(r.fsys, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := IsScoreConfig(data); !ok {
+		return nil, err
+	}
+
+	cfg, err := ParseConfigFile(fileName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.validate == nil {
+		if err := Validate(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Watch reloads the registry whenever the directory changes until ctx is
+// cancelled. Events are debounced so a burst of writes triggers one reload.
+func (r *Registry) Watch(ctx context.Context) error {
+	if r.dir == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(r.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", r.dir, err)
+	}
+	r.logger.Info("Watching score configs", zap.String("dir", r.dir))
+
+	timer := time.NewTimer(reloadDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			r.logger.Debug("Score config change", zap.String("event", ev.String()))
+			timer.Reset(reloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.logger.Error("Score config watcher error", zap.Error(err))
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Failed to reload score configs", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const scoreA = `name: score_a
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_1
+`
+
+const scoreAv2 = `name: score_a
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: waste.was_2
+`
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestRegistryLoadsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "score_a.yaml", scoreA)
+	writeFile(t, dir, "loki-config.yaml", "auth_enabled: false\n")
+
+	r, err := NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"score_a"}, r.Names())
+
+	cfg, ok := r.Get("score_a")
+	require.True(t, ok)
+	assert.Equal(t, "waste.was_1", cfg.Metrics[0].Operation.Parameters[0].Source)
+
+	byFile, ok := r.Get("score_a.yaml")
+	require.True(t, ok)
+	assert.Same(t, cfg, byFile)
+}
+
+func TestParseConfigFileNamesAfterFile(t *testing.T) {
+	unnamed := strings.TrimPrefix(scoreA, "name: score_a\n")
+	cfg, err := ParseConfigFile(filepath.Join("configs", "score_b.yaml"), []byte(unnamed))
+	require.NoError(t, err)
+	assert.Equal(t, "score_b", cfg.Name)
+	assert.Equal(t, filepath.Join("configs", "score_b.yaml"), cfg.File)
+
+	cfg, err = ParseConfigFile("score_b.yaml", []byte(scoreA))
+	require.NoError(t, err)
+	assert.Equal(t, "score_a", cfg.Name)
+}
+
+func TestRegistryKeepsLastGoodVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "score_a.yaml", scoreA)
+
+	r, err := NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+	version := r.Version()
+
+	// duplicate metric names fail validation
+	writeFile(t, dir, "score_a.yaml", scoreA+scoreA[len("name: score_a\nmetrics:\n"):])
+	writeFile(t, dir, "score_b.yaml", "name: score_b\nmetrics: [")
+	require.NoError(t, r.Reload())
+	assert.Greater(t, r.Version(), version)
+
+	cfg, ok := r.Get("score_a")
+	require.True(t, ok)
+	assert.Len(t, cfg.Metrics, 1)
+	_, ok = r.Get("score_b")
+	assert.False(t, ok)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "score_a.yaml")))
+	require.NoError(t, r.Reload())
+	assert.Empty(t, r.Names())
+}
+
+func TestRegistryWatchSwapsNewVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "score_a.yaml", scoreA)
+
+	r, err := NewRegistry(zap.NewNop(), dir, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = r.Watch(ctx) }()
+	time.Sleep(50 * time.Millisecond) // let the watcher register
+
+	writeFile(t, dir, "score_a.yaml", scoreAv2)
+
+	assert.Eventually(t, func() bool {
+		cfg, ok := r.Get("score_a")
+		return ok && cfg.Metrics[0].Operation.Parameters[0].Source == "waste.was_2"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestRegistryFallsBackToEmbedded(t *testing.T) {
+	r, err := NewRegistry(zap.NewNop(), filepath.Join(t.TempDir(), "missing"), nil)
+	require.NoError(t, err)
+
+	_, ok := r.Get("score_1.yaml")
+	assert.True(t, ok)
+}
+
+func TestRegistryRevalidatesDependents(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "score_a.yaml", scoreA)
+	writeFile(t, dir, "score_b.yaml", `name: score_b
+metrics:
+  - name: metric_1
+    operation:
+      type: sum
+      parameters:
+        - source: score_a.metric_1
+`)
+
+	// score_b needs score_a, and score_a is rejected when it reads was_2
+	validate := func(cfg *Config, lookup Lookup) error {
+		if cfg.Metrics[0].Operation.Parameters[0].Source == "waste.was_2" {
+			return assert.AnError
+		}
+		if cfg.Name == "score_b" {
+			if _, ok := lookup("score_a"); !ok {
+				return assert.AnError
+			}
+		}
+		return nil
+	}
+
+	r, err := NewRegistry(zap.NewNop(), dir, validate)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"score_a", "score_b"}, r.Names())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "score_a.yaml")))
+	writeFile(t, dir, "score_a2.yaml", scoreAv2)
+	require.NoError(t, r.Reload())
+	assert.Empty(t, r.Names())
+}
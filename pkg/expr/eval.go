@@ -0,0 +1,307 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Resolver returns the value of a referenced source and whether it is null.
+type Resolver func(source string) (float64, bool)
+
+// value is a nullable number. Booleans are 1 (true) and 0 (false).
+type value struct {
+	v    float64
+	null bool
+}
+
+var null = value{null: true}
+
+func number(v float64) value {
+	return value{v: v}
+}
+
+func boolean(b bool) value {
+	if b {
+		return value{v: 1}
+	}
+	return value{v: 0}
+}
+
+func (v value) truthy() bool {
+	return !v.null && v.v != 0
+}
+
+// Eval evaluates the expression. Nulls propagate through arithmetic and
+// comparisons; and/or follow three-valued logic (false and null is false,
+// true or null is true); if() with a null condition is null; coalesce()
+// returns its first non-null argument and min()/max() ignore nulls. Errors
+// such as division by zero are returned as *Error.
+func (e *Expr) Eval(resolve Resolver) (float64, bool, error) {
+	v, err := eval(e.root, resolve)
+	if err != nil {
+		return 0, true, err
+	}
+	if v.null {
+		return 0, true, nil
+	}
+	return v.v, false, nil
+}
+
+func eval(n node, resolve Resolver) (value, error) {
+	switch n := n.(type) {
+	case numberNode:
+		return number(n.value), nil
+	case nullNode:
+		return null, nil
+	case refNode:
+		v, isNull := resolve(n.source)
+		if isNull {
+			return null, nil
+		}
+		return number(v), nil
+	case unaryNode:
+		operand, err := eval(n.operand, resolve)
+		if err != nil || operand.null {
+			return null, err
+		}
+		if n.op == "-" {
+			return number(-operand.v), nil
+		}
+		return boolean(!operand.truthy()), nil
+	case binaryNode:
+		return evalBinary(n, resolve)
+	case callNode:
+		return functions[n.name].call(n, resolve)
+	}
+	return null, fmt.Errorf("unknown expression node %T", n)
+}
+
+func evalBinary(n binaryNode, resolve Resolver) (value, error) {
+	left, err := eval(n.left, resolve)
+	if err != nil {
+		return null, err
+	}
+
+	// and/or short-circuit and treat null as unknown
+	switch n.op {
+	case "&&":
+		if !left.null && !left.truthy() {
+			return boolean(false), nil
+		}
+		right, err := eval(n.right, resolve)
+		if err != nil {
+			return null, err
+		}
+		if !right.null && !right.truthy() {
+			return boolean(false), nil
+		}
+		if left.null || right.null {
+			return null, nil
+		}
+		return boolean(true), nil
+	case "||":
+		if left.truthy() {
+			return boolean(true), nil
+		}
+		right, err := eval(n.right, resolve)
+		if err != nil {
+			return null, err
+		}
+		if right.truthy() {
+			return boolean(true), nil
+		}
+		if left.null || right.null {
+			return null, nil
+		}
+		return boolean(false), nil
+	}
+
+	right, err := eval(n.right, resolve)
+	if err != nil {
+		return null, err
+	}
+	if left.null || right.null {
+		return null, nil
+	}
+
+	switch n.op {
+	case "+":
+		return number(left.v + right.v), nil
+	case "-":
+		return number(left.v - right.v), nil
+	case "*":
+		return number(left.v * right.v), nil
+	case "/":
+		if right.v == 0 {
+			return null, errorf(n.at, "division by zero")
+		}
+		return number(left.v / right.v), nil
+	case "%":
+		if right.v == 0 {
+			return null, errorf(n.at, "modulo by zero")
+		}
+		return number(math.Mod(left.v, right.v)), nil
+	case "==":
+		return boolean(left.v == right.v), nil
+	case "!=":
+		return boolean(left.v != right.v), nil
+	case "<":
+		return boolean(left.v < right.v), nil
+	case "<=":
+		return boolean(left.v <= right.v), nil
+	case ">":
+		return boolean(left.v > right.v), nil
+	case ">=":
+		return boolean(left.v >= right.v), nil
+	}
+	return null, errorf(n.at, "unknown operator %q", n.op)
+}
+
+// function is a builtin callable from expressions. maxArgs < 0 means
+// variadic.
+type function struct {
+	minArgs, maxArgs int
+	call             func(n callNode, resolve Resolver) (value, error)
+}
+
+func (f function) arity() string {
+	switch {
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d arguments", f.minArgs)
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d arguments", f.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+	}
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"if":       {minArgs: 3, maxArgs: 3, call: callIf},
+		"coalesce": {minArgs: 1, maxArgs: -1, call: callCoalesce},
+		"min":      {minArgs: 1, maxArgs: -1, call: callExtreme(math.Min)},
+		"max":      {minArgs: 1, maxArgs: -1, call: callExtreme(math.Max)},
+		"abs":      {minArgs: 1, maxArgs: 1, call: callMath(math.Abs)},
+		"round":    {minArgs: 1, maxArgs: 2, call: callRound},
+		"log":      {minArgs: 1, maxArgs: 2, call: callLog},
+		"pow":      {minArgs: 2, maxArgs: 2, call: callPow},
+	}
+}
+
+// evalArgs evaluates every argument eagerly.
+func evalArgs(n callNode, resolve Resolver) ([]value, error) {
+	args := make([]value, len(n.args))
+	for i, arg := range n.args {
+		v, err := eval(arg, resolve)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+func callIf(n callNode, resolve Resolver) (value, error) {
+	cond, err := eval(n.args[0], resolve)
+	if err != nil || cond.null {
+		return null, err
+	}
+	if cond.truthy() {
+		return eval(n.args[1], resolve)
+	}
+	return eval(n.args[2], resolve)
+}
+
+func callCoalesce(n callNode, resolve Resolver) (value, error) {
+	for _, arg := range n.args {
+		v, err := eval(arg, resolve)
+		if err != nil {
+			return null, err
+		}
+		if !v.null {
+			return v, nil
+		}
+	}
+	return null, nil
+}
+
+func callExtreme(pick func(a, b float64) float64) func(callNode, Resolver) (value, error) {
+	return func(n callNode, resolve Resolver) (value, error) {
+		args, err := evalArgs(n, resolve)
+		if err != nil {
+			return null, err
+		}
+		result := null
+		for _, v := range args {
+			switch {
+			case v.null:
+			case result.null:
+				result = v
+			default:
+				result = number(pick(result.v, v.v))
+			}
+		}
+		return result, nil
+	}
+}
+
+func callMath(fn func(float64) float64) func(callNode, Resolver) (value, error) {
+	return func(n callNode, resolve Resolver) (value, error) {
+		args, err := evalArgs(n, resolve)
+		if err != nil || args[0].null {
+			return null, err
+		}
+		return number(fn(args[0].v)), nil
+	}
+}
+
+func callRound(n callNode, resolve Resolver) (value, error) {
+	args, err := evalArgs(n, resolve)
+	if err != nil || args[0].null {
+		return null, err
+	}
+	digits := 0.0
+	if len(args) == 2 {
+		if args[1].null {
+			return null, nil
+		}
+		digits = math.Trunc(args[1].v)
+	}
+	scale := math.Pow(10, digits)
+	return number(math.Round(args[0].v*scale) / scale), nil
+}
+
+func callLog(n callNode, resolve Resolver) (value, error) {
+	args, err := evalArgs(n, resolve)
+	if err != nil || args[0].null {
+		return null, err
+	}
+	if args[0].v <= 0 {
+		return null, errorf(n.at, "log of non-positive value %g", args[0].v)
+	}
+	if len(args) == 1 {
+		return number(math.Log(args[0].v)), nil
+	}
+	base := args[1]
+	if base.null {
+		return null, nil
+	}
+	if base.v <= 0 || base.v == 1 {
+		return null, errorf(n.at, "invalid log base %g", base.v)
+	}
+	return number(math.Log(args[0].v) / math.Log(base.v)), nil
+}
+
+func callPow(n callNode, resolve Resolver) (value, error) {
+	args, err := evalArgs(n, resolve)
+	if err != nil || args[0].null || args[1].null {
+		return null, err
+	}
+	result := math.Pow(args[0].v, args[1].v)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return null, errorf(n.at, "pow(%g, %g) is not a finite number", args[0].v, args[1].v)
+	}
+	return number(result), nil
+}
@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	values := map[string]float64{
+		"waste.was_1":      10,
+		"disclosure.dis_2": 5,
+		"emissions.emi_4":  0.5,
+		"self.metric_1":    -3,
+	}
+	resolve := func(source string) (float64, bool) {
+		v, ok := values[source]
+		return v, !ok
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		want     float64
+		wantNull bool
+		wantErr  bool
+	}{
+		{name: "precedence", input: "1 + 2 * 3", want: 7},
+		{name: "parentheses", input: "(1 + 2) * 3", want: 9},
+		{name: "unary minus", input: "-self.metric_1", want: 3},
+		{name: "modulo", input: "7 % 4", want: 3},
+		{name: "product formula", input: "(waste.was_1 + disclosure.dis_2) / max(emissions.emi_4, 1) * 100", want: 1500},
+		{name: "comparison", input: "waste.was_1 > 5", want: 1},
+		{name: "boolean words", input: "waste.was_1 > 5 and not (disclosure.dis_2 == 5)", want: 0},
+		{name: "boolean symbols", input: "waste.was_1 < 5 || disclosure.dis_2 != 4", want: 1},
+		{name: "if", input: "if(waste.was_1 >= 10, 1, 2)", want: 1},
+		{name: "if null condition", input: "if(missing.x > 1, 1, 2)", wantNull: true},
+		{name: "coalesce", input: "coalesce(missing.x, null, disclosure.dis_2)", want: 5},
+		{name: "coalesce all null", input: "coalesce(missing.x, missing.y)", wantNull: true},
+		{name: "min ignores nulls", input: "min(missing.x, 4, waste.was_1)", want: 4},
+		{name: "abs", input: "abs(self.metric_1)", want: 3},
+		{name: "round", input: "round(2.345, 2)", want: 2.35},
+		{name: "round default digits", input: "round(2.5)", want: 3},
+		{name: "natural log", input: "log(1)", want: 0},
+		{name: "log base", input: "log(100, 10)", want: 2},
+		{name: "pow", input: "pow(2, 10)", want: 1024},
+		{name: "null propagates", input: "missing.x + 1", wantNull: true},
+		{name: "false and null", input: "false and missing.x", want: 0},
+		{name: "true or null", input: "true or missing.x", want: 1},
+		{name: "true and null", input: "true and missing.x", wantNull: true},
+		{name: "scientific notation", input: "1.5e3 / .5", want: 3000},
+		{name: "division by zero", input: "waste.was_1 / 0", wantErr: true},
+		{name: "log of zero", input: "log(0)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.input)
+			require.NoError(t, err)
+
+			got, isNull, err := e.Eval(resolve)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNull, isNull)
+			if !tt.wantNull {
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantOffset int
+		wantMsg    string
+	}{
+		{input: "1 +", wantOffset: 3, wantMsg: "unexpected end of expression"},
+		{input: "(1 + 2", wantOffset: 6, wantMsg: "expected ) to close ( at column 1"},
+		{input: "sqrt(4)", wantOffset: 0, wantMsg: `unknown function "sqrt"`},
+		{input: "pow(2)", wantOffset: 0, wantMsg: "pow expects 2 arguments, got 1"},
+		{input: "waste.was_1 # 2", wantOffset: 12, wantMsg: `unexpected character '#'`},
+		{input: "x + 1", wantOffset: 0, wantMsg: `invalid reference "x", expected <dataset>.<field> or self.<metric>`},
+		{input: "1 < 2 < 3", wantOffset: 6, wantMsg: "comparisons cannot be chained, use and"},
+		{input: "1 2", wantOffset: 2, wantMsg: `unexpected "2" after end of expression`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var exprErr *Error
+			require.True(t, errors.As(err, &exprErr), "expected *Error, got %v", err)
+			assert.Equal(t, tt.wantOffset, exprErr.Offset)
+			assert.Equal(t, tt.wantMsg, exprErr.Message)
+		})
+	}
+}
+
+func TestRefs(t *testing.T) {
+	e, err := Parse("if(self.metric_1 > 0, waste.was_1, score_2.metric_1)")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Ref{
+		{Source: "self.metric_1", Offset: 3},
+		{Source: "waste.was_1", Offset: 22},
+		{Source: "score_2.metric_1", Offset: 35},
+	}, e.Refs())
+}
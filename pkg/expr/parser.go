@@ -0,0 +1,308 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// node is one node of a parsed expression.
+type node interface {
+	pos() int
+}
+
+type numberNode struct {
+	at    int
+	value float64
+}
+
+type nullNode struct {
+	at int
+}
+
+type refNode struct {
+	at     int
+	source string
+}
+
+type unaryNode struct {
+	at      int
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	at          int
+	op          string
+	left, right node
+}
+
+type callNode struct {
+	at   int
+	name string
+	args []node
+}
+
+func (n numberNode) pos() int { return n.at }
+func (n nullNode) pos() int   { return n.at }
+func (n refNode) pos() int    { return n.at }
+func (n unaryNode) pos() int  { return n.at }
+func (n binaryNode) pos() int { return n.at }
+func (n callNode) pos() int   { return n.at }
+
+// Ref is a source referenced by an expression and where it appears.
+type Ref struct {
+	Source string
+	Offset int
+}
+
+// Expr is a parsed expression, safe for concurrent evaluation.
+type Expr struct {
+	src  string
+	root node
+	refs []Ref
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Refs returns every source the expression reads, in order of appearance.
+func (e *Expr) Refs() []Ref {
+	return e.refs
+}
+
+// Parse compiles an expression. The error, if any, is an *Error carrying
+// the offset of the offending token.
+func Parse(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorf(tok.pos, "unexpected %q after end of expression", tok.text)
+	}
+	return &Expr{src: src, root: root, refs: p.refs}, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	refs   []Ref
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+// acceptOp consumes the next token when it is one of ops. Keyword
+// operators (and, or, not) are accepted alongside their symbols.
+func (p *parser) acceptOp(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return tok, true
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("||", "or")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{at: tok.pos, op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("&&", "and")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{at: tok.pos, op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if next, chained := p.acceptOp("==", "!=", "<", "<=", ">", ">="); chained {
+		return nil, errorf(next.pos, "comparisons cannot be chained, use and")
+	}
+	return binaryNode{at: tok.pos, op: tok.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{at: tok.pos, op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{at: tok.pos, op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if tok, ok := p.acceptOp("-", "!", "not"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		op := tok.text
+		if op == "not" {
+			op = "!"
+		}
+		return unaryNode{at: tok.pos, op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, errorf(tok.pos, "invalid number %q", tok.text)
+		}
+		return numberNode{at: tok.pos, value: v}, nil
+
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorf(closing.pos, "expected ) to close ( at column %d", tok.pos+1)
+		}
+		return inner, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		switch tok.text {
+		case "true":
+			return numberNode{at: tok.pos, value: 1}, nil
+		case "false":
+			return numberNode{at: tok.pos, value: 0}, nil
+		case "null":
+			return nullNode{at: tok.pos}, nil
+		}
+		parts := strings.Split(tok.text, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errorf(tok.pos, "invalid reference %q, expected <dataset>.<field> or self.<metric>", tok.text)
+		}
+		p.refs = append(p.refs, Ref{Source: tok.text, Offset: tok.pos})
+		return refNode{at: tok.pos, source: tok.text}, nil
+
+	case tokEOF:
+		return nil, errorf(tok.pos, "unexpected end of expression")
+	default:
+		return nil, errorf(tok.pos, "unexpected %q", tok.text)
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errorf(name.pos, "unknown function %q", name.text)
+	}
+	p.next() // (
+
+	var args []node
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, errorf(closing.pos, "expected , or ) in call to %s", name.text)
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, errorf(name.pos, "%s expects %s, got %d", name.text, fn.arity(), len(args))
+	}
+	return callNode{at: name.pos, name: name.text, args: args}, nil
+}
@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka consumes topics of a Kafka-compatible cluster, such as Redpanda, as
+// a member of a consumer group.
+type Kafka struct {
+	reader *kafka.Reader
+}
+
+// NewKafka joins group on the cluster of brokers, host:port addresses,
+// reading topics from the offsets the group last committed, or from the
+// first message of partitions it never committed.
+func NewKafka(brokers []string, group string, topics ...string) *Kafka {
+	return &Kafka{reader: kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     group,
+		GroupTopics: topics,
+		StartOffset: kafka.FirstOffset,
+	})}
+}
+
+func (k *Kafka) Fetch(ctx context.Context) (Message, error) {
+	msg, err := k.reader.FetchMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		return Message{}, ErrClosed
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}, nil
+}
+
+func (k *Kafka) Commit(ctx context.Context, msg Message) error {
+	return k.reader.CommitMessages(ctx, kafka.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	})
+}
+
+func (k *Kafka) Close() error {
+	return k.reader.Close()
+}
@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// Memory is an in-process broker, for tests. Every topic has a single
+// partition, and consumer groups keep their committed offsets for as long
+// as the broker lives.
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string][]Message
+	// committed is the next offset to read of each topic, by group.
+	committed map[string]map[string]int64
+	// produced is closed and replaced whenever a message is produced.
+	produced chan struct{}
+}
+
+// NewMemory returns a broker without topics.
+func NewMemory() *Memory {
+	return &Memory{
+		topics:    make(map[string][]Message),
+		committed: make(map[string]map[string]int64),
+		produced:  make(chan struct{}),
+	}
+}
+
+// Produce appends a message to topic and returns its offset.
+func (m *Memory) Produce(topic string, key, value []byte) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	offset := int64(len(m.topics[topic]))
+	m.topics[topic] = append(m.topics[topic], Message{
+		Topic:  topic,
+		Offset: offset,
+		Key:    bytes.Clone(key),
+		Value:  bytes.Clone(value),
+	})
+	close(m.produced)
+	m.produced = make(chan struct{})
+	return offset
+}
+
+// Committed is the next offset group reads of topic, 0 before its first
+// commit.
+func (m *Memory) Committed(group, topic string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.committed[group][topic]
+}
+
+// Consumer joins group, reading topics from the offsets the group last
+// committed.
+func (m *Memory) Consumer(group string, topics ...string) *MemoryConsumer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	positions := make(map[string]int64, len(topics))
+	for _, topic := range topics {
+		positions[topic] = m.committed[group][topic]
+	}
+	return &MemoryConsumer{broker: m, group: group, topics: topics, positions: positions}
+}
+
+// MemoryConsumer is a consumer of a Memory broker. Topics are read in the
+// order given, the earlier ones first when several have messages.
+type MemoryConsumer struct {
+	broker    *Memory
+	group     string
+	topics    []string
+	positions map[string]int64
+	closed    bool
+}
+
+func (mc *MemoryConsumer) Fetch(ctx context.Context) (Message, error) {
+	m := mc.broker
+	for {
+		m.mu.Lock()
+		if mc.closed {
+			m.mu.Unlock()
+			return Message{}, ErrClosed
+		}
+		for _, topic := range mc.topics {
+			if pos := mc.positions[topic]; pos < int64(len(m.topics[topic])) {
+				mc.positions[topic] = pos + 1
+				msg := m.topics[topic][pos]
+				m.mu.Unlock()
+				return msg, nil
+			}
+		}
+		produced := m.produced
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-produced:
+		}
+	}
+}
+
+func (mc *MemoryConsumer) Commit(_ context.Context, msg Message) error {
+	m := mc.broker
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mc.closed {
+		return ErrClosed
+	}
+	committed, ok := m.committed[mc.group]
+	if !ok {
+		committed = make(map[string]int64)
+		m.committed[mc.group] = committed
+	}
+	committed[msg.Topic] = max(committed[msg.Topic], msg.Offset+1)
+	return nil
+}
+
+// Close stops the consumer. A Fetch blocked at the time returns once a
+// message is produced or its context is done.
+func (mc *MemoryConsumer) Close() error {
+	mc.broker.mu.Lock()
+	defer mc.broker.mu.Unlock()
+	mc.closed = true
+	return nil
+}
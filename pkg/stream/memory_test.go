@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemory()
+	assert.Equal(t, int64(0), broker.Produce("emissions", []byte("1000"), []byte(`{"emi_1": 4}`)))
+	assert.Equal(t, int64(0), broker.Produce("waste", nil, []byte(`{"was_1": 2}`)))
+	assert.Equal(t, int64(1), broker.Produce("emissions", []byte("1001"), []byte(`{"emi_1": 5}`)))
+
+	consumer := broker.Consumer("scoring", "emissions", "waste")
+	msg, err := consumer.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Message{Topic: "emissions", Offset: 0, Key: []byte("1000"), Value: []byte(`{"emi_1": 4}`)}, msg)
+	require.NoError(t, consumer.Commit(ctx, msg))
+	assert.Equal(t, int64(1), broker.Committed("scoring", "emissions"))
+
+	// fetched but never committed, so delivered again to the next member
+	msg, err = consumer.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), msg.Offset)
+	require.NoError(t, consumer.Close())
+	_, err = consumer.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+
+	consumer = broker.Consumer("scoring", "emissions", "waste")
+	msg, err = consumer.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "emissions", msg.Topic)
+	assert.Equal(t, int64(1), msg.Offset)
+	msg, err = consumer.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "waste", msg.Topic)
+
+	// other groups keep their own offsets
+	assert.Equal(t, int64(0), broker.Committed("audit", "emissions"))
+	msg, err = broker.Consumer("audit", "emissions").Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), msg.Offset)
+}
+
+func TestMemoryFetchWaits(t *testing.T) {
+	broker := NewMemory()
+	consumer := broker.Consumer("scoring", "emissions")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		broker.Produce("emissions", nil, []byte(`{}`))
+	}()
+	msg, err := consumer.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "emissions", msg.Topic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = consumer.Fetch(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,31 @@
+package stream
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by the Fetch of a closed consumer.
+var ErrClosed = errors.New("consumer closed")
+
+// Message is one record of a topic partition.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// Consumer reads the topics of a consumer group. Offsets are only
+// committed when asked, so a consumer restarted in the same group resumes
+// after the last message committed in each partition and messages fetched
+// but not committed are delivered again.
+type Consumer interface {
+	// Fetch blocks until the next message, or until ctx is done.
+	Fetch(ctx context.Context) (Message, error)
+	// Commit marks msg, and every earlier message of its partition, as
+	// consumed by the group.
+	Commit(ctx context.Context, msg Message) error
+	Close() error
+}
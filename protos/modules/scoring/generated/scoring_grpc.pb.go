@@ -21,6 +21,8 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	ScoringService_CalculateScores_FullMethodName       = "/scoringpb.ScoringService/CalculateScores"
 	ScoringService_CalculateScoresStream_FullMethodName = "/scoringpb.ScoringService/CalculateScoresStream"
+	ScoringService_ValidateConfig_FullMethodName        = "/scoringpb.ScoringService/ValidateConfig"
+	ScoringService_DescribeScore_FullMethodName         = "/scoringpb.ScoringService/DescribeScore"
 )
 
 // ScoringServiceClient is the client API for ScoringService service.
@@ -29,6 +31,8 @@ const (
 type ScoringServiceClient interface {
 	CalculateScores(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error)
 	CalculateScoresStream(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CompanyScore], error)
+	ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error)
+	DescribeScore(ctx context.Context, in *DescribeScoreRequest, opts ...grpc.CallOption) (*DescribeScoreResponse, error)
 }
 
 type scoringServiceClient struct {
@@ -68,12 +72,34 @@ func (c *scoringServiceClient) CalculateScoresStream(ctx context.Context, in *Ca
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ScoringService_CalculateScoresStreamClient = grpc.ServerStreamingClient[CompanyScore]
 
+func (c *scoringServiceClient) ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateConfigResponse)
+	err := c.cc.Invoke(ctx, ScoringService_ValidateConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoringServiceClient) DescribeScore(ctx context.Context, in *DescribeScoreRequest, opts ...grpc.CallOption) (*DescribeScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DescribeScoreResponse)
+	err := c.cc.Invoke(ctx, ScoringService_DescribeScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ScoringServiceServer is the server API for ScoringService service.
 // All implementations must embed UnimplementedScoringServiceServer
 // for forward compatibility.
 type ScoringServiceServer interface {
 	CalculateScores(context.Context, *CalculateRequest) (*CalculateResponse, error)
 	CalculateScoresStream(*CalculateRequest, grpc.ServerStreamingServer[CompanyScore]) error
+	ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error)
+	DescribeScore(context.Context, *DescribeScoreRequest) (*DescribeScoreResponse, error)
 	mustEmbedUnimplementedScoringServiceServer()
 }
 
@@ -90,6 +116,12 @@ func (UnimplementedScoringServiceServer) CalculateScores(context.Context, *Calcu
 func (UnimplementedScoringServiceServer) CalculateScoresStream(*CalculateRequest, grpc.ServerStreamingServer[CompanyScore]) error {
 	return status.Errorf(codes.Unimplemented, "method CalculateScoresStream not implemented")
 }
+func (UnimplementedScoringServiceServer) ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateConfig not implemented")
+}
+func (UnimplementedScoringServiceServer) DescribeScore(context.Context, *DescribeScoreRequest) (*DescribeScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeScore not implemented")
+}
 func (UnimplementedScoringServiceServer) mustEmbedUnimplementedScoringServiceServer() {}
 func (UnimplementedScoringServiceServer) testEmbeddedByValue()                        {}
 
@@ -140,6 +172,42 @@ func _ScoringService_CalculateScoresStream_Handler(srv interface{}, stream grpc.
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ScoringService_CalculateScoresStreamServer = grpc.ServerStreamingServer[CompanyScore]
 
+func _ScoringService_ValidateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoringServiceServer).ValidateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ScoringService_ValidateConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoringServiceServer).ValidateConfig(ctx, req.(*ValidateConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ScoringService_DescribeScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoringServiceServer).DescribeScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ScoringService_DescribeScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoringServiceServer).DescribeScore(ctx, req.(*DescribeScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ScoringService_ServiceDesc is the grpc.ServiceDesc for ScoringService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -151,6 +219,14 @@ var ScoringService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CalculateScores",
 			Handler:    _ScoringService_CalculateScores_Handler,
 		},
+		{
+			MethodName: "ValidateConfig",
+			Handler:    _ScoringService_ValidateConfig_Handler,
+		},
+		{
+			MethodName: "DescribeScore",
+			Handler:    _ScoringService_DescribeScore_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
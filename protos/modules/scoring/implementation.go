@@ -58,3 +58,11 @@ func (b *Broker) CalculateScoresStream(ctx context.Context, in *generated.Calcul
 	return b.client.CalculateScoresStream(ctx, in, opts...)
 
 }
+
+func (b *Broker) ValidateConfig(ctx context.Context, in *generated.ValidateConfigRequest, opts ...grpc.CallOption) (*generated.ValidateConfigResponse, error) {
+	return b.client.ValidateConfig(ctx, in, opts...)
+}
+
+func (b *Broker) DescribeScore(ctx context.Context, in *generated.DescribeScoreRequest, opts ...grpc.CallOption) (*generated.DescribeScoreResponse, error) {
+	return b.client.DescribeScore(ctx, in, opts...)
+}